@@ -0,0 +1,103 @@
+// Package atlassian reads the status of Atlassian applications like Jira and Confluence, completes their setup
+// wizards, and calls the parts of the Jira REST API that the e2e tests use to create issues.
+package atlassian
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/browser"
+)
+
+// The states an application reports on /status.
+const (
+	// StateStarting is reported while the application starts
+	StateStarting = "STARTING"
+	// StateFirstRun is reported when the application has started but its setup wizard hasn't been completed
+	StateFirstRun = "FIRST_RUN"
+	// StateRunning is reported when the application is set up and serving
+	StateRunning = "RUNNING"
+	// StateError is reported when the application failed to start
+	StateError = "ERROR"
+)
+
+// maxSetupSteps is how many pages of a setup wizard CompleteSetup will submit before giving up.
+const maxSetupSteps = 15
+
+// Status is what an application reports on /status.
+type Status struct {
+	State string `json:"state"`
+}
+
+// GetStatus returns the status of the application at baseURL, such as https://jira.bigbang.dev. The status is read
+// whatever the HTTP status code is, since applications that are still starting report it with a 503.
+func GetStatus(client *http.Client, baseURL string) (*Status, error) {
+	response, err := client.Get(strings.TrimSuffix(baseURL, "/") + "/status") //nolint:noctx
+	if err != nil {
+		return nil, fmt.Errorf("unable to get status of %s: %w", baseURL, err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read status of %s: %w", baseURL, err)
+	}
+	status := new(Status)
+	if err := json.Unmarshal(content, status); err != nil || status.State == "" {
+		return nil, fmt.Errorf("%s returned HTTP %d without a status: %s", baseURL, response.StatusCode, content)
+	}
+
+	return status, nil
+}
+
+// CompleteSetup walks through the setup wizard of the application at baseURL with a browser. On every page it submits
+// the wizard's form, filling in the fields it has values for, such as the license. It stops once the browser is sent
+// away from the wizard, whose pages all have "setup" in their path. A page that comes back after it was submitted
+// rejected the values.
+func CompleteSetup(session *browser.Browser, baseURL string, values map[string]string) error {
+	page, err := session.Get(strings.TrimSuffix(baseURL, "/") + "/")
+	if err != nil {
+		return err
+	}
+	for step := 0; step < maxSetupSteps; step++ {
+		if !strings.Contains(strings.ToLower(page.URL.Path), "setup") {
+			return nil
+		}
+		form, ok := setupForm(page)
+		if !ok {
+			return fmt.Errorf("setup page %s (%q) has no form to fill in", page.URL, page.Title())
+		}
+		filled := make(map[string]string)
+		for name := range form.Values {
+			if value, ok := values[name]; ok {
+				filled[name] = value
+			}
+		}
+		previous := page.URL.Path
+		if page, err = session.Submit(form, filled, ""); err != nil {
+			return err
+		}
+		if page.URL.Path == previous {
+			return fmt.Errorf("setup page %s (%q) rejected the values, it returned HTTP %d", page.URL, page.Title(), page.StatusCode)
+		}
+	}
+
+	return fmt.Errorf("setup of %s did not finish after %d pages, it is on %s (%q)", baseURL, maxSetupSteps, page.URL, page.Title())
+}
+
+// setupForm returns the form of a setup page that submits to the wizard, or the only form on the page if none of them
+// do.
+func setupForm(page *browser.Page) (browser.Form, bool) {
+	for _, form := range page.Forms {
+		if strings.Contains(strings.ToLower(form.Action.Path), "setup") {
+			return form, true
+		}
+	}
+	if len(page.Forms) == 1 {
+		return page.Forms[0], true
+	}
+
+	return browser.Form{}, false
+}
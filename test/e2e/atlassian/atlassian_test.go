@@ -0,0 +1,111 @@
+package atlassian_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/atlassian"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/browser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStatus(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/starting/status", func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(writer, `{"state":"STARTING"}`)
+	})
+	mux.HandleFunc("/broken/status", func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, "no healthy upstream", http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	status, err := atlassian.GetStatus(server.Client(), server.URL+"/starting/")
+	require.NoError(t, err)
+	require.Equal(t, atlassian.StateStarting, status.State)
+	_, err = atlassian.GetStatus(server.Client(), server.URL+"/broken")
+	require.ErrorContains(t, err, "returned HTTP 503 without a status: no healthy upstream")
+}
+
+func TestCompleteSetup(t *testing.T) {
+	t.Parallel()
+	var license string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		target := "/secure/SetupLicense!default.jspa"
+		if license != "" {
+			target = "/secure/Dashboard.jspa"
+		}
+		http.Redirect(writer, request, target, http.StatusFound)
+	})
+	mux.HandleFunc("/secure/SetupLicense!default.jspa", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(writer, `<html><body>
+<form action="/secure/QuickSearch.jspa"><input name="searchString"></form>
+<form method="post" action="SetupLicense.jspa"><input type="hidden" name="atl_token" value="xsrf"><textarea name="setupLicenseKey"></textarea></form>
+</body></html>`)
+	})
+	mux.HandleFunc("/secure/SetupLicense.jspa", func(writer http.ResponseWriter, request *http.Request) {
+		if request.PostFormValue("atl_token") != "xsrf" || request.PostFormValue("setupLicenseKey") != "AAAB" {
+			http.Redirect(writer, request, "/secure/SetupLicense!default.jspa", http.StatusFound)
+
+			return
+		}
+		license = request.PostFormValue("setupLicenseKey")
+		http.Redirect(writer, request, "/secure/SetupComplete!default.jspa", http.StatusFound)
+	})
+	mux.HandleFunc("/secure/SetupComplete!default.jspa", func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(writer, `<html><body><form method="post" action="/"><input type="submit" name="finish" value="Finish"></form></body></html>`)
+	})
+	mux.HandleFunc("/secure/Dashboard.jspa", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, "dashboard")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	session, err := browser.New(server.Client())
+	require.NoError(t, err)
+	err = atlassian.CompleteSetup(session, server.URL, map[string]string{"setupLicenseKey": "wrong"})
+	require.ErrorContains(t, err, "rejected the values")
+	require.NoError(t, atlassian.CompleteSetup(session, server.URL, map[string]string{"setupLicenseKey": "AAAB", "searchString": "unused"}))
+	require.Equal(t, "AAAB", license)
+}
+
+func TestJira(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rest/api/2/issue", func(writer http.ResponseWriter, request *http.Request) {
+		if username, password, _ := request.BasicAuth(); username != "e2e-admin" || password != "secret" {
+			http.Error(writer, "", http.StatusUnauthorized)
+
+			return
+		}
+		writer.WriteHeader(http.StatusCreated)
+		fmt.Fprint(writer, `{"id": "10000", "key": "E2E-1"}`)
+	})
+	mux.HandleFunc("/rest/api/2/issue/E2E-1", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, `{"key": "E2E-1", "fields": {"summary": "Survive the upgrade", "description": "Seeded"}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	jira := atlassian.NewJira(server.Client(), server.URL+"/", "e2e-admin", "secret")
+
+	key, err := jira.CreateTask("E2E", "Survive the upgrade", "Seeded")
+	require.NoError(t, err)
+	require.Equal(t, "E2E-1", key)
+	issue, err := jira.Issue(key)
+	require.NoError(t, err)
+	require.Equal(t, "Survive the upgrade", issue.Fields.Summary)
+	require.Equal(t, "Seeded", issue.Fields.Description)
+
+	_, err = jira.Issue("E2E-2")
+	require.True(t, errors.Is(err, atlassian.ErrNotFound), "got %v", err)
+	_, err = atlassian.NewJira(server.Client(), server.URL, "e2e-admin", "wrong").CreateTask("E2E", "", "")
+	require.ErrorContains(t, err, "HTTP 401")
+}
@@ -0,0 +1,131 @@
+package atlassian
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotFound is returned when an application says what was asked for doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// jiraProjectTemplate is the template of the projects CreateProject creates, a business project that every edition of
+// Jira has, with the Task issue type.
+const jiraProjectTemplate = "com.atlassian.jira-core-project-templates:jira-core-project-management"
+
+// Jira calls the REST API of a Jira that has been set up, as a user with basic auth.
+type Jira struct {
+	baseURL  string
+	http     *http.Client
+	username string
+	password string
+}
+
+// Issue is a Jira issue, with the fields the tests look at.
+type Issue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+	} `json:"fields"`
+}
+
+// NewJira returns a client for the Jira at baseURL, such as https://jira.bigbang.dev, that authenticates as a user
+// with basic auth. It makes its requests with httpClient.
+func NewJira(httpClient *http.Client, baseURL string, username string, password string) *Jira {
+	return &Jira{baseURL: strings.TrimSuffix(baseURL, "/"), http: httpClient, username: username, password: password}
+}
+
+// CreateProject creates a project with the given key, such as E2E, led by the user of the client.
+func (jira *Jira) CreateProject(key string, name string) error {
+	project := map[string]string{
+		"key":                key,
+		"name":               name,
+		"projectTypeKey":     "business",
+		"projectTemplateKey": jiraProjectTemplate,
+		"lead":               jira.username,
+	}
+	if err := jira.do(http.MethodPost, "/project", project, nil); err != nil {
+		return fmt.Errorf("unable to create project %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// CreateTask creates an issue of type Task in a project and returns its key, such as E2E-1.
+func (jira *Jira) CreateTask(projectKey string, summary string, description string) (string, error) {
+	issue := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": projectKey},
+			"issuetype":   map[string]string{"name": "Task"},
+			"summary":     summary,
+			"description": description,
+		},
+	}
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := jira.do(http.MethodPost, "/issue", issue, &created); err != nil {
+		return "", fmt.Errorf("unable to create task in project %s: %w", projectKey, err)
+	}
+
+	return created.Key, nil
+}
+
+// Issue returns the issue with the given key.
+func (jira *Jira) Issue(key string) (*Issue, error) {
+	issue := new(Issue)
+	if err := jira.do(http.MethodGet, "/issue/"+url.PathEscape(key)+"?fields=summary,description", nil, issue); err != nil {
+		return nil, fmt.Errorf("unable to get issue %s: %w", key, err)
+	}
+
+	return issue, nil
+}
+
+// do calls the REST API with body encoded as JSON, and decodes the JSON response into out if it isn't nil. Responses
+// that aren't a 2xx are returned as an error.
+func (jira *Jira) do(method string, apiPath string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("unable to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request, err := http.NewRequest(method, jira.baseURL+"/rest/api/2"+apiPath, reader) //nolint:noctx
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	request.SetBasicAuth(jira.username, jira.password)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := jira.http.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to %s %s: %w", method, apiPath, err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response of %s %s: %w", method, apiPath, err)
+	}
+	if response.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s %s: %w", method, apiPath, ErrNotFound)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned HTTP %d: %s", method, apiPath, response.StatusCode, content)
+	}
+	if out != nil {
+		if err := json.Unmarshal(content, out); err != nil {
+			return fmt.Errorf("unable to parse response of %s %s: %w", method, apiPath, err)
+		}
+	}
+
+	return nil
+}
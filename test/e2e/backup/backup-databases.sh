@@ -0,0 +1,285 @@
+#!/bin/bash
+# Backs up and restores the Postgres databases and MinIO buckets of the software factory. It is what the e2e backup and
+# restore scenario runs on the test host, and the reference procedure for doing the same by hand, with kubectl pointed
+# at the cluster:
+#
+#   backup-databases.sh backup DIR NAMESPACE...          logical dumps of every database of the Postgres in each namespace
+#   backup-databases.sh destroy NAMESPACE...             delete the Postgres and its volumes, and let it come back empty
+#   backup-databases.sh restore DIR NAMESPACE...         load the dumps taken by backup into the Postgres in each namespace
+#   backup-databases.sh backup-buckets DIR NAMESPACE...  copy every object of the MinIO in each namespace to DIR
+#   backup-databases.sh destroy-buckets NAMESPACE...     delete the MinIO pods and their volumes, and let them come back empty
+#   backup-databases.sh restore-buckets DIR NAMESPACE... copy the objects taken by backup-buckets back into the MinIO
+#   backup-databases.sh restart NAMESPACE...             restart the workloads of an application once its database is back
+#   backup-databases.sh connections NAMESPACE DB USER    count the connections of USER to database DB in a namespace
+#
+# Postgres is found by its image, and is reached as its superuser with the password from the environment of its
+# container, so no password has to be passed in, and so is MinIO. Every dump and copy prints a line like
+# "dumped NAMESPACE DATABASE BYTES" that the e2e tests read.
+
+set -euo pipefail
+
+# postgres_env makes psql, pg_dump and pg_restore in a Postgres container connect as the superuser. The Bitnami image
+# has the password of the superuser in POSTGRES_POSTGRES_PASSWORD when there is an application user as well.
+# shellcheck disable=SC2016
+postgres_env='
+if [ -n "${POSTGRES_POSTGRES_PASSWORD:-}" ]; then
+  export PGUSER=postgres PGPASSWORD="${POSTGRES_POSTGRES_PASSWORD}"
+elif [ -n "${POSTGRES_POSTGRES_PASSWORD_FILE:-}" ]; then
+  export PGUSER=postgres PGPASSWORD="$(cat "${POSTGRES_POSTGRES_PASSWORD_FILE}")"
+else
+  export PGUSER="${POSTGRES_USER:-postgres}" PGPASSWORD="${POSTGRES_PASSWORD:-}"
+fi
+export PGHOST=127.0.0.1 PGDATABASE=postgres
+'
+
+# minio_env makes mc in a MinIO container talk to that MinIO as its root user, whether the credentials are in the
+# environment or in the config file the MinIO operator mounts.
+# shellcheck disable=SC2016
+minio_env='
+if [ -f /tmp/minio/config.env ]; then . /tmp/minio/config.env; fi
+export MC_CONFIG_DIR=/tmp/.mc-backup
+mc alias set local http://127.0.0.1:9000 "${MINIO_ROOT_USER}" "${MINIO_ROOT_PASSWORD}" >/dev/null 2>&1 ||
+  mc alias set --insecure local https://127.0.0.1:9000 "${MINIO_ROOT_USER}" "${MINIO_ROOT_PASSWORD}" >/dev/null
+'
+
+# find_container prints the pod and the container in a namespace whose image matches a pattern, such as
+# "postgresql-0 postgresql".
+find_container() {
+  local namespace="$1" pattern="$2" found
+  found=$(kubectl get pods -n "${namespace}" --field-selector=status.phase=Running \
+    -o jsonpath='{range .items[*]}{.metadata.name}{" "}{range .spec.containers[*]}{.name}{"="}{.image}{" "}{end}{"\n"}{end}' |
+    while read -r pod containers; do
+      for container in ${containers}; do
+        if [[ "${container#*=}" =~ ^${pattern} ]]; then
+          echo "${pod} ${container%%=*}"
+          break 2
+        fi
+      done
+    done)
+  if [ -z "${found}" ]; then
+    echo "no running container in ${namespace} has an image that matches ${pattern}" >&2
+    return 1
+  fi
+  echo "${found}"
+}
+
+# postgres_container prints the pod and the container of the Postgres in a namespace.
+postgres_container() {
+  find_container "$1" '(.*/)?postgres(ql)?:'
+}
+
+# minio_container prints the pod and the container of the MinIO in a namespace.
+minio_container() {
+  find_container "$1" '(.*/)?minio:'
+}
+
+# in_postgres runs a script in the Postgres of a namespace as its superuser, passing on stdin.
+in_postgres() {
+  local namespace="$1" script="$2" found pod container
+  found=$(postgres_container "${namespace}")
+  read -r pod container <<<"${found}"
+  kubectl exec -i -n "${namespace}" "${pod}" -c "${container}" -- sh -c "${postgres_env}${script}"
+}
+
+# in_minio runs a script in the MinIO of a namespace with mc set up as its root user.
+in_minio() {
+  local namespace="$1" script="$2" found pod container
+  found=$(minio_container "${namespace}")
+  read -r pod container <<<"${found}"
+  kubectl exec -i -n "${namespace}" "${pod}" -c "${container}" -- sh -c "${minio_env}${script}"
+}
+
+# backup dumps the roles of the Postgres in every namespace into DIR/NAMESPACE/globals.sql, and every database but the
+# templates and postgres into DIR/NAMESPACE/DATABASE.dump, in the custom format of pg_dump.
+backup() {
+  local dir="$1" namespace databases database
+  shift
+  for namespace in "$@"; do
+    mkdir -p "${dir}/${namespace}"
+    in_postgres "${namespace}" 'pg_dumpall --globals-only' >"${dir}/${namespace}/globals.sql"
+    # The list is read into a variable first, since set -e doesn't catch a failure in the list of a for loop
+    databases=$(in_postgres "${namespace}" 'psql -At -c "SELECT datname FROM pg_database WHERE NOT datistemplate AND datname <> '"'postgres'"'"')
+    for database in ${databases}; do
+      in_postgres "${namespace}" "pg_dump --format=custom --dbname=${database}" >"${dir}/${namespace}/${database}.dump"
+      echo "dumped ${namespace} ${database} $(stat -c %s "${dir}/${namespace}/${database}.dump")"
+    done
+  done
+}
+
+# destroy scales the StatefulSet of the Postgres in every namespace down, deletes the volumes of its pods, and scales it
+# back up, so that it starts over with the empty database and the users its chart creates.
+destroy() {
+  local namespace found pod container statefulset replicas pods claims
+  for namespace in "$@"; do
+    found=$(postgres_container "${namespace}")
+    read -r pod container <<<"${found}"
+    statefulset=$(kubectl get pod -n "${namespace}" "${pod}" -o jsonpath='{.metadata.ownerReferences[?(@.kind=="StatefulSet")].name}')
+    if [ -z "${statefulset}" ]; then
+      echo "${namespace}/${pod} doesn't belong to a StatefulSet" >&2
+      return 1
+    fi
+    replicas=$(kubectl get statefulset -n "${namespace}" "${statefulset}" -o jsonpath='{.spec.replicas}')
+    pods=$(kubectl get pods -n "${namespace}" -o name | grep -E "^pod/${statefulset}-[0-9]+$")
+    claims=$(for pod in ${pods}; do
+      kubectl get -n "${namespace}" "${pod}" -o jsonpath='{range .spec.volumes[*]}{.persistentVolumeClaim.claimName}{" "}{end}'
+    done)
+    kubectl scale statefulset -n "${namespace}" "${statefulset}" --replicas=0
+    # shellcheck disable=SC2086
+    kubectl wait -n "${namespace}" --for=delete --timeout=5m ${pods}
+    for claim in ${claims}; do
+      kubectl delete pvc -n "${namespace}" "${claim}" --wait=true
+    done
+    kubectl scale statefulset -n "${namespace}" "${statefulset}" --replicas="${replicas}"
+    kubectl rollout status statefulset -n "${namespace}" "${statefulset}" --timeout=10m
+    echo "destroyed ${namespace} ${statefulset}"
+  done
+}
+
+# restore loads the roles from DIR/NAMESPACE/globals.sql into the Postgres in every namespace, and every
+# DIR/NAMESPACE/DATABASE.dump into its database, which is created if the chart didn't create it again. The roles the
+# chart created again already exist, so psql carries on after errors, and fails the restore on any other error.
+restore() {
+  local dir="$1" namespace output unexpected dump database
+  shift
+  for namespace in "$@"; do
+    output=$(in_postgres "${namespace}" 'psql --quiet --set ON_ERROR_STOP=0 2>&1' <"${dir}/${namespace}/globals.sql")
+    unexpected=$(grep -E "(ERROR|FATAL):" <<<"${output}" | grep -v -E 'ERROR: +role ".*" already exists' || true)
+    if [ -n "${unexpected}" ]; then
+      echo "unable to restore the roles of ${namespace}:" >&2
+      echo "${unexpected}" >&2
+      return 1
+    fi
+    for dump in "${dir}/${namespace}"/*.dump; do
+      database=$(basename "${dump}" .dump)
+      in_postgres "${namespace}" "psql -At -c \"SELECT 1 FROM pg_database WHERE datname = '${database}'\" | grep -q 1 || createdb ${database}"
+      in_postgres "${namespace}" "pg_restore --clean --if-exists --dbname=${database}" <"${dump}"
+      echo "restored ${namespace} ${database}"
+    done
+  done
+}
+
+# list_buckets prints the buckets of the MinIO in a namespace, one per line.
+list_buckets() {
+  # shellcheck disable=SC2016
+  in_minio "$1" 'mc ls --json local | sed -n "s/.*\"key\":\"\([^\"]*\)\/\".*/\1/p"'
+}
+
+# backup_buckets copies every object of every bucket of the MinIO in every namespace to DIR/NAMESPACE/buckets/BUCKET on
+# this host, one `mc cat` at a time, so that the copy survives the MinIO and its volumes. An earlier copy is replaced.
+backup_buckets() {
+  local dir="$1" namespace buckets bucket objects object key count
+  shift
+  for namespace in "$@"; do
+    buckets=$(list_buckets "${namespace}")
+    rm -rf "${dir:?}/${namespace}/buckets"
+    for bucket in ${buckets}; do
+      mkdir -p "${dir}/${namespace}/buckets/${bucket}"
+      objects=$(in_minio "${namespace}" "mc find local/${bucket}")
+      count=0
+      # The objects are read from another file descriptor, since kubectl exec reads stdin
+      while IFS= read -r object <&3; do
+        key="${object#local/"${bucket}"/}"
+        if [ -z "${object}" ] || [ "${key}" = "${object}" ]; then
+          continue
+        fi
+        mkdir -p "$(dirname "${dir}/${namespace}/buckets/${bucket}/${key}")"
+        in_minio "${namespace}" "mc cat \"local/${bucket}/${key}\"" </dev/null >"${dir}/${namespace}/buckets/${bucket}/${key}"
+        count=$((count + 1))
+      done 3<<<"${objects}"
+      echo "copied ${namespace} ${bucket} ${count}"
+    done
+  done
+}
+
+# destroy_buckets deletes the volumes of the MinIO pods in every namespace along with the pods, so that they start over
+# empty. The pods are deleted rather than scaled down, since the MinIO operator would scale its StatefulSet back up. Pods
+# that were recreated before their volumes were gone are deleted once more, so that they get new volumes.
+destroy_buckets() {
+  local namespace found pod container statefulset pods claims claim
+  for namespace in "$@"; do
+    found=$(minio_container "${namespace}")
+    read -r pod container <<<"${found}"
+    statefulset=$(kubectl get pod -n "${namespace}" "${pod}" -o jsonpath='{.metadata.ownerReferences[?(@.kind=="StatefulSet")].name}')
+    if [ -z "${statefulset}" ]; then
+      echo "${namespace}/${pod} doesn't belong to a StatefulSet" >&2
+      return 1
+    fi
+    pods=$(kubectl get pods -n "${namespace}" -o name | grep -E "^pod/${statefulset}-[0-9]+$")
+    claims=$(for pod in ${pods}; do
+      kubectl get -n "${namespace}" "${pod}" -o jsonpath='{range .spec.volumes[*]}{.persistentVolumeClaim.claimName}{" "}{end}'
+    done)
+    for claim in ${claims}; do
+      kubectl delete pvc -n "${namespace}" "${claim}" --wait=false
+    done
+    # shellcheck disable=SC2086
+    kubectl delete -n "${namespace}" --wait=true ${pods}
+    for claim in ${claims}; do
+      kubectl wait -n "${namespace}" --for=delete --timeout=5m "pvc/${claim}"
+    done
+    # shellcheck disable=SC2086
+    kubectl delete -n "${namespace}" --wait=true --ignore-not-found ${pods}
+    kubectl rollout status statefulset -n "${namespace}" "${statefulset}" --timeout=10m
+    echo "destroyed ${namespace} ${statefulset}"
+  done
+}
+
+# restore_buckets creates every bucket in DIR/NAMESPACE/buckets in the MinIO in every namespace, and copies the objects
+# in it back with `mc pipe`. It prints the number of objects in the bucket once that is done.
+restore_buckets() {
+  local dir="$1" namespace path bucket file key count
+  shift
+  for namespace in "$@"; do
+    for path in "${dir}/${namespace}/buckets"/*/; do
+      if [ ! -d "${path}" ]; then
+        continue
+      fi
+      bucket=$(basename "${path}")
+      in_minio "${namespace}" "mc mb --ignore-existing local/${bucket}" </dev/null >/dev/null
+      while IFS= read -r -d '' file <&3; do
+        key="${file#"${path}"}"
+        in_minio "${namespace}" "mc pipe \"local/${bucket}/${key}\"" <"${file}" >/dev/null
+      done 3< <(find "${path}" -type f -print0)
+      count=$(in_minio "${namespace}" "mc ls --recursive local/${bucket}" </dev/null | wc -l)
+      echo "mirrored ${namespace} ${bucket} ${count}"
+    done
+  done
+}
+
+# restart restarts the Deployments and StatefulSets in every namespace and waits for them, so that the applications
+# reconnect to their restored databases and drop what they cached from the empty ones.
+restart() {
+  local namespace workload
+  for namespace in "$@"; do
+    for workload in $(kubectl get deployments,statefulsets -n "${namespace}" -o name); do
+      kubectl rollout restart -n "${namespace}" "${workload}"
+    done
+    for workload in $(kubectl get deployments,statefulsets -n "${namespace}" -o name); do
+      kubectl rollout status -n "${namespace}" "${workload}" --timeout=20m
+    done
+  done
+}
+
+# connections counts the connections of a user to a database of the Postgres in a namespace, other than its own, which
+# is how to tell that an application is really using the database it is configured with.
+connections() {
+  local namespace="$1" database="$2" user="$3" count
+  count=$(in_postgres "${namespace}" "psql -At -c \"SELECT count(*) FROM pg_stat_activity WHERE datname = '${database}' AND usename = '${user}' AND pid <> pg_backend_pid()\"")
+  echo "connected ${namespace} ${database} ${count}"
+}
+
+command="${1:-}"
+shift || true
+case "${command}" in
+backup) backup "$@" ;;
+destroy) destroy "$@" ;;
+restore) restore "$@" ;;
+backup-buckets) backup_buckets "$@" ;;
+destroy-buckets) destroy_buckets "$@" ;;
+restore-buckets) restore_buckets "$@" ;;
+restart) restart "$@" ;;
+connections) connections "$@" ;;
+*)
+  sed -n '2,/^$/p' "$0" >&2
+  exit 1
+  ;;
+esac
@@ -0,0 +1,207 @@
+// Package backup describes the databases and object stores of the software factory that are backed up, and reads what
+// backup-databases.sh reports it backed up and restored.
+package backup
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Script backs up and restores the databases and buckets on the test host, see the usage at the top of it. It is the
+// reference procedure for the ops runbook as well.
+//
+//go:embed backup-databases.sh
+var Script []byte
+
+// Database is the Postgres of a capability, deployed by a package of the bundle.
+type Database struct {
+	// Package is the package of the bundle that deploys the database
+	Package string
+	// Namespace is where the database runs
+	Namespace string
+	// Applications are the namespaces of the workloads that use the database, which are restarted once it is restored
+	Applications []string
+	// Capability is the name of the capability in capabilities.yaml that keeps its data in the database
+	Capability string
+}
+
+// Store is the MinIO of a capability, deployed by a package of the bundle.
+type Store struct {
+	// Package is the package of the bundle that deploys the store
+	Package string
+	// Namespace is where the store runs
+	Namespace string
+}
+
+// Databases are the databases of the capabilities in the bundle.
+var Databases = []Database{
+	{Package: "keycloak-postgres", Namespace: "keycloak", Applications: []string{"keycloak"}, Capability: "Keycloak"},
+	{Package: "gitlab-postgres", Namespace: "gitlab-db", Applications: []string{"gitlab"}, Capability: "GitLab"},
+	{Package: "sonarqube-postgres", Namespace: "sonarqube-db", Applications: []string{"sonarqube"}, Capability: "SonarQube"},
+	{Package: "jira-postgres", Namespace: "jira-db", Applications: []string{"jira"}, Capability: "Jira"},
+	{Package: "confluence-postgres", Namespace: "confluence-db", Applications: []string{"confluence"}, Capability: "Confluence"},
+	{Package: "mattermost-postgres", Namespace: "mattermost-db", Applications: []string{"mattermost"}, Capability: "Mattermost"},
+	{Package: "nexus-postgres", Namespace: "nexus-db", Applications: []string{"nexus"}, Capability: "Nexus"},
+}
+
+// Stores are the object stores of the capabilities in the bundle.
+var Stores = []Store{
+	{Package: "gitlab-minio", Namespace: "gitlab-minio"},
+	{Package: "mattermost-minio", Namespace: "mattermost-minio"},
+}
+
+// Item is a database or a bucket that the script backed up or restored.
+type Item struct {
+	// Namespace is where the database or store runs
+	Namespace string
+	// Name is the name of the database or bucket
+	Name string
+	// Size is the size of the dump in bytes, or the number of objects in the bucket. It is -1 if the script doesn't
+	// report it.
+	Size int64
+}
+
+// Inventory is what the script reported, by what it did: "dumped", "restored", "copied", "mirrored" or "connected". The
+// size of a "connected" item is the number of connections to the database.
+type Inventory map[string][]Item
+
+// ParseInventory reads the lines of the output of the script that report an item, such as
+// "dumped gitlab-db gitlabhq_production 123456", and skips the others.
+func ParseInventory(output string) (Inventory, error) {
+	inventory := make(Inventory)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		switch fields[0] {
+		case "dumped", "restored", "copied", "mirrored", "connected":
+		default:
+			continue
+		}
+		item := Item{Namespace: fields[1], Name: fields[2], Size: -1}
+		if len(fields) > 3 {
+			size, err := strconv.ParseInt(fields[3], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse the size in %q: %w", line, err)
+			}
+			item.Size = size
+		}
+		inventory[fields[0]] = append(inventory[fields[0]], item)
+	}
+
+	return inventory, nil
+}
+
+// Missing returns the namespaces where nothing was done, or only empty dumps were taken.
+func (inventory Inventory) Missing(done string, namespaces []string) []string {
+	found := make(map[string]bool)
+	for _, item := range inventory[done] {
+		if item.Size != 0 {
+			found[item.Namespace] = true
+		}
+	}
+	var missing []string
+	for _, namespace := range namespaces {
+		if !found[namespace] {
+			missing = append(missing, namespace)
+		}
+	}
+
+	return missing
+}
+
+// Unreported returns the namespaces the script didn't report anything done for, not even an empty bucket.
+func (inventory Inventory) Unreported(done string, namespaces []string) []string {
+	found := make(map[string]bool)
+	for _, item := range inventory[done] {
+		found[item.Namespace] = true
+	}
+	var unreported []string
+	for _, namespace := range namespaces {
+		if !found[namespace] {
+			unreported = append(unreported, namespace)
+		}
+	}
+
+	return unreported
+}
+
+// Unmatched returns the items that were done as from, but not as to with the same size, such as the buckets that were
+// "copied" but not "mirrored" back with as many objects.
+func (inventory Inventory) Unmatched(from string, to string) []Item {
+	sizes := make(map[Item]bool)
+	for _, item := range inventory[to] {
+		sizes[item] = true
+	}
+	var unmatched []Item
+	for _, item := range inventory[from] {
+		if !sizes[item] {
+			unmatched = append(unmatched, item)
+		}
+	}
+
+	return unmatched
+}
+
+// Merge returns the items of the inventory and of others together.
+func (inventory Inventory) Merge(others ...Inventory) Inventory {
+	merged := make(Inventory)
+	for _, source := range append([]Inventory{inventory}, others...) {
+		for done, items := range source {
+			merged[done] = append(merged[done], items...)
+		}
+	}
+
+	return merged
+}
+
+// String returns what was done to which item, sorted, such as "dumped gitlab-db/gitlabhq_production (123456)".
+func (inventory Inventory) String() string {
+	var lines []string
+	for done, items := range inventory {
+		for _, item := range items {
+			line := fmt.Sprintf("%s %s/%s", done, item.Namespace, item.Name)
+			if item.Size >= 0 {
+				line += fmt.Sprintf(" (%d)", item.Size)
+			}
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Namespaces returns the namespaces of the databases.
+func Namespaces(databases []Database) []string {
+	namespaces := make([]string, 0, len(databases))
+	for _, database := range databases {
+		namespaces = append(namespaces, database.Namespace)
+	}
+
+	return namespaces
+}
+
+// StoreNamespaces returns the namespaces of the stores.
+func StoreNamespaces(stores []Store) []string {
+	namespaces := make([]string, 0, len(stores))
+	for _, store := range stores {
+		namespaces = append(namespaces, store.Namespace)
+	}
+
+	return namespaces
+}
+
+// Applications returns the namespaces of the applications of the databases.
+func Applications(databases []Database) []string {
+	var namespaces []string
+	for _, database := range databases {
+		namespaces = append(namespaces, database.Applications...)
+	}
+
+	return namespaces
+}
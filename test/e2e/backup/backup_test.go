@@ -0,0 +1,84 @@
+package backup_test
+
+import (
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/backup"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabasesAndStores(t *testing.T) {
+	t.Parallel()
+	udsBundle, err := bundle.Read("../../..")
+	require.NoError(t, err)
+	namespaces, err := bundle.Namespaces("../../..")
+	require.NoError(t, err)
+	created := make(map[string]bool)
+	for _, namespace := range namespaces {
+		created[namespace.Name] = true
+	}
+
+	capabilities, err := utils.LoadCapabilities("../../..")
+	require.NoError(t, err)
+	names := make(map[string]bool)
+	for _, capability := range capabilities {
+		names[capability.Name] = true
+	}
+
+	for _, database := range backup.Databases {
+		require.True(t, names[database.Capability], "%s isn't a capability in %s", database.Capability, utils.CapabilitiesFile)
+		_, ok := udsBundle.Package(database.Package)
+		require.True(t, ok, "the bundle has no package %s", database.Package)
+		require.True(t, created[database.Namespace], "%s isn't created by %s", database.Namespace, bundle.NamespacesFile)
+		for _, application := range database.Applications {
+			require.True(t, created[application], "%s isn't created by %s", application, bundle.NamespacesFile)
+		}
+	}
+	for _, store := range backup.Stores {
+		_, ok := udsBundle.Package(store.Package)
+		require.True(t, ok, "the bundle has no package %s", store.Package)
+		require.True(t, created[store.Namespace], "%s isn't created by %s", store.Namespace, bundle.NamespacesFile)
+	}
+	require.Contains(t, string(backup.Script), "backup-databases.sh backup DIR NAMESPACE...")
+}
+
+func TestParseInventory(t *testing.T) {
+	t.Parallel()
+	inventory, err := backup.ParseInventory(`statefulset.apps/postgresql scaled
+dumped gitlab-db gitlabhq_production 123456
+dumped nexus-db nexus 0
+restored gitlab-db gitlabhq_production
+copied gitlab-minio gitlab-uploads 3
+connected jira-db jiradb 0
+`)
+	require.NoError(t, err)
+	require.Equal(t, []backup.Item{
+		{Namespace: "gitlab-db", Name: "gitlabhq_production", Size: 123456},
+		{Namespace: "nexus-db", Name: "nexus", Size: 0},
+	}, inventory["dumped"])
+	require.Equal(t, []string{"nexus-db", "jira-db"}, inventory.Missing("dumped", []string{"gitlab-db", "nexus-db", "jira-db"}))
+	require.Empty(t, inventory.Missing("restored", []string{"gitlab-db"}))
+	require.Equal(t, []string{"jira-db"}, inventory.Missing("connected", []string{"jira-db"}))
+	require.Equal(t, `connected jira-db/jiradb (0)
+copied gitlab-minio/gitlab-uploads (3)
+dumped gitlab-db/gitlabhq_production (123456)
+dumped nexus-db/nexus (0)
+restored gitlab-db/gitlabhq_production
+`, inventory.String())
+
+	mirrored, err := backup.ParseInventory(`mirrored gitlab-minio gitlab-uploads 0
+mirrored gitlab-minio gitlab-artifacts 0
+`)
+	require.NoError(t, err)
+	merged := inventory.Merge(mirrored)
+	require.Len(t, merged["copied"], 1)
+	require.Len(t, merged["mirrored"], 2)
+	require.Equal(t, []backup.Item{{Namespace: "gitlab-minio", Name: "gitlab-uploads", Size: 3}}, merged.Unmatched("copied", "mirrored"))
+	require.Equal(t, []string{"mattermost-minio"}, merged.Unreported("mirrored", []string{"gitlab-minio", "mattermost-minio"}))
+	require.Equal(t, []string{"gitlab-minio", "mattermost-minio"}, merged.Missing("mirrored", []string{"gitlab-minio", "mattermost-minio"}))
+
+	_, err = backup.ParseInventory("dumped gitlab-db gitlabhq_production lots")
+	require.ErrorContains(t, err, `unable to parse the size in "dumped gitlab-db gitlabhq_production lots"`)
+}
@@ -0,0 +1,274 @@
+// Package browser is a minimal headless browser for walking through login flows in the e2e tests. It keeps cookies,
+// follows redirects, and fills in and submits HTML forms, which is all that SSO logins need since they work without
+// JavaScript.
+package browser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// maxBodySize is the most that is read of a page
+	maxBodySize = 10 << 20
+	// requestTimeout is how long a request, including its redirects, may take
+	requestTimeout = time.Minute
+)
+
+// Browser keeps the cookies of a session across requests.
+type Browser struct {
+	// Client makes the requests. Its Transport decides where connections are made from.
+	Client *http.Client
+}
+
+// Page is a page the browser ended up on after following redirects.
+type Page struct {
+	// URL is the URL of the page after redirects
+	URL *url.URL
+	// StatusCode is the HTTP status code of the page
+	StatusCode int
+	// Body is the content of the page
+	Body []byte
+	// Forms are the HTML forms on the page
+	Forms []Form
+}
+
+// Form is an HTML form on a page.
+type Form struct {
+	// Action is the absolute URL the form submits to
+	Action *url.URL
+	// Method is either GET or POST
+	Method string
+	// Values are the values of the form's inputs, including hidden ones like CSRF tokens
+	Values url.Values
+	// Buttons are the submit buttons of the form, by name
+	Buttons map[string]string
+}
+
+// New returns a browser with an empty cookie jar that uses the transport of client, such as the client returned by
+// the platform's HTTPClient.
+func New(client *http.Client) (*Browser, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cookie jar: %w", err)
+	}
+
+	return &Browser{Client: &http.Client{Transport: client.Transport, Jar: jar, Timeout: requestTimeout}}, nil
+}
+
+// Get loads the page at rawURL.
+func (browser *Browser) Get(rawURL string) (*Page, error) {
+	request, err := http.NewRequest(http.MethodGet, rawURL, nil) //nolint:noctx
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request for %s: %w", rawURL, err)
+	}
+
+	return browser.Do(request)
+}
+
+// Submit fills in the form with values, on top of the values it already has, and submits it. Set button to the name
+// of the submit button to press, or leave it empty to submit without one.
+func (browser *Browser) Submit(form Form, values map[string]string, button string) (*Page, error) {
+	data := url.Values{}
+	for key, value := range form.Values {
+		data[key] = append([]string(nil), value...)
+	}
+	for key, value := range values {
+		data.Set(key, value)
+	}
+	if button != "" {
+		value, ok := form.Buttons[button]
+		if !ok {
+			return nil, fmt.Errorf("form %s has no button %s", form.Action, button)
+		}
+		data.Set(button, value)
+	}
+
+	var request *http.Request
+	var err error
+	if form.Method == http.MethodGet {
+		action := *form.Action
+		action.RawQuery = data.Encode()
+		request, err = http.NewRequest(http.MethodGet, action.String(), nil) //nolint:noctx
+	} else {
+		request, err = http.NewRequest(http.MethodPost, form.Action.String(), strings.NewReader(data.Encode())) //nolint:noctx
+		if request != nil {
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request for %s: %w", form.Action, err)
+	}
+
+	return browser.Do(request)
+}
+
+// Do sends the request, follows its redirects, and parses the page it ends up on.
+func (browser *Browser) Do(request *http.Request) (*Page, error) {
+	response, err := browser.Client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to %s %s: %w", request.Method, request.URL, err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", response.Request.URL, err)
+	}
+	page := &Page{URL: response.Request.URL, StatusCode: response.StatusCode, Body: body}
+	if strings.Contains(response.Header.Get("Content-Type"), "html") {
+		page.Forms, err = parseForms(page.URL, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// FormWithField returns the first form on the page with an input of the given name.
+func (page *Page) FormWithField(name string) (Form, bool) {
+	for _, form := range page.Forms {
+		if _, ok := form.Values[name]; ok {
+			return form, true
+		}
+	}
+
+	return Form{}, false
+}
+
+// FormWithButton returns the first form on the page with a submit button of the given name.
+func (page *Page) FormWithButton(name string) (Form, bool) {
+	for _, form := range page.Forms {
+		if _, ok := form.Buttons[name]; ok {
+			return form, true
+		}
+	}
+
+	return Form{}, false
+}
+
+// FormWithAction returns the first form on the page that submits to a path ending in suffix.
+func (page *Page) FormWithAction(suffix string) (Form, bool) {
+	for _, form := range page.Forms {
+		if strings.HasSuffix(form.Action.Path, suffix) {
+			return form, true
+		}
+	}
+
+	return Form{}, false
+}
+
+// Title returns the title of the page, which helps explain where a flow got stuck.
+func (page *Page) Title() string {
+	doc, err := html.Parse(bytes.NewReader(page.Body))
+	if err != nil {
+		return ""
+	}
+	var title string
+	walk(doc, func(node *html.Node) {
+		if title == "" && node.Type == html.ElementNode && node.Data == "title" && node.FirstChild != nil {
+			title = strings.TrimSpace(node.FirstChild.Data)
+		}
+	})
+
+	return title
+}
+
+// Meta returns the content of the meta tag of the page with the given name, such as the CSRF token of a Rails app.
+func (page *Page) Meta(name string) string {
+	doc, err := html.Parse(bytes.NewReader(page.Body))
+	if err != nil {
+		return ""
+	}
+	var content string
+	walk(doc, func(node *html.Node) {
+		if content == "" && node.Type == html.ElementNode && node.Data == "meta" && attr(node, "name") == name {
+			content = attr(node, "content")
+		}
+	})
+
+	return content
+}
+
+// parseForms finds the forms in an HTML page, resolving their actions against the URL of the page.
+func parseForms(pageURL *url.URL, body []byte) ([]Form, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", pageURL, err)
+	}
+	var forms []Form
+	var parseErr error
+	walk(doc, func(node *html.Node) {
+		if node.Type != html.ElementNode || node.Data != "form" || parseErr != nil {
+			return
+		}
+		action, err := pageURL.Parse(attr(node, "action"))
+		if err != nil {
+			parseErr = fmt.Errorf("form on %s has an invalid action: %w", pageURL, err)
+
+			return
+		}
+		form := Form{Action: action, Method: strings.ToUpper(attr(node, "method")), Values: url.Values{}, Buttons: make(map[string]string)}
+		if form.Method != http.MethodPost {
+			form.Method = http.MethodGet
+		}
+		walk(node, func(field *html.Node) {
+			name := attr(field, "name")
+			if field.Type != html.ElementNode || name == "" {
+				return
+			}
+			fieldType := strings.ToLower(attr(field, "type"))
+			switch {
+			case field.Data == "button" || (field.Data == "input" && fieldType == "submit"):
+				form.Buttons[name] = attr(field, "value")
+			case field.Data == "input" && (fieldType == "checkbox" || fieldType == "radio"):
+				if hasAttr(field, "checked") {
+					form.Values.Add(name, attr(field, "value"))
+				}
+			case field.Data == "input" || field.Data == "textarea":
+				form.Values.Add(name, attr(field, "value"))
+			}
+		})
+		forms = append(forms, form)
+	})
+
+	return forms, parseErr
+}
+
+// walk calls visit for node and everything below it, in document order.
+func walk(node *html.Node, visit func(*html.Node)) {
+	visit(node)
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, visit)
+	}
+}
+
+// attr returns the value of an attribute of an HTML element, or an empty string if it doesn't have it.
+func attr(node *html.Node, key string) string {
+	for _, attribute := range node.Attr {
+		if attribute.Key == key {
+			return attribute.Val
+		}
+	}
+
+	return ""
+}
+
+// hasAttr returns true if an HTML element has the attribute, with or without a value.
+func hasAttr(node *html.Node, key string) bool {
+	for _, attribute := range node.Attr {
+		if attribute.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
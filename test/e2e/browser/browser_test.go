@@ -0,0 +1,72 @@
+package browser_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/browser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmit(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(writer http.ResponseWriter, request *http.Request) {
+		http.SetCookie(writer, &http.Cookie{Name: "session", Value: "abc"})
+		writer.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(writer, `<html><head><title>Sign in</title><meta name="csrf-token" content="meta-token"></head><body>
+<form action="/search"><input name="q"></form>
+<form id="login" method="post" action="authenticate?step=1">
+  <input type="hidden" name="csrf" value="token">
+  <input type="text" name="username">
+  <input type="password" name="password">
+  <input type="checkbox" name="remember" value="on">
+  <input type="submit" name="login" value="Sign In">
+</form></body></html>`)
+	})
+	mux.HandleFunc("/authenticate", func(writer http.ResponseWriter, request *http.Request) {
+		cookie, err := request.Cookie("session")
+		if err != nil || request.Method != http.MethodPost || request.URL.Query().Get("step") != "1" {
+			http.Error(writer, "bad request", http.StatusBadRequest)
+
+			return
+		}
+		_ = request.ParseForm()
+		query := url.Values{
+			"user":     {request.PostForm.Get("username")},
+			"csrf":     {request.PostForm.Get("csrf")},
+			"remember": {request.PostForm.Get("remember")},
+			"login":    {request.PostForm.Get("login")},
+			"cookie":   {cookie.Value},
+		}
+		http.Redirect(writer, request, "/home?"+query.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/home", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, request.URL.RawQuery)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	session, err := browser.New(server.Client())
+	require.NoError(t, err)
+	page, err := session.Get(server.URL + "/login")
+	require.NoError(t, err)
+	require.Equal(t, "Sign in", page.Title())
+	require.Equal(t, "meta-token", page.Meta("csrf-token"))
+	require.Len(t, page.Forms, 2)
+
+	form, ok := page.FormWithField("password")
+	require.True(t, ok)
+	require.Equal(t, http.MethodPost, form.Method)
+	require.Equal(t, server.URL+"/authenticate?step=1", form.Action.String())
+	_, ok = page.FormWithAction("/search")
+	require.True(t, ok)
+
+	page, err = session.Submit(form, map[string]string{"username": "alice", "password": "secret"}, "login")
+	require.NoError(t, err)
+	require.Equal(t, "/home", page.URL.Path)
+	require.Equal(t, "cookie=abc&csrf=token&login=Sign+In&remember=&user=alice", string(page.Body))
+}
@@ -0,0 +1,102 @@
+// Package bundle reads the UDS bundle definition of the software factory, and the Zarf packages the repo builds for it
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is the UDS bundle definition, relative to the root of the repo.
+const File = "uds-bundle.yaml"
+
+// Bundle is a UDS bundle definition.
+type Bundle struct {
+	Kind     string    `yaml:"kind"`
+	Metadata Metadata  `yaml:"metadata"`
+	Packages []Package `yaml:"packages"`
+}
+
+// Metadata describes the bundle itself.
+type Metadata struct {
+	Name         string `yaml:"name"`
+	Description  string `yaml:"description"`
+	Version      string `yaml:"version"`
+	Architecture string `yaml:"architecture"`
+}
+
+// Package is a Zarf package in the bundle. It is pulled from Repository if that is set, otherwise it is read from the
+// local folder Path.
+type Package struct {
+	Name               string     `yaml:"name"`
+	Repository         string     `yaml:"repository,omitempty"`
+	Path               string     `yaml:"path,omitempty"`
+	Ref                string     `yaml:"ref"`
+	OptionalComponents []string   `yaml:"optional-components,omitempty"`
+	Imports            []Variable `yaml:"imports,omitempty"`
+	Exports            []Variable `yaml:"exports,omitempty"`
+}
+
+// Variable is a variable that a package exports, or imports from another package.
+type Variable struct {
+	Name string `yaml:"name"`
+	// Package is the package an imported variable comes from. It is empty for exported variables.
+	Package string `yaml:"package,omitempty"`
+}
+
+// Read parses the bundle definition in the root of the repo.
+func Read(repoRoot string) (*Bundle, error) {
+	bundle := new(Bundle)
+	if err := readYaml(filepath.Join(repoRoot, File), bundle); err != nil {
+		return nil, err
+	}
+	if bundle.Kind != "UDSBundle" {
+		return nil, fmt.Errorf("%s is a %q, not a UDSBundle", File, bundle.Kind)
+	}
+	exported := make(map[string]map[string]bool)
+	for _, pkg := range bundle.Packages {
+		if pkg.Name == "" || pkg.Ref == "" {
+			return nil, fmt.Errorf("every package in %s needs a name and a ref, found %+v", File, pkg)
+		}
+		for _, variable := range pkg.Imports {
+			if !exported[variable.Package][variable.Name] {
+				return nil, fmt.Errorf("package %s imports %s from %s, which isn't exported by an earlier package", pkg.Name, variable.Name, variable.Package)
+			}
+		}
+		exported[pkg.Name] = make(map[string]bool)
+		for _, variable := range pkg.Exports {
+			exported[pkg.Name][variable.Name] = true
+		}
+	}
+
+	return bundle, nil
+}
+
+// Package returns the package with the given name, or false if the bundle doesn't have it.
+func (bundle *Bundle) Package(name string) (Package, bool) {
+	for _, pkg := range bundle.Packages {
+		if pkg.Name == name {
+			return pkg, true
+		}
+	}
+
+	return Package{}, false
+}
+
+// readYaml parses the YAML file at path into value, rejecting fields that value doesn't have.
+func readYaml(path string, value interface{}) error {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", path, err)
+	}
+	defer file.Close()
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(value); err != nil {
+		return fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package bundle_test
+
+import (
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+	udsBundle, err := bundle.Read("../../..")
+	require.NoError(t, err)
+	require.Equal(t, "software-factory-demo", udsBundle.Metadata.Name)
+
+	initPackage, ok := udsBundle.Package("init")
+	require.True(t, ok)
+	require.Equal(t, "ghcr.io/defenseunicorns/packages/init", initPackage.Repository)
+	require.Equal(t, []string{"git-server"}, initPackage.OptionalComponents)
+
+	idam, ok := udsBundle.Package("uds-idam")
+	require.True(t, ok)
+	require.Contains(t, idam.Imports, bundle.Variable{Name: "REALM_IMPORT_FILE", Package: "software-factory-idam-realm"})
+
+	namespaces, ok := udsBundle.Package("software-factory-namespaces")
+	require.True(t, ok)
+	require.Equal(t, "build", namespaces.Path)
+}
+
+func TestReadConfig(t *testing.T) {
+	t.Parallel()
+	udsBundle, err := bundle.Read("../../..")
+	require.NoError(t, err)
+	config, err := udsBundle.ReadConfig("../../..")
+	require.NoError(t, err)
+
+	name, ok := config.Variable("jira", "JIRA_DB_NAME")
+	require.True(t, ok)
+	require.Equal(t, "jiradb", name)
+	_, ok = config.Variable("jira", "CONFLUENCE_DB_NAME")
+	require.False(t, ok)
+}
+
+func TestManifests(t *testing.T) {
+	t.Parallel()
+	resources, err := bundle.Manifests("../../..")
+	require.NoError(t, err)
+	require.Contains(t, resources, bundle.Resource{Kind: "Service", Namespace: "keycloak", Name: "keycloak-postgresql"})
+	require.Contains(t, resources, bundle.Resource{Kind: "Secret", Namespace: "gitlab", Name: "gitlab-sso-provider"})
+	require.Contains(t, resources, bundle.Resource{Kind: "PolicyException", Namespace: "keycloak", Name: "keycloak-postgres-registry-exception"})
+	require.Contains(t, resources, bundle.Resource{Kind: "PolicyException", Namespace: "jira", Name: "jira-non-root-exceptions"})
+
+	namespaces, err := bundle.Namespaces("../../..")
+	require.NoError(t, err)
+	require.Contains(t, namespaces, bundle.Resource{Kind: "Namespace", Name: "gitlab-runner-sandbox"})
+	require.Equal(t, "Namespace gitlab-runner-sandbox", bundle.Resource{Kind: "Namespace", Name: "gitlab-runner-sandbox"}.String())
+}
@@ -0,0 +1,39 @@
+package bundle
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// ConfigFile is the UDS config the bundle is deployed with, relative to the root of the repo.
+const ConfigFile = "uds-config.yaml"
+
+// Config is a UDS config, which sets the variables of the packages in a bundle.
+type Config struct {
+	Options map[string]interface{} `yaml:"options,omitempty"`
+	// Variables are the values of variables, by package and then by variable name
+	Variables map[string]map[string]string `yaml:"variables,omitempty"`
+}
+
+// ReadConfig parses the UDS config in the root of the repo, and checks that it only sets variables of packages in the
+// bundle.
+func (bundle *Bundle) ReadConfig(repoRoot string) (*Config, error) {
+	config := new(Config)
+	if err := readYaml(filepath.Join(repoRoot, ConfigFile), config); err != nil {
+		return nil, err
+	}
+	for name := range config.Variables {
+		if _, ok := bundle.Package(name); !ok {
+			return nil, fmt.Errorf("%s sets variables of package %s, which isn't in %s", ConfigFile, name, File)
+		}
+	}
+
+	return config, nil
+}
+
+// Variable returns the value the config sets for a variable of a package, or false if it doesn't set it.
+func (config *Config) Variable(pkg string, name string) (string, bool) {
+	value, ok := config.Variables[pkg][name]
+
+	return value, ok
+}
@@ -0,0 +1,141 @@
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PackagesDir holds the Zarf packages the repo builds for the bundle, relative to the root of the repo.
+	PackagesDir = "packages"
+	// NamespacesFile lists the namespaces software-factory-namespaces creates, relative to the root of the repo.
+	NamespacesFile = "packages/namespaces/values.yaml"
+)
+
+// Resource identifies a Kubernetes resource.
+type Resource struct {
+	Kind      string `yaml:"kind"`
+	Namespace string `yaml:"namespace,omitempty"`
+	Name      string `yaml:"name"`
+}
+
+// zarfPackage is the part of a Zarf package definition that lists its manifests.
+type zarfPackage struct {
+	Kind       string `yaml:"kind"`
+	Components []struct {
+		Manifests []struct {
+			Namespace string   `yaml:"namespace"`
+			Files     []string `yaml:"files"`
+		} `yaml:"manifests"`
+	} `yaml:"components"`
+}
+
+// String returns the resource the way kubectl refers to it, with its namespace, such as "Service keycloak/postgresql".
+func (resource Resource) String() string {
+	if resource.Namespace == "" {
+		return fmt.Sprintf("%s %s", resource.Kind, resource.Name)
+	}
+
+	return fmt.Sprintf("%s %s/%s", resource.Kind, resource.Namespace, resource.Name)
+}
+
+// Namespaces returns the namespaces in NamespacesFile.
+func Namespaces(repoRoot string) ([]Resource, error) {
+	var values struct {
+		Namespaces []struct {
+			Name string `yaml:"name"`
+		} `yaml:"namespaces"`
+	}
+	content, err := os.ReadFile(filepath.Join(repoRoot, NamespacesFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", NamespacesFile, err)
+	}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", NamespacesFile, err)
+	}
+	namespaces := make([]Resource, 0, len(values.Namespaces))
+	for _, namespace := range values.Namespaces {
+		namespaces = append(namespaces, Resource{Kind: "Namespace", Name: namespace.Name})
+	}
+
+	return namespaces, nil
+}
+
+// Manifests returns the resources in the manifests of the Zarf packages in PackagesDir, sorted by kind, namespace and
+// name. A resource without a namespace gets the one its manifest is deployed to.
+func Manifests(repoRoot string) ([]Resource, error) {
+	definitions, err := filepath.Glob(filepath.Join(repoRoot, PackagesDir, "*", "zarf.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the packages in %s: %w", PackagesDir, err)
+	}
+	var resources []Resource
+	for _, definition := range definitions {
+		content, err := os.ReadFile(definition)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", definition, err)
+		}
+		var pkg zarfPackage
+		if err := yaml.Unmarshal(content, &pkg); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", definition, err)
+		}
+		if pkg.Kind != "ZarfPackageConfig" {
+			return nil, fmt.Errorf("%s is a %q, not a ZarfPackageConfig", definition, pkg.Kind)
+		}
+		for _, component := range pkg.Components {
+			for _, manifest := range component.Manifests {
+				for _, file := range manifest.Files {
+					found, err := readManifest(filepath.Join(filepath.Dir(definition), file), manifest.Namespace)
+					if err != nil {
+						return nil, err
+					}
+					resources = append(resources, found...)
+				}
+			}
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].String() < resources[j].String()
+	})
+
+	return resources, nil
+}
+
+// readManifest returns the resources in every document of a manifest file, giving the ones without a namespace the
+// default namespace.
+func readManifest(path string, defaultNamespace string) ([]Resource, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %w", path, err)
+	}
+	defer file.Close()
+	var resources []Resource
+	decoder := yaml.NewDecoder(file)
+	for {
+		var document struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Namespace string `yaml:"namespace"`
+				Name      string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		if err := decoder.Decode(&document); errors.Is(err, io.EOF) {
+			return resources, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+		if document.Kind == "" {
+			continue
+		}
+		resource := Resource{Kind: document.Kind, Namespace: document.Metadata.Namespace, Name: document.Metadata.Name}
+		if resource.Namespace == "" {
+			resource.Namespace = defaultNamespace
+		}
+		resources = append(resources, resource)
+	}
+}
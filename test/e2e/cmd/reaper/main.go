@@ -0,0 +1,110 @@
+// Command reaper finds AWS infrastructure left behind by e2e test runs that crashed before they could tear down, reports
+// what it is costing, and destroys it. Run it with `make test-reap`.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/reaper"
+)
+
+func main() {
+	region := flag.String("region", defaultRegion(), "the AWS region to look in, defaults to AWS_REGION or AWS_DEFAULT_REGION")
+	olderThan := flag.Duration("older-than", 6*time.Hour, "only reap runs created longer ago than this") //nolint:gomnd
+	yes := flag.Bool("yes", false, "destroy without asking for confirmation")
+	endpoint := flag.String("endpoint", "", "use a different EC2 endpoint, such as a local AWS API stand-in")
+	flag.Parse()
+
+	if *region == "" {
+		exit("expected either --region or the AWS_REGION or AWS_DEFAULT_REGION env var to be set, but they were not")
+	}
+	config := aws.NewConfig().WithRegion(*region)
+	if *endpoint != "" {
+		config = config.WithEndpoint(*endpoint)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		exit(fmt.Sprintf("unable to create aws session: %v", err))
+	}
+	r := reaper.New(ec2.New(sess))
+
+	runs, err := r.Find(*olderThan)
+	if err != nil {
+		exit(err.Error())
+	}
+	if len(runs) == 0 {
+		fmt.Printf("No test infrastructure older than %v found in %s\n", *olderThan, *region)
+
+		return
+	}
+
+	total := 0.0
+	for _, run := range runs {
+		age := "unknown age"
+		if createdAt := run.CreatedAt(); !createdAt.IsZero() {
+			age = fmt.Sprintf("created %s ago", time.Since(createdAt).Round(time.Minute))
+		}
+		hourly, known := run.HourlyCost()
+		cost := fmt.Sprintf("~$%.2f/hour", hourly)
+		if !known {
+			cost += " plus instances of unknown cost"
+		}
+		fmt.Printf("%s (%s, %s)\n", run.Name, age, cost)
+		for _, resource := range run.Resources {
+			fmt.Printf("  %-15s %s %s\n", resource.Kind, resource.ID, resource.InstanceType)
+		}
+		if createdAt := run.CreatedAt(); !createdAt.IsZero() {
+			total += hourly * time.Since(createdAt).Hours()
+		}
+	}
+	fmt.Printf("Estimated cost so far: ~$%.2f\n", total)
+
+	if !*yes && !confirm(fmt.Sprintf("Destroy %d runs?", len(runs))) {
+		fmt.Println("Nothing was destroyed")
+
+		return
+	}
+	failed := false
+	for _, run := range runs {
+		fmt.Printf("Destroying %s\n", run.Name)
+		if err := r.Destroy(run); err != nil {
+			fmt.Fprintf(os.Stderr, "error destroying %s: %v\n", run.Name, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// defaultRegion returns AWS_REGION, falling back to AWS_DEFAULT_REGION.
+func defaultRegion() string {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// confirm asks a yes/no question on the terminal.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+// exit prints the error and exits.
+func exit(message string) {
+	fmt.Fprintf(os.Stderr, "error: %s\n", message)
+	os.Exit(1)
+}
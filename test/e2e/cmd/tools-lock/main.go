@@ -0,0 +1,32 @@
+// Command tools-lock regenerates the lock file that pins the checksums of the tools installed on the e2e test host.
+// Run it from the root of the repo with `make test-tools-lock` whenever a version in .tool-versions or the Makefile changes.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/tools"
+)
+
+func main() {
+	repoRoot := flag.String("root", ".", "path to the root of the repo")
+	flag.Parse()
+
+	client := &http.Client{Timeout: 10 * time.Minute} //nolint:gomnd
+	lock, err := tools.GenerateLock(*repoRoot, client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error generating lock file: %v\n", err)
+		os.Exit(1)
+	}
+	if err := tools.WriteLock(*repoRoot, lock); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing lock file: %v\n", err)
+		os.Exit(1)
+	}
+	for _, tool := range lock.Tools {
+		fmt.Printf("%v %v %v\n", tool.Name, tool.Version, tool.SHA256)
+	}
+}
@@ -0,0 +1,312 @@
+// Package drift compares the state of the cluster before and after the software factory bundle is deployed again, to
+// find what a deployment that should be idempotent changed.
+package drift
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"gopkg.in/yaml.v3"
+)
+
+// ExpectedFile lists the changes a redeploy is expected to make, relative to the root of the repo.
+const ExpectedFile = "test/e2e/drift.yaml"
+
+// Change is how a resource changed between two snapshots.
+type Change string
+
+const (
+	// ChangeAdded is a resource that only the second snapshot has
+	ChangeAdded Change = "added"
+	// ChangeRemoved is a resource that only the first snapshot has
+	ChangeRemoved Change = "removed"
+	// ChangeRecreated is a resource that was deleted and created again, so that it has a new UID
+	ChangeRecreated Change = "recreated"
+	// ChangeGeneration is a resource whose spec changed, such as a workload that was restarted with a new pod template
+	ChangeGeneration Change = "generation"
+	// ChangeData is a secret or config map whose data changed, such as a password that was rotated
+	ChangeData Change = "data"
+	// ChangePods is a workload whose pods were replaced
+	ChangePods Change = "pods"
+	// ChangeRestarts is a workload whose containers restarted
+	ChangeRestarts Change = "restarts"
+	// ChangeCorefile is a Corefile of CoreDNS that changed
+	ChangeCorefile Change = "corefile"
+	// ChangeDuplicateHost is a host that the Corefile of CoreDNS maps more than once
+	ChangeDuplicateHost Change = "duplicate host"
+)
+
+// Corefile is the ConfigMap that holds the Corefile of CoreDNS, which software-factory-idam-dns adds hosts to.
+var Corefile = bundle.Resource{Kind: "ConfigMap", Namespace: "kube-system", Name: "coredns"}
+
+// Object is the state of a resource in a snapshot.
+type Object struct {
+	bundle.Resource
+	// UID changes when the resource is deleted and created again
+	UID string
+	// Generation changes when the spec of the resource does
+	Generation int64
+	// Hash is the SHA-256 of the data of a secret or config map, so that snapshots don't hold secrets
+	Hash string
+	// Pods is the SHA-256 of the UIDs of the pods of a workload, which changes when they are replaced
+	Pods string
+	// Restarts is how often the containers of the pods of a workload restarted
+	Restarts int32
+}
+
+// Snapshot is the state of the resources in the cluster at one point in time.
+type Snapshot struct {
+	// Objects are the resources, by resource
+	Objects map[bundle.Resource]Object
+	// Corefile is the Corefile of CoreDNS
+	Corefile string
+}
+
+// Difference is a change of a resource between two snapshots.
+type Difference struct {
+	Resource bundle.Resource
+	Change   Change
+	// Detail says what changed, if the change itself doesn't
+	Detail string
+}
+
+// Expected is a change that a redeploy is expected to make. Namespace and Name may be patterns like "sh.helm.*", see
+// path.Match, and an empty Change matches every change.
+type Expected struct {
+	bundle.Resource `yaml:",inline"`
+	Change          Change `yaml:"change,omitempty"`
+	// Reason is why the redeploy makes the change
+	Reason string `yaml:"reason"`
+}
+
+// ExpectedDifference is a difference that was expected, with the reason it was.
+type ExpectedDifference struct {
+	Difference
+	Reason string
+}
+
+// Report is what changed between two snapshots.
+type Report struct {
+	// Unexpected are the differences that aren't in the expected changes
+	Unexpected []Difference
+	// Expected are the differences that are in the expected changes
+	Expected []ExpectedDifference
+}
+
+// NewSnapshot returns an empty snapshot.
+func NewSnapshot() *Snapshot {
+	return &Snapshot{Objects: make(map[bundle.Resource]Object)}
+}
+
+// Add adds a resource to the snapshot.
+func (snapshot *Snapshot) Add(object Object) {
+	snapshot.Objects[object.Resource] = object
+}
+
+// String returns the resource and the change, followed by the detail if there is one, such as
+// "Secret gitlab/gitlab-sso-provider: data".
+func (difference Difference) String() string {
+	if difference.Detail == "" {
+		return fmt.Sprintf("%s: %s", difference.Resource, difference.Change)
+	}
+
+	return fmt.Sprintf("%s: %s, %s", difference.Resource, difference.Change, difference.Detail)
+}
+
+// ReadExpected returns the changes in ExpectedFile. Every one of them needs a reason.
+func ReadExpected(repoRoot string) ([]Expected, error) {
+	var file struct {
+		Expected []Expected `yaml:"expected"`
+	}
+	content, err := os.ReadFile(filepath.Join(repoRoot, ExpectedFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", ExpectedFile, err)
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", ExpectedFile, err)
+	}
+	for _, expected := range file.Expected {
+		if expected.Kind == "" || expected.Name == "" || expected.Reason == "" {
+			return nil, fmt.Errorf("every change in %s needs a kind, a name and a reason, found %+v", ExpectedFile, expected)
+		}
+		if _, err := path.Match(expected.Name, ""); err != nil {
+			return nil, fmt.Errorf("%s in %s is not a valid pattern: %w", expected.Name, ExpectedFile, err)
+		}
+	}
+
+	return file.Expected, nil
+}
+
+// Matches returns true if the difference is the expected change.
+func (expected Expected) Matches(difference Difference) bool {
+	if expected.Kind != difference.Resource.Kind || (expected.Change != "" && expected.Change != difference.Change) {
+		return false
+	}
+	namespaceMatches, _ := path.Match(expected.Namespace, difference.Resource.Namespace)
+	nameMatches, _ := path.Match(expected.Name, difference.Resource.Name)
+
+	return namespaceMatches && nameMatches
+}
+
+// Compare returns what changed between the snapshots, sorted by resource, and sorts the differences into expected and
+// unexpected ones. A host the Corefile of after maps more than once is a difference even if before had it too.
+func Compare(before *Snapshot, after *Snapshot, expected []Expected) *Report {
+	var differences []Difference
+	for resource, old := range before.Objects {
+		current, ok := after.Objects[resource]
+		if !ok {
+			differences = append(differences, Difference{Resource: resource, Change: ChangeRemoved})
+
+			continue
+		}
+		differences = append(differences, compareObject(old, current)...)
+	}
+	for resource := range after.Objects {
+		if _, ok := before.Objects[resource]; !ok {
+			differences = append(differences, Difference{Resource: resource, Change: ChangeAdded})
+		}
+	}
+	if before.Corefile != after.Corefile {
+		differences = append(differences, Difference{Resource: Corefile, Change: ChangeCorefile, Detail: lineChanges(before.Corefile, after.Corefile)})
+	}
+	for _, host := range DuplicateHosts(after.Corefile) {
+		differences = append(differences, Difference{Resource: Corefile, Change: ChangeDuplicateHost, Detail: host})
+	}
+	sort.SliceStable(differences, func(i, j int) bool {
+		return differences[i].String() < differences[j].String()
+	})
+
+	report := new(Report)
+	for _, difference := range differences {
+		if reason, ok := expectedReason(expected, difference); ok {
+			report.Expected = append(report.Expected, ExpectedDifference{Difference: difference, Reason: reason})
+		} else {
+			report.Unexpected = append(report.Unexpected, difference)
+		}
+	}
+
+	return report
+}
+
+// DuplicateHosts returns the hosts that the hosts blocks of a Corefile map more than once, as the address and the
+// host, such as "10.0.0.1 keycloak.bigbang.dev".
+func DuplicateHosts(corefile string) []string {
+	counts := make(map[string]int)
+	inHosts := false
+	for _, line := range strings.Split(corefile, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "hosts" && fields[len(fields)-1] == "{":
+			inHosts = true
+		case inHosts && fields[0] == "}":
+			inHosts = false
+		case inHosts && net.ParseIP(fields[0]) != nil:
+			for _, host := range fields[1:] {
+				counts[fields[0]+" "+host]++
+			}
+		}
+	}
+	var duplicates []string
+	for entry, count := range counts {
+		if count > 1 {
+			duplicates = append(duplicates, entry)
+		}
+	}
+	sort.Strings(duplicates)
+
+	return duplicates
+}
+
+// OK returns true if nothing changed that wasn't expected to.
+func (report *Report) OK() bool {
+	return len(report.Unexpected) == 0
+}
+
+// String returns the unexpected differences, followed by the expected ones with their reason.
+func (report *Report) String() string {
+	var text strings.Builder
+	if report.OK() {
+		text.WriteString("Nothing changed unexpectedly when the bundle was deployed again\n")
+	} else {
+		text.WriteString("Changed when the bundle was deployed again:\n")
+		for _, difference := range report.Unexpected {
+			fmt.Fprintf(&text, "  %s\n", difference)
+		}
+	}
+	if len(report.Expected) > 0 {
+		text.WriteString("Expected:\n")
+		for _, difference := range report.Expected {
+			fmt.Fprintf(&text, "  %s (%s)\n", difference.Difference, difference.Reason)
+		}
+	}
+
+	return text.String()
+}
+
+// compareObject returns what changed between two states of the same resource. A resource that was recreated isn't
+// compared any further.
+func compareObject(before Object, after Object) []Difference {
+	if before.UID != after.UID {
+		return []Difference{{Resource: before.Resource, Change: ChangeRecreated}}
+	}
+	var differences []Difference
+	if before.Generation != after.Generation {
+		differences = append(differences, Difference{Resource: before.Resource, Change: ChangeGeneration, Detail: fmt.Sprintf("%d to %d", before.Generation, after.Generation)})
+	}
+	if before.Hash != after.Hash {
+		differences = append(differences, Difference{Resource: before.Resource, Change: ChangeData})
+	}
+	if before.Pods != after.Pods {
+		differences = append(differences, Difference{Resource: before.Resource, Change: ChangePods})
+	}
+	if after.Restarts > before.Restarts {
+		differences = append(differences, Difference{Resource: before.Resource, Change: ChangeRestarts, Detail: fmt.Sprintf("%d more", after.Restarts-before.Restarts)})
+	}
+
+	return differences
+}
+
+// expectedReason returns the reason of the first expected change the difference matches, or false if it matches none.
+func expectedReason(expected []Expected, difference Difference) (string, bool) {
+	for _, change := range expected {
+		if change.Matches(difference) {
+			return change.Reason, true
+		}
+	}
+
+	return "", false
+}
+
+// lineChanges describes the lines that were removed from before and added to after, ignoring their order and
+// indentation, such as `removed "a", added "b"`.
+func lineChanges(before string, after string) string {
+	counts := make(map[string]int)
+	for _, line := range strings.Split(before, "\n") {
+		counts[strings.TrimSpace(line)]--
+	}
+	for _, line := range strings.Split(after, "\n") {
+		counts[strings.TrimSpace(line)]++
+	}
+	var changes []string
+	for line, count := range counts {
+		switch {
+		case count < 0:
+			changes = append(changes, fmt.Sprintf("removed %q", line))
+		case count > 0:
+			changes = append(changes, fmt.Sprintf("added %q", line))
+		}
+	}
+	sort.Strings(changes)
+	if len(changes) == 0 {
+		return "only the indentation or order of lines changed"
+	}
+
+	return strings.Join(changes, ", ")
+}
@@ -0,0 +1,118 @@
+package drift_test
+
+import (
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/drift"
+	"github.com/stretchr/testify/require"
+)
+
+// corefile is the Corefile of CoreDNS in k3d after coredns-add-hostname.sh added the hosts of the software factory.
+const corefile = `.:53 {
+    errors
+    health
+    ready
+    hosts /etc/coredns/NodeHosts {
+      #swf-begin
+      10.0.0.1 keycloak.bigbang.dev
+      10.0.0.2 gitlab.bigbang.dev sonarqube.bigbang.dev
+      #swf-end
+      ttl 60
+      reload 15s
+      fallthrough
+    }
+    kubernetes cluster.local in-addr.arpa ip6.arpa {
+      pods insecure
+      fallthrough in-addr.arpa ip6.arpa
+    }
+}
+`
+
+func TestReadExpected(t *testing.T) {
+	t.Parallel()
+	expected, err := drift.ReadExpected("../../..")
+	require.NoError(t, err)
+	require.NotEmpty(t, expected)
+	require.True(t, expected[0].Matches(drift.Difference{
+		Resource: bundle.Resource{Kind: "Secret", Namespace: "gitlab", Name: "sh.helm.release.v1.gitlab.v2"},
+		Change:   drift.ChangeAdded,
+	}))
+	require.False(t, expected[0].Matches(drift.Difference{
+		Resource: bundle.Resource{Kind: "Secret", Namespace: "gitlab", Name: "gitlab-sso-provider"},
+		Change:   drift.ChangeAdded,
+	}))
+
+	coredns := bundle.Resource{Kind: "Deployment", Namespace: "kube-system", Name: "coredns"}
+	for _, change := range []drift.Change{drift.ChangeGeneration, drift.ChangePods, drift.ChangeRestarts, drift.ChangeRecreated} {
+		matched := false
+		for _, entry := range expected {
+			require.NotEmpty(t, entry.Change, "%s/%s/%s in %s matches every change", entry.Kind, entry.Namespace, entry.Name, drift.ExpectedFile)
+			matched = matched || entry.Matches(drift.Difference{Resource: coredns, Change: change})
+		}
+		require.Equal(t, change == drift.ChangeGeneration || change == drift.ChangePods, matched, "change %s of CoreDNS", change)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+	gitlab := bundle.Resource{Kind: "Deployment", Namespace: "gitlab", Name: "gitlab-webservice-default"}
+	postgres := bundle.Resource{Kind: "StatefulSet", Namespace: "keycloak", Name: "postgresql"}
+	password := bundle.Resource{Kind: "Secret", Namespace: "keycloak", Name: "keycloak-postgres"}
+	claim := bundle.Resource{Kind: "PersistentVolumeClaim", Namespace: "gitlab-db", Name: "data-postgresql-0"}
+	release := bundle.Resource{Kind: "Secret", Namespace: "gitlab", Name: "sh.helm.release.v1.gitlab.v2"}
+
+	before := drift.NewSnapshot()
+	before.Add(drift.Object{Resource: gitlab, UID: "1", Generation: 1, Pods: "a"})
+	before.Add(drift.Object{Resource: postgres, UID: "2", Generation: 1, Pods: "b", Restarts: 1})
+	before.Add(drift.Object{Resource: password, UID: "3", Hash: "x"})
+	before.Add(drift.Object{Resource: claim, UID: "4"})
+	before.Corefile = corefile
+	after := drift.NewSnapshot()
+	after.Add(drift.Object{Resource: gitlab, UID: "1", Generation: 2, Pods: "c"})
+	after.Add(drift.Object{Resource: postgres, UID: "2", Generation: 1, Pods: "b", Restarts: 3})
+	after.Add(drift.Object{Resource: password, UID: "3", Hash: "y"})
+	after.Add(drift.Object{Resource: claim, UID: "5"})
+	after.Add(drift.Object{Resource: release, UID: "6"})
+	after.Corefile = corefile
+
+	report := drift.Compare(before, after, []drift.Expected{{Resource: bundle.Resource{Kind: "Secret", Namespace: "*", Name: "sh.helm.release.v1.*"}, Change: drift.ChangeAdded, Reason: "new revision"}})
+	require.False(t, report.OK())
+	require.Equal(t, `Changed when the bundle was deployed again:
+  Deployment gitlab/gitlab-webservice-default: generation, 1 to 2
+  Deployment gitlab/gitlab-webservice-default: pods
+  PersistentVolumeClaim gitlab-db/data-postgresql-0: recreated
+  Secret keycloak/keycloak-postgres: data
+  StatefulSet keycloak/postgresql: restarts, 2 more
+Expected:
+  Secret gitlab/sh.helm.release.v1.gitlab.v2: added (new revision)
+`, report.String())
+
+	require.True(t, drift.Compare(before, before, nil).OK())
+}
+
+func TestCompareCorefile(t *testing.T) {
+	t.Parallel()
+	before := drift.NewSnapshot()
+	before.Corefile = corefile
+	after := drift.NewSnapshot()
+	after.Corefile = corefile[:len(".:53 {\n")] + "    hosts {\n      10.0.0.1 keycloak.bigbang.dev\n      fallthrough\n    }\n" + corefile[len(".:53 {\n"):]
+
+	report := drift.Compare(before, after, nil)
+	require.Equal(t, []drift.Difference{
+		{Resource: drift.Corefile, Change: drift.ChangeCorefile, Detail: `added "10.0.0.1 keycloak.bigbang.dev", added "fallthrough", added "hosts {", added "}"`},
+		{Resource: drift.Corefile, Change: drift.ChangeDuplicateHost, Detail: "10.0.0.1 keycloak.bigbang.dev"},
+	}, report.Unexpected)
+}
+
+func TestDuplicateHosts(t *testing.T) {
+	t.Parallel()
+	require.Empty(t, drift.DuplicateHosts(corefile))
+	require.Equal(t, []string{"10.0.0.2 gitlab.bigbang.dev", "10.0.0.2 sonarqube.bigbang.dev"}, drift.DuplicateHosts(corefile+`
+.:5353 {
+    hosts {
+      10.0.0.2 gitlab.bigbang.dev sonarqube.bigbang.dev
+    }
+}
+`))
+}
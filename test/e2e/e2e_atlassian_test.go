@@ -0,0 +1,171 @@
+package test_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/atlassian"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/browser"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// atlassianStartTimeout is how long Jira or Confluence may take to start, which is a while on first start.
+	atlassianStartTimeout = 20 * time.Minute
+	// atlassianAdmin is the username of the admin the setup wizards of Jira and Confluence are completed with
+	atlassianAdmin = "e2e-admin"
+)
+
+// atlassianApp is an Atlassian application of the software factory.
+type atlassianApp struct {
+	// name is the name of the application, and of its package, namespace and StatefulSet
+	name string
+	// databaseNamespace is where the Postgres of the application runs
+	databaseNamespace string
+	// host is the host it is exposed on in the domain
+	host string
+	// variablePrefix prefixes the database variables of its package, such as JIRA for JIRA_DB_NAME
+	variablePrefix string
+	// licenseEnv is the environment variable that holds a license to complete its setup wizard with
+	licenseEnv string
+	// setupValues are the values its setup wizard is filled in with, given the license and the admin password
+	setupValues func(baseURL string, license string, password string) map[string]string
+}
+
+// atlassianApps are Jira and Confluence.
+var atlassianApps = []atlassianApp{
+	{
+		name:              "jira",
+		databaseNamespace: "jira-db",
+		host:              "jira",
+		variablePrefix:    "JIRA",
+		licenseEnv:        "JIRA_LICENSE",
+		setupValues: func(baseURL string, license string, password string) map[string]string {
+			return map[string]string{
+				"title":           "Software Factory",
+				"mode":            "private",
+				"baseURL":         baseURL,
+				"setupLicenseKey": license,
+				"fullname":        "E2E Admin",
+				"email":           "e2e-admin@" + domain,
+				"username":        atlassianAdmin,
+				"password":        password,
+				"confirm":         password,
+			}
+		},
+	},
+	{
+		name:              "confluence",
+		databaseNamespace: "confluence-db",
+		host:              "confluence",
+		variablePrefix:    "CONFLUENCE",
+		licenseEnv:        "CONFLUENCE_LICENSE",
+		setupValues: func(baseURL string, license string, password string) map[string]string {
+			return map[string]string{
+				"confLicenseString": license,
+				"baseUrl":           baseURL,
+				"username":          atlassianAdmin,
+				"fullName":          "E2E Admin",
+				"email":             "e2e-admin@" + domain,
+				"password":          password,
+				"confirm":           password,
+			}
+		},
+	},
+}
+
+// testAtlassianApps checks Jira and Confluence for the state they report and the database they use.
+func testAtlassianApps(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	udsBundle, err := bundle.Read(platform.RepoRoot)
+	require.NoError(t, err)
+	config, err := udsBundle.ReadConfig(platform.RepoRoot)
+	require.NoError(t, err)
+	for _, app := range atlassianApps {
+		app := app
+		t.Run(app.name, func(t *testing.T) {
+			t.Run("state", func(t *testing.T) {
+				testAtlassianState(t, platform, app)
+			})
+			t.Run("database", func(t *testing.T) {
+				testAtlassianDatabase(t, platform, config, app)
+			})
+		})
+	}
+}
+
+// testAtlassianState waits for an application to have started, which is RUNNING once it is set up and FIRST_RUN
+// before that. Its setup wizard is completed if a license for it is in its licenseEnv, after which it has to be RUNNING.
+// Without a license the setup can't be checked, so the test is skipped rather than passing.
+func testAtlassianState(t *testing.T, platform *types.TestPlatform, app atlassianApp) {
+	t.Helper()
+	baseURL := fmt.Sprintf("https://%s.%s", app.host, domain)
+	status := utils.WaitForAtlassianState(t, platform.HTTPClient(), baseURL, atlassianStartTimeout, atlassian.StateRunning, atlassian.StateFirstRun)
+	if status.State == atlassian.StateRunning {
+		return
+	}
+	license := os.Getenv(app.licenseEnv)
+	if license == "" {
+		t.Skipf("%s is waiting for its setup wizard, set %s to a license to complete it", baseURL, app.licenseEnv)
+	}
+	completeAtlassianSetup(t, platform, app, license, fmt.Sprintf("%s!%s#%s", random.UniqueId(), random.UniqueId(), random.UniqueId()))
+}
+
+// completeAtlassianSetup completes the setup wizard of an application that is on FIRST_RUN with a license, which makes
+// atlassianAdmin its admin with the given password, and waits for it to be RUNNING.
+func completeAtlassianSetup(t *testing.T, platform *types.TestPlatform, app atlassianApp, license string, password string) {
+	t.Helper()
+	baseURL := fmt.Sprintf("https://%s.%s", app.host, domain)
+	session, err := browser.New(platform.HTTPClient())
+	require.NoError(t, err)
+	err = atlassian.CompleteSetup(session, baseURL, app.setupValues(baseURL, license, password))
+	require.NoError(t, err)
+	utils.WaitForAtlassianState(t, platform.HTTPClient(), baseURL, atlassianStartTimeout, atlassian.StateRunning)
+}
+
+// testAtlassianDatabase checks that the application connects to the database with the name and user that
+// uds-config.yaml sets for its package, by reading the JDBC settings of its container, and that it is connected to that
+// database as that user, according to pg_stat_activity of its Postgres.
+func testAtlassianDatabase(t *testing.T, platform *types.TestPlatform, config *bundle.Config, app atlassianApp) {
+	t.Helper()
+	expectedName, ok := config.Variable(app.name, app.variablePrefix+"_DB_NAME")
+	require.True(t, ok, "%s doesn't set %s_DB_NAME for %s", bundle.ConfigFile, app.variablePrefix, app.name)
+	expectedUser, ok := config.Variable(app.name, app.variablePrefix+"_DB_USERNAME")
+	require.True(t, ok, "%s doesn't set %s_DB_USERNAME for %s", bundle.ConfigFile, app.variablePrefix, app.name)
+
+	kube, err := platform.Kubernetes()
+	require.NoError(t, err)
+	statefulSet, err := kube.Clientset.AppsV1().StatefulSets(app.name).Get(context.Background(), app.name, metav1.GetOptions{})
+	require.NoError(t, err)
+	var jdbcURL, user string
+	for _, container := range statefulSet.Spec.Template.Spec.Containers {
+		value, found, err := kube.EnvValue(app.name, container, "ATL_JDBC_URL")
+		require.NoError(t, err)
+		if !found {
+			continue
+		}
+		jdbcURL = value
+		user, _, err = kube.EnvValue(app.name, container, "ATL_JDBC_USER")
+		require.NoError(t, err)
+	}
+	require.NotEmpty(t, jdbcURL, "no container of %s has ATL_JDBC_URL", app.name)
+
+	parsed, err := url.Parse(strings.TrimPrefix(jdbcURL, "jdbc:"))
+	require.NoError(t, err)
+	require.Equal(t, "postgresql", parsed.Scheme, "%s doesn't use Postgres: %s", app.name, jdbcURL)
+	require.Equal(t, expectedName, strings.TrimPrefix(parsed.Path, "/"), "%s uses database %s", app.name, jdbcURL)
+	require.Equal(t, expectedUser, user)
+
+	connected := utils.RunBackupScript(t, platform, "connections", app.databaseNamespace, expectedName, expectedUser)
+	require.Empty(t, connected.Missing("connected", []string{app.databaseNamespace}), "%s has no connection to database %s as %s:\n%s", app.name, expectedName, expectedUser, connected)
+}
@@ -0,0 +1,66 @@
+package test_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/backup"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/upgrade"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// backupRestoreReportFile is the artifact the backup and restore scenario saves what it backed up, restored and lost as
+const backupRestoreReportFile = "backup-restore-report.txt"
+
+// testBackupRestore seeds the data of the upgrade scenario, takes logical dumps of every database and copies of every
+// bucket onto the test host with backup-databases.sh, destroys the databases and object stores so that they come back
+// empty, and restores them. It then waits for the capabilities to be ready again and checks that the seeded data came
+// back. Restored databases that nothing is seeded in, like the one of Confluence, are reported as not checked. The steps
+// are the ones the ops runbook follows.
+func testBackupRestore(t *testing.T, platform *types.TestPlatform, capabilities []types.Capability) {
+	t.Helper()
+	databases := backup.Namespaces(backup.Databases)
+	stores := backup.StoreNamespaces(backup.Stores)
+	scenario := upgradeScenario(platform)
+	scenario.Seed(t)
+
+	dumped := utils.RunBackupScript(t, platform, append([]string{"backup", utils.BackupDir}, databases...)...)
+	require.Empty(t, dumped.Missing("dumped", databases), "no database was dumped in these namespaces:\n%s", dumped)
+	copied := utils.RunBackupScript(t, platform, append([]string{"backup-buckets", utils.BackupDir}, stores...)...)
+	require.Empty(t, copied.Unreported("copied", stores), "no bucket was copied in these namespaces:\n%s", copied)
+
+	utils.RunBackupScript(t, platform, append([]string{"destroy"}, databases...)...)
+	utils.RunBackupScript(t, platform, append([]string{"destroy-buckets"}, stores...)...)
+	restored := utils.RunBackupScript(t, platform, append([]string{"restore", utils.BackupDir}, databases...)...)
+	require.Empty(t, restored.Missing("restored", databases), "no database was restored in these namespaces:\n%s", restored)
+	mirrored := utils.RunBackupScript(t, platform, append([]string{"restore-buckets", utils.BackupDir}, stores...)...)
+	require.Empty(t, copied.Merge(mirrored).Unmatched("copied", "mirrored"), "these buckets didn't come back with as many objects:\n%s", mirrored)
+	utils.RunBackupScript(t, platform, append([]string{"restart"}, backup.Applications(backup.Databases)...)...)
+	utils.CheckCapabilities(t, platform, domain, capabilities)
+
+	report := scenario.Verify(t)
+	for _, database := range backup.Databases {
+		if _, ok := report.Unchecked[database.Capability]; !ok && !seeds(scenario, database.Capability) {
+			report.Unchecked[database.Capability] = fmt.Sprintf("the database in %s was restored, but nothing is seeded in it to check", database.Namespace)
+		}
+	}
+	content := dumped.String() + copied.String() + restored.String() + mirrored.String() + report.String()
+	path, err := platform.SaveArtifact(backupRestoreReportFile, []byte(content))
+	require.NoError(t, err)
+	logger.Default.Logf(t, "Saved the backup and restore report to %s:\n%s", path, content)
+	require.True(t, report.OK(), report.String())
+}
+
+// seeds returns true if the scenario seeds data into the capability.
+func seeds(scenario *upgrade.Scenario, capability string) bool {
+	for _, name := range scenario.Names() {
+		if name == capability {
+			return true
+		}
+	}
+
+	return false
+}
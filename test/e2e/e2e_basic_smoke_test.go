@@ -9,14 +9,30 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// TestAllServicesRunning waits until all services report that they are ready.
-func TestAllServicesRunning(t *testing.T) { //nolint:funlen
+// domain is the domain every capability of the software factory is exposed on.
+const domain = "bigbang.dev"
+
+// TestAllServicesRunning waits until the capability deployed by every package in uds-bundle.yaml reports that it is ready,
+// and then tests that the capabilities work. When UPGRADE is "yes" it also checks that the data seeded before the
+// upgrade is still there.
+func TestAllServicesRunning(t *testing.T) {
 	// BOILERPLATE, EXPECTED TO BE PRESENT AT THE BEGINNING OF EVERY TEST FUNCTION
 
 	t.Parallel()
 	platform := types.NewTestPlatform(t)
+	// Fail before spending any time on infrastructure if a package in the bundle has no readiness check
+	capabilities, err := utils.LoadCapabilities(platform.RepoRoot)
+	require.NoError(t, err)
 	defer platform.Teardown()
-	utils.SetupTestPlatform(t, platform)
+	// When UPGRADE is "yes", data is seeded on the version that is upgraded from and checked once the upgrade is done
+	scenario := upgradeScenario(platform)
+	seeded := false
+	utils.SetupUpgradeTestPlatform(t, platform, func() {
+		t.Run("UpgradeSeed", func(t *testing.T) {
+			seedUpgrade(t, platform, capabilities, scenario)
+		})
+		seeded = true
+	})
 	// The repo has now been downloaded to /root/app and the software factory package deployment has been initiated.
 	teststructure.RunTestStage(platform.T, "TEST", func() {
 		// END BOILERPLATE
@@ -27,142 +43,80 @@ func TestAllServicesRunning(t *testing.T) { //nolint:funlen
 		output, err := platform.RunSSHCommandAsSudo(`kubectl get nodes`)
 		require.NoError(t, err, output)
 
-		// Wait for the GitLab Webservice Deployment to exist.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! kubectl get deployment gitlab-webservice-default -n gitlab; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the GitLab Webservice Deployment to report that it is ready
-		output, err = platform.RunSSHCommandAsSudo(`kubectl rollout status deployment/gitlab-webservice-default -n gitlab --watch --timeout=1200s`)
-		require.NoError(t, err, output)
-
-		// Ensure that the services do not accept discontinued TLS versions. If they reject TLSv1.1 it is assumed that they also reject anything below TLSv1.1.
-		// Ensure that GitLab does not accept TLSv1.1
-		output, err = platform.RunSSHCommandAsSudo(`sslscan gitlab.bigbang.dev | grep "TLSv1.1" | grep "disabled"`)
-		require.NoError(t, err, output)
-
-		// Setup DNS records for cluster services
-		output, err = platform.RunSSHCommandAsSudo(`cd ~/app && utils/metallb/dns.sh && utils/metallb/hosts-write.sh`)
-		require.NoError(t, err, output)
-
-		// Ensure that GitLab is available outside of the cluster.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! curl -L -s --fail --show-error https://gitlab.bigbang.dev/-/health > /dev/null; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the GitLab Runner Deployment to exist.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! kubectl get deployment gitlab-runner -n gitlab-runner; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the GitLab Runner Deployment to report that it is ready
-		output, err = platform.RunSSHCommandAsSudo(`kubectl rollout status deployment/gitlab-runner -n gitlab-runner --watch --timeout=1200s`)
-		require.NoError(t, err, output)
-
-		// Wait for the Sonarqube Statefulset to exist.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! kubectl get statefulset sonarqube-sonarqube -n sonarqube; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the Sonarqube Statefulset to report that it is ready
-		output, err = platform.RunSSHCommandAsSudo(`kubectl rollout status statefulset/sonarqube-sonarqube -n sonarqube --watch --timeout=1200s`)
-		require.NoError(t, err, output)
-
-		// Ensure that the services do not accept discontinued TLS versions. If they reject TLSv1.1 it is assumed that they also reject anything below TLSv1.1.
-		// Ensure that Sonarqube does not accept TLSv1.1
-		output, err = platform.RunSSHCommandAsSudo(`sslscan sonarqube.bigbang.dev | grep "TLSv1.1" | grep "disabled"`)
-		require.NoError(t, err, output)
-
-		// Setup DNS records for cluster services
-		output, err = platform.RunSSHCommandAsSudo(`cd ~/app && utils/metallb/dns.sh && utils/metallb/hosts-write.sh`)
-		require.NoError(t, err, output)
-
-		// Ensure that Sonarqube is available outside of the cluster.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! curl -L -s --fail --show-error https://sonarqube.bigbang.dev/login > /dev/null; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the jira statefulset to exist.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! kubectl get statefulset jira -n jira; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the jira statefulset to report that it is ready
-		output, err = platform.RunSSHCommandAsSudo(`kubectl rollout status statefulset/jira -n jira --watch --timeout=1200s`)
-		require.NoError(t, err, output)
-
-		// Ensure that the services do not accept discontinued TLS versions. If they reject TLSv1.1 it is assumed that they also reject anything below TLSv1.1.
-		// Ensure that jira does not accept TLSv1.1
-		output, err = platform.RunSSHCommandAsSudo(`sslscan jira.bigbang.dev | grep "TLSv1.1" | grep "disabled"`)
-		require.NoError(t, err, output)
-
-		// Setup DNS records for cluster services
-		output, err = platform.RunSSHCommandAsSudo(`cd ~/app && utils/metallb/dns.sh && utils/metallb/hosts-write.sh`)
-		require.NoError(t, err, output)
-
-		// Ensure that jira is available outside of the cluster.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! curl -L -s --fail --show-error https://jira.bigbang.dev/status > /dev/null; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the confluence statefulset to exist.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! kubectl get statefulset confluence -n confluence; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the confluence statefulset to report that it is ready
-		output, err = platform.RunSSHCommandAsSudo(`kubectl rollout status statefulset/confluence -n confluence --watch --timeout=1200s`)
-		require.NoError(t, err, output)
-
-		// Ensure that the services do not accept discontinued TLS versions. If they reject TLSv1.1 it is assumed that they also reject anything below TLSv1.1.
-		// Ensure that confluence does not accept TLSv1.1
-		output, err = platform.RunSSHCommandAsSudo(`sslscan confluence.bigbang.dev | grep "TLSv1.1" | grep "disabled"`)
-		require.NoError(t, err, output)
-
-		// Setup DNS records for cluster services
-		output, err = platform.RunSSHCommandAsSudo(`cd ~/app && utils/metallb/dns.sh && utils/metallb/hosts-write.sh`)
-		require.NoError(t, err, output)
-
-		// Ensure that confluence is available outside of the cluster.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! curl -L -s --fail --show-error https://confluence.bigbang.dev/status > /dev/null; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the mattermost-operator Deployment to exist.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! kubectl get deployment mattermost-operator -n mattermost-operator; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the mattermost-operator Deployment to report that it is ready
-		output, err = platform.RunSSHCommandAsSudo(`kubectl rollout status deployment/mattermost-operator -n mattermost-operator --watch --timeout=1200s`)
-		require.NoError(t, err, output)
-
-		// Wait for the mattermost Deployment to exist.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! kubectl get deployment mattermost -n mattermost; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Setup DNS records for cluster services
-		output, err = platform.RunSSHCommandAsSudo(`cd ~/app && utils/metallb/dns.sh && utils/metallb/hosts-write.sh`)
-		require.NoError(t, err, output)
-
-		// Ensure that Mattermost does not accept TLSv1.1
-		output, err = platform.RunSSHCommandAsSudo(`sslscan chat.bigbang.dev | grep "TLSv1.1" | grep "disabled"`)
-		require.NoError(t, err, output)
-
-		// Ensure that Mattermost is available outside of the cluster.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! curl -L -s --fail --show-error https://chat.bigbang.dev/login > /dev/null; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the nexus Deployment to exist.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! kubectl get deployment nexus-nexus-repository-manager -n nexus; do sleep 5; done"`)
-		require.NoError(t, err, output)
-
-		// Wait for the nexus Deployment to report that it is ready
-		output, err = platform.RunSSHCommandAsSudo(`kubectl rollout status deployment/nexus-nexus-repository-manager -n nexus --watch --timeout=1200s`)
-		require.NoError(t, err, output)
-
-		// Ensure that the services do not accept discontinued TLS versions. If they reject TLSv1.1 it is assumed that they also reject anything below TLSv1.1.
-		// Ensure that nexus does not accept TLSv1.1
-		output, err = platform.RunSSHCommandAsSudo(`sslscan nexus.bigbang.dev | grep "TLSv1.1" | grep "disabled"`)
-		require.NoError(t, err, output)
-
-		// Setup DNS records for cluster services
-		output, err = platform.RunSSHCommandAsSudo(`cd ~/app && utils/metallb/dns.sh && utils/metallb/hosts-write.sh`)
-		require.NoError(t, err, output)
+		utils.CheckCapabilities(t, platform, domain, capabilities)
+
+		// Make sure the data seeded before an upgrade survived it
+		if seeded {
+			t.Run("UpgradeVerify", func(t *testing.T) {
+				verifyUpgrade(t, platform, scenario)
+			})
+		}
+
+		// Make sure people can log in to the capabilities through Keycloak
+		t.Run("KeycloakRealmImport", func(t *testing.T) {
+			testRealmImport(t, platform)
+		})
+		t.Run("GitLabOIDCLogin", func(t *testing.T) {
+			testGitLabOIDCLogin(t, platform)
+		})
+		t.Run("SonarQubeSAMLLogin", func(t *testing.T) {
+			testSonarQubeSAMLLogin(t, platform)
+		})
+
+		// Make sure the capabilities do their jobs
+		t.Run("GitLabCIPipeline", func(t *testing.T) {
+			testGitLabCIPipeline(t, platform)
+		})
+		t.Run("SonarQubeCIAnalysis", func(t *testing.T) {
+			testSonarQubeCIAnalysis(t, platform)
+		})
+		t.Run("MattermostREST", func(t *testing.T) {
+			testMattermostREST(t, platform)
+		})
+		t.Run("NexusRepositories", func(t *testing.T) {
+			testNexusRepositories(t, platform)
+		})
+		t.Run("AtlassianApps", func(t *testing.T) {
+			testAtlassianApps(t, platform)
+		})
+	})
+	// Back up the databases, destroy them and restore them, which leaves the seeded data where it was
+	teststructure.RunTestStage(platform.T, "BACKUP", func() {
+		if skipAfterFailure(t, "BACKUP") {
+			return
+		}
+		t.Run("BackupRestore", func(t *testing.T) {
+			testBackupRestore(t, platform, capabilities)
+		})
+	})
+	// Deploy the same bundle again, which shouldn't change what is already there
+	teststructure.RunTestStage(platform.T, "REDEPLOY", func() {
+		if skipAfterFailure(t, "REDEPLOY") {
+			return
+		}
+		t.Run("IdempotentRedeploy", func(t *testing.T) {
+			testIdempotentRedeploy(t, platform, capabilities)
+		})
+	})
+	// Remove the bundle last, since nothing works after it
+	teststructure.RunTestStage(platform.T, "REMOVAL", func() {
+		if skipAfterFailure(t, "REMOVAL") {
+			return
+		}
+		t.Run("BundleRemoval", func(t *testing.T) {
+			testBundleRemoval(t, platform)
+		})
+	})
+}
 
-		// Ensure that nexus is available outside of the cluster.
-		output, err = platform.RunSSHCommandAsSudo(`timeout 1200 bash -c "while ! curl -L -s --fail --show-error https://nexus.bigbang.dev > /dev/null; do sleep 5; done"`)
-		require.NoError(t, err, output)
+// skipAfterFailure reports whether a stage that changes what is deployed should be skipped because the test already
+// failed, so that Teardown collects diagnostics from the platform in the state it failed in.
+func skipAfterFailure(t *testing.T, stage string) bool {
+	t.Helper()
+	if !t.Failed() {
+		return false
+	}
+	t.Logf("Skipping stage %s, since the test already failed", stage)
 
-	})
+	return true
 }
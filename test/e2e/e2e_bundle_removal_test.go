@@ -0,0 +1,32 @@
+package test_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/removal"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// bundleRemovalTimeout is how long the namespaces of the bundle get to terminate after it was removed.
+const bundleRemovalTimeout = 10 * time.Minute
+
+// testBundleRemoval removes the bundle and checks that what its packages created is gone, apart from the resources
+// listed in retained.yaml.
+func testBundleRemoval(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	expected, err := utils.ExpectedRemovals(platform.RepoRoot)
+	require.NoError(t, err)
+	retained, err := removal.ReadRetained(platform.RepoRoot)
+	require.NoError(t, err)
+
+	utils.RemoveBundle(t, platform)
+	result := utils.WaitForBundleRemoval(t, platform, expected, retained, bundleRemovalTimeout)
+	path, err := platform.SaveArtifact("bundle-removal.txt", []byte(result.String()))
+	require.NoError(t, err)
+	logger.Default.Logf(t, "Saved the result of the bundle removal to %s:\n%s", path, result)
+	require.True(t, result.OK(), result.String())
+}
@@ -0,0 +1,53 @@
+package test_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// runnerSandboxNamespace is where the GitLab Runner runs the pods of CI jobs
+	runnerSandboxNamespace = "gitlab-runner-sandbox"
+	// pipelineTimeout is how long a test pipeline may take, including pulling the job image
+	pipelineTimeout = 15 * time.Minute
+)
+
+// testGitLabCIPipeline pushes a project with a .gitlab-ci.yml to GitLab and checks that the deployed runner picked up
+// its job and ran it to success in the sandbox namespace. The job only gets a pod if the runner's RBAC lets it create
+// one there, Zarf leaves its image alone, and it copes with the Istio sidecar. The job log is saved as an artifact.
+func testGitLabCIPipeline(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	client := utils.GitLabAdmin(t, platform, domain)
+	project := utils.CreateGitLabTestProject(t, client)
+	marker := "e2e-" + strings.ToLower(random.UniqueId())
+	ciConfig := fmt.Sprintf(`e2e:
+  script:
+    - echo "%s-$CI_JOB_ID"
+`, marker)
+	sha := utils.PushToGitLab(t, platform, client, project, map[string][]byte{".gitlab-ci.yml": []byte(ciConfig)})
+
+	pipeline := utils.WaitForGitLabPipeline(t, client, project, sha, pipelineTimeout)
+	jobs, err := client.PipelineJobs(project.ID, pipeline.ID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	job := jobs[0]
+	jobLog, err := client.JobLog(project.ID, job.ID)
+	require.NoError(t, err)
+	artifact, err := platform.SaveArtifact("gitlab-ci-job.log", []byte(jobLog))
+	require.NoError(t, err)
+	logger.Default.Logf(t, "Log of job %s saved to %s", job.WebURL, artifact)
+
+	require.Equal(t, "success", pipeline.Status, "pipeline %s did not succeed, see %s", pipeline.WebURL, artifact)
+	require.Equal(t, "success", job.Status)
+	require.NotNil(t, job.Runner, "no runner picked up the job")
+	require.Contains(t, jobLog, "Using Kubernetes namespace: "+runnerSandboxNamespace)
+	require.Contains(t, jobLog, fmt.Sprintf("%s-%d", marker, job.ID), "the job's script did not run")
+}
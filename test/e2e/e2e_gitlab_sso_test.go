@@ -0,0 +1,64 @@
+package test_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/browser"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/keycloak"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// gitlabUser is a user as returned by the GitLab API.
+type gitlabUser struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	State    string `json:"state"`
+}
+
+// testGitLabOIDCLogin logs in to GitLab through Keycloak as a new user of the realm, the way a person would by clicking
+// the SSO button, and checks that GitLab created the user with its preferred_username as the uid of its identity.
+func testGitLabOIDCLogin(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	gitlabURL := "https://gitlab." + domain
+	user, password := utils.CreateKeycloakTestUser(t, platform, domain)
+
+	// Start the login the way the SSO button on the sign in page does
+	session, err := browser.New(platform.HTTPClient())
+	require.NoError(t, err)
+	page, err := session.Get(gitlabURL + "/users/sign_in")
+	require.NoError(t, err)
+	form, ok := page.FormWithAction("/users/auth/openid_connect")
+	require.True(t, ok, "GitLab's sign in page has no SSO button")
+	page, err = session.Submit(form, nil, "")
+	require.NoError(t, err)
+	page, err = keycloak.Login(session, page, user.Username, password)
+	require.NoError(t, err)
+	require.Equal(t, "gitlab."+domain, page.URL.Hostname(), "Keycloak did not send the browser back to GitLab")
+
+	// GitLab has a session for the user
+	current := new(gitlabUser)
+	getGitLabJSON(t, session, gitlabURL+"/api/v4/user", current)
+	require.Equal(t, user.Username, current.Username)
+	require.Equal(t, "active", current.State)
+
+	// The user's OpenID Connect identity uses preferred_username as its uid
+	var users []gitlabUser
+	getGitLabJSON(t, utils.GitLabRootSession(t, platform, domain), gitlabURL+"/api/v4/users?provider=openid_connect&extern_uid="+url.QueryEscape(user.Username), &users)
+	require.Len(t, users, 1)
+	require.Equal(t, current.ID, users[0].ID)
+}
+
+// getGitLabJSON gets a GitLab API endpoint with the session's cookies, and decodes the JSON it returns into out.
+func getGitLabJSON(t *testing.T, session *browser.Browser, apiURL string, out interface{}) {
+	t.Helper()
+	page, err := session.Get(apiURL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, page.StatusCode, string(page.Body))
+	err = json.Unmarshal(page.Body, out)
+	require.NoError(t, err)
+}
@@ -0,0 +1,21 @@
+package test_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+)
+
+// TestBuildGoldenImage builds the golden image that SetupTestPlatform launches from when GOLDEN_IMAGE is "yes". It only
+// runs when BUILD_GOLDEN_IMAGE is "yes", use `make test-golden-image`.
+func TestBuildGoldenImage(t *testing.T) {
+	if os.Getenv("BUILD_GOLDEN_IMAGE") != "yes" {
+		t.Skip("Skipping golden image build, set BUILD_GOLDEN_IMAGE=yes to run it")
+	}
+	t.Parallel()
+	platform := types.NewTestPlatform(t)
+	defer platform.Teardown()
+	utils.BuildGoldenImage(t, platform)
+}
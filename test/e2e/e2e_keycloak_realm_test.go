@@ -0,0 +1,28 @@
+package test_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/keycloak"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// testRealmImport exports the realm from Keycloak and checks that its clients, with their redirect URIs and protocol
+// mappers, and its roles are the ones in the realm file software-factory-idam-realm shipped to uds-idam.
+func testRealmImport(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	expected, err := utils.ExpectedRealm(platform.RepoRoot, domain)
+	require.NoError(t, err)
+	actual, err := utils.KeycloakAdmin(t, platform, domain).ExportRealm(keycloak.Realm)
+	require.NoError(t, err)
+
+	differences := keycloak.DiffRealm(expected, actual)
+	lines := make([]string, 0, len(differences))
+	for _, difference := range differences {
+		lines = append(lines, difference.String())
+	}
+	require.Empty(t, differences, "realm %s does not match %s:\n%s", keycloak.Realm, utils.RealmFile, strings.Join(lines, "\n"))
+}
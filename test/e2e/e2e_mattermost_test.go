@@ -0,0 +1,81 @@
+package test_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/mattermost"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// mattermostStableTimeout is how long the Mattermost operator may take to finish reconciling Mattermost.
+const mattermostStableTimeout = 10 * time.Minute
+
+// mattermostGVR is the custom resource the Mattermost operator deploys Mattermost from.
+var mattermostGVR = schema.GroupVersionResource{Group: "installation.mattermost.com", Version: "v1beta1", Resource: "mattermosts"}
+
+// testMattermostREST waits for the operator to report Mattermost as stable, and then uses the REST API the way people
+// use Mattermost: it creates a team, a channel and a user, has the user post a message with a file attached, and reads
+// everything back. Reading the post back goes through Postgres, and downloading the file goes through MinIO.
+func testMattermostREST(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	kube, err := platform.Kubernetes()
+	require.NoError(t, err)
+	err = kube.WaitForCRField(mattermostGVR, "mattermost", "mattermost", "stable", mattermostStableTimeout, "status", "state")
+	require.NoError(t, err)
+
+	admin := utils.MattermostAdmin(t, platform, domain)
+	name := "e2e-" + strings.ToLower(random.UniqueId())
+	team, err := admin.CreateTeam(mattermost.Team{Name: name, DisplayName: "E2E " + name, Type: "I"})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := admin.DeleteTeam(team.ID); err != nil {
+			t.Errorf("unable to delete mattermost test team %s: %v", name, err)
+		}
+	})
+	channel, err := admin.CreateChannel(mattermost.Channel{TeamID: team.ID, Name: name, DisplayName: "E2E " + name, Type: "O"})
+	require.NoError(t, err)
+	password := utils.MattermostTestPassword()
+	user, err := admin.CreateUser(mattermost.User{Username: name, Email: fmt.Sprintf("%s@%s", name, domain), Password: password})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := admin.DeactivateUser(user.ID); err != nil {
+			t.Errorf("unable to deactivate mattermost test user %s: %v", name, err)
+		}
+	})
+	require.NoError(t, admin.AddTeamMember(team.ID, user.ID))
+	require.NoError(t, admin.AddChannelMember(channel.ID, user.ID))
+
+	// The user posts a message with an attachment
+	session, err := mattermost.Login(platform.HTTPClient(), "https://chat."+domain, name, password)
+	require.NoError(t, err)
+	attachment := []byte("uploaded by " + name + "\n")
+	file, err := session.UploadFile(channel.ID, "e2e.txt", attachment)
+	require.NoError(t, err)
+	message := "Hello from " + name
+	post, err := session.CreatePost(mattermost.Post{ChannelID: channel.ID, Message: message, FileIDs: []string{file.ID}})
+	require.NoError(t, err)
+
+	// Everything reads back the way it was created
+	readTeam, err := admin.Team(team.ID)
+	require.NoError(t, err)
+	require.Equal(t, name, readTeam.Name)
+	readChannel, err := admin.ChannelByName(team.ID, name)
+	require.NoError(t, err)
+	require.Equal(t, channel.ID, readChannel.ID)
+	posts, err := admin.ChannelPosts(channel.ID)
+	require.NoError(t, err)
+	require.Contains(t, posts, post.ID)
+	require.Equal(t, message, posts[post.ID].Message)
+	require.Equal(t, user.ID, posts[post.ID].UserID)
+	require.Equal(t, []string{file.ID}, posts[post.ID].FileIDs)
+	content, err := admin.File(file.ID)
+	require.NoError(t, err)
+	require.Equal(t, attachment, content, "the attachment did not survive a round trip through MinIO")
+}
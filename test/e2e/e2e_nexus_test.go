@@ -0,0 +1,127 @@
+package test_test
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/keycloak"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/nexus"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// samlMetadata is the part of the SAML metadata of a service provider that Keycloak has to agree with.
+type samlMetadata struct {
+	EntityID        string `xml:"entityID,attr"`
+	SPSSODescriptor struct {
+		KeyDescriptors []struct {
+			Use         string `xml:"use,attr"`
+			Certificate string `xml:"KeyInfo>X509Data>X509Certificate"`
+		} `xml:"KeyDescriptor"`
+		AssertionConsumerServices []struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+		} `xml:"AssertionConsumerService"`
+	} `xml:"SPSSODescriptor"`
+}
+
+// testNexusRepositories creates hosted raw, Maven and Docker repositories in Nexus and round trips an artifact through
+// each of them, and checks that the SAML metadata of Nexus matches its client in the realm.
+func testNexusRepositories(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	client := utils.NexusAdmin(t, platform, domain)
+
+	t.Run("Raw", func(t *testing.T) {
+		repository := utils.CreateNexusTestRepository(t, client, client.CreateRawHostedRepository)
+		content := []byte("uploaded to " + repository + "\n")
+		require.NoError(t, client.Upload(repository, "e2e/hello.txt", content))
+		downloaded, err := client.Download(repository, "e2e/hello.txt")
+		require.NoError(t, err)
+		require.Equal(t, content, downloaded)
+
+		// Nexus computed the same checksum from what it stored
+		assets, err := client.Assets(repository)
+		require.NoError(t, err)
+		require.Len(t, assets, 1)
+		require.Equal(t, "e2e/hello.txt", strings.TrimPrefix(assets[0].Path, "/"))
+		require.Equal(t, fmt.Sprintf("%x", sha256.Sum256(content)), assets[0].Checksum["sha256"])
+	})
+
+	t.Run("Maven", func(t *testing.T) {
+		repository := utils.CreateNexusTestRepository(t, client, client.CreateMavenHostedRepository)
+		artifactPath := "dev/bigbang/e2e/hello/1.0.0/hello-1.0.0"
+		pom := []byte(`<project><modelVersion>4.0.0</modelVersion><groupId>dev.bigbang.e2e</groupId><artifactId>hello</artifactId><version>1.0.0</version><packaging>jar</packaging></project>`)
+		jar := []byte("not really a jar, but Maven repositories don't look inside\n")
+		require.NoError(t, client.Upload(repository, artifactPath+".pom", pom))
+		require.NoError(t, client.Upload(repository, artifactPath+".jar", jar))
+
+		downloaded, err := client.Download(repository, artifactPath+".jar")
+		require.NoError(t, err)
+		require.Equal(t, jar, downloaded)
+		// Maven clients check what they download against the .sha1 Nexus generates next to it
+		checksum, err := client.Download(repository, artifactPath+".jar.sha1")
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%x", sha1.Sum(jar)), strings.TrimSpace(string(checksum))) //nolint:gosec
+	})
+
+	t.Run("Docker", func(t *testing.T) {
+		repository := utils.CreateNexusTestRepository(t, client, client.CreateDockerHostedRepository)
+		image, err := nexus.NewTestImage("hello.txt", []byte("pushed to "+repository+"\n"))
+		require.NoError(t, err)
+		registry := client.Registry(repository)
+		pushed, err := registry.Push("e2e/hello", "1.0.0", image)
+		require.NoError(t, err)
+		pulled, digest, err := registry.Pull("e2e/hello", "1.0.0")
+		require.NoError(t, err)
+		require.Equal(t, pushed, digest)
+		require.Equal(t, image, pulled)
+	})
+
+	t.Run("SAMLMetadata", func(t *testing.T) {
+		testNexusSAMLMetadata(t, platform)
+	})
+}
+
+// testNexusSAMLMetadata checks that the SAML metadata Nexus publishes agrees with the client of Nexus in the realm:
+// the client ID is the entity ID, Keycloak posts assertions to where Nexus consumes them, and Keycloak verifies the
+// requests of Nexus with the certificate Nexus signs them with.
+func testNexusSAMLMetadata(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	metadataURL := fmt.Sprintf("https://nexus.%s/service/rest/v1/security/saml/metadata", domain)
+	response, err := platform.HTTPClient().Get(metadataURL) //nolint:noctx
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, response.StatusCode, string(body))
+	var metadata samlMetadata
+	require.NoError(t, xml.Unmarshal(body, &metadata))
+
+	client, err := utils.KeycloakAdmin(t, platform, domain).FindClient(keycloak.Realm, metadataURL)
+	require.NoError(t, err)
+	require.Equal(t, "saml", client.Protocol)
+	require.Equal(t, client.ClientID, metadata.EntityID)
+
+	var consumers []string
+	for _, service := range metadata.SPSSODescriptor.AssertionConsumerServices {
+		if strings.HasSuffix(service.Binding, ":HTTP-POST") {
+			consumers = append(consumers, service.Location)
+		}
+	}
+	require.Contains(t, consumers, client.Attributes["saml_assertion_consumer_url_post"])
+
+	var signing []string
+	for _, key := range metadata.SPSSODescriptor.KeyDescriptors {
+		if key.Use == "" || key.Use == "signing" {
+			signing = append(signing, strings.Join(strings.Fields(key.Certificate), ""))
+		}
+	}
+	require.Contains(t, signing, client.Attributes["saml.signing.certificate"])
+}
@@ -0,0 +1,18 @@
+package test_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+)
+
+// TestFinishPendingTeardowns tears down infrastructure left behind by earlier runs that were killed before their
+// Teardown finished. It isn't parallel, so it finishes before any of the parallel tests create new infrastructure.
+func TestFinishPendingTeardowns(t *testing.T) {
+	repoRoot, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types.FinishPendingTeardowns(t, repoRoot)
+}
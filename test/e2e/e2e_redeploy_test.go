@@ -0,0 +1,42 @@
+package test_test
+
+import (
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/drift"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// testIdempotentRedeploy deploys the bundle again over itself, waits for the capabilities to be ready again, and checks
+// that nothing changed apart from what is listed in drift.yaml. Rotated passwords, restarted workloads and hosts that
+// CoreDNS maps twice all show up.
+func testIdempotentRedeploy(t *testing.T, platform *types.TestPlatform, capabilities []types.Capability) {
+	t.Helper()
+	expected, err := drift.ReadExpected(platform.RepoRoot)
+	require.NoError(t, err)
+	namespaces, err := bundle.Namespaces(platform.RepoRoot)
+	require.NoError(t, err)
+	names := make([]string, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		names = append(names, namespace.Name)
+	}
+	kube, err := platform.Kubernetes()
+	require.NoError(t, err)
+
+	before, err := utils.SnapshotCluster(kube, names)
+	require.NoError(t, err)
+	utils.RedeployBundle(t, platform)
+	utils.CheckCapabilities(t, platform, domain, capabilities)
+	after, err := utils.SnapshotCluster(kube, names)
+	require.NoError(t, err)
+
+	report := drift.Compare(before, after, expected)
+	path, err := platform.SaveArtifact("redeploy-drift.txt", []byte(report.String()))
+	require.NoError(t, err)
+	logger.Default.Logf(t, "Saved what the redeploy changed to %s:\n%s", path, report)
+	require.True(t, report.OK(), report.String())
+}
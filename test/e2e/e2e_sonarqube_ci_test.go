@@ -0,0 +1,76 @@
+package test_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// analysisTimeout is how long SonarQube may take to process an analysis once the pipeline has submitted it.
+const analysisTimeout = 5 * time.Minute
+
+// sonarScannerCIConfig runs sonar-scanner against SonarQube by its public hostname. The job pod runs in the cluster, so
+// the hostname only resolves because of the entries software-factory-idam-dns adds to the cluster's DNS, and it
+// reaches SonarQube through the Istio gateway.
+const sonarScannerCIConfig = `sonarqube:
+  image:
+    name: sonarsource/sonar-scanner-cli:5.0
+    entrypoint: [""]
+  variables:
+    GIT_DEPTH: "0"
+  script:
+    - sonar-scanner -Dsonar.host.url=https://sonarqube.%s -Dsonar.projectKey=%s -Dsonar.sources=.
+`
+
+// sampleSource is the code the pipeline analyzes. The unused variable gives SonarQube something to report.
+const sampleSource = `def greet(name):
+    unused = 42
+    return "Hello, " + name
+
+
+print(greet("software factory"))
+`
+
+// testSonarQubeCIAnalysis pushes a small project with a sonar-scanner job to GitLab, with a SonarQube token in a masked
+// CI variable, since the token is the admin's, and checks that SonarQube processed the analysis the job submitted and
+// computed its quality gate.
+func testSonarQubeCIAnalysis(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	sonar := utils.SonarQubeAdmin(t, platform, domain)
+	client := utils.GitLabAdmin(t, platform, domain)
+	project := utils.CreateGitLabTestProject(t, client)
+	projectKey := project.Name
+	t.Cleanup(func() {
+		if err := sonar.DeleteProject(projectKey); err != nil {
+			t.Errorf("unable to delete sonarqube test project %s: %v", projectKey, err)
+		}
+	})
+	err := client.CreateProjectVariable(project.ID, "SONAR_TOKEN", utils.CreateSonarQubeToken(t, sonar), true)
+	require.NoError(t, err)
+	sha := utils.PushToGitLab(t, platform, client, project, map[string][]byte{
+		".gitlab-ci.yml": []byte(fmt.Sprintf(sonarScannerCIConfig, domain, projectKey)),
+		"app/greet.py":   []byte(sampleSource),
+	})
+
+	pipeline := utils.WaitForGitLabPipeline(t, client, project, sha, pipelineTimeout)
+	jobs, err := client.PipelineJobs(project.ID, pipeline.ID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	jobLog, err := client.JobLog(project.ID, jobs[0].ID)
+	require.NoError(t, err)
+	artifact, err := platform.SaveArtifact("sonar-scanner-job.log", []byte(jobLog))
+	require.NoError(t, err)
+	logger.Default.Logf(t, "Log of job %s saved to %s", jobs[0].WebURL, artifact)
+	require.Equal(t, "success", pipeline.Status, "pipeline %s did not succeed, see %s", pipeline.WebURL, artifact)
+
+	task := utils.WaitForSonarQubeAnalysis(t, sonar, projectKey, analysisTimeout)
+	require.NotEmpty(t, task.AnalysisID)
+	status, err := sonar.QualityGateStatus(projectKey)
+	require.NoError(t, err)
+	require.Contains(t, []string{"OK", "WARN", "ERROR"}, status, "SonarQube did not compute a quality gate for %s", projectKey)
+}
@@ -0,0 +1,49 @@
+package test_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/browser"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/keycloak"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// testSonarQubeSAMLLogin logs in to SonarQube through Keycloak with SAML as a new user of the realm, and checks that
+// SonarQube mapped the login, name and email attributes of the assertion onto the user. SonarQube only accepts the
+// assertion if the SAML certificate that software-factory-idam-sonarqube got from the realm is right.
+func testSonarQubeSAMLLogin(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	sonarqubeURL := "https://sonarqube." + domain
+	user, password := utils.CreateKeycloakTestUser(t, platform, domain)
+
+	// Start the login the way the "Log in with SAML" button on the login page does
+	session, err := browser.New(platform.HTTPClient())
+	require.NoError(t, err)
+	page, err := session.Get(sonarqubeURL + "/sessions/init/saml?return_to=/")
+	require.NoError(t, err)
+	page, err = keycloak.Login(session, page, user.Username, password)
+	require.NoError(t, err)
+	require.Equal(t, "sonarqube."+domain, page.URL.Hostname(), "Keycloak did not send the browser back to SonarQube")
+	require.NotContains(t, page.URL.Path, "/sessions/unauthorized", "SonarQube rejected the SAML response")
+
+	// SonarQube has a session for the user, with the attributes from the assertion
+	page, err = session.Get(sonarqubeURL + "/api/users/current")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, page.StatusCode, string(page.Body))
+	var current struct {
+		IsLoggedIn bool   `json:"isLoggedIn"`
+		Login      string `json:"login"`
+		Name       string `json:"name"`
+		Email      string `json:"email"`
+	}
+	err = json.Unmarshal(page.Body, &current)
+	require.NoError(t, err)
+	require.True(t, current.IsLoggedIn, "SonarQube has no session for the user")
+	require.Equal(t, user.Username, current.Login)
+	require.Equal(t, user.Username, current.Name)
+	require.Equal(t, user.Email, current.Email)
+}
@@ -0,0 +1,441 @@
+package test_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/atlassian"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/gitlab"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/keycloak"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/mattermost"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/nexus"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/sonarqube"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/upgrade"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	teststructure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// upgradeReportFile is the artifact the report of the upgrade scenario is saved as
+	upgradeReportFile = "upgrade-report.txt"
+	// upgradeMatrixFile is the artifact TestUpgradeMatrix saves the outcome of the upgrade from every version as
+	upgradeMatrixFile = "upgrade-matrix.md"
+	// upgradeJiraProject is the key of the Jira project the upgrade scenario seeds, which is only created on a new Jira
+	upgradeJiraProject = "UPGRADE"
+)
+
+// TestUpgradeMatrix runs the upgrade scenario from every version in UPGRADE_FROM to the branch version, each on a
+// platform of its own, and saves a matrix of which upgrades are supported. The platforms run at the same time, or one
+// after another if UPGRADE_MATRIX_SEQUENTIAL is "yes". It only runs when UPGRADE_FROM is set, see
+// utils.UpgradeSources, use `make test-upgrade-matrix`.
+func TestUpgradeMatrix(t *testing.T) {
+	repoRoot, err := types.FindRepoRoot()
+	require.NoError(t, err)
+	versions := utils.UpgradeSources(t, repoRoot)
+	if len(versions) == 0 {
+		t.Skip("Skipping the upgrade matrix, set UPGRADE_FROM to the versions to upgrade from to run it")
+	}
+	t.Parallel()
+	capabilities, err := utils.LoadCapabilities(repoRoot)
+	require.NoError(t, err)
+	logger.Default.Logf(t, "Upgrading from %s", strings.Join(versions, ", "))
+
+	matrix := upgrade.NewMatrix(versions)
+	sequential := os.Getenv("UPGRADE_MATRIX_SEQUENTIAL") == "yes"
+	// The group only returns once the upgrades from every version are done, even when they run in parallel
+	t.Run("From", func(t *testing.T) {
+		for _, version := range versions {
+			version := version
+			t.Run(version, func(t *testing.T) {
+				if !sequential {
+					t.Parallel()
+				}
+				testUpgradeFrom(t, version, capabilities, matrix)
+			})
+		}
+	})
+
+	path, err := types.SaveArtifact(t, repoRoot, upgradeMatrixFile, []byte(matrix.String()))
+	require.NoError(t, err)
+	logger.Default.Logf(t, "Saved the upgrade matrix to %s:\n%s", path, matrix)
+}
+
+// testUpgradeFrom runs the upgrade scenario from a version on a new platform, and adds the result to the matrix.
+func testUpgradeFrom(t *testing.T, version string, capabilities []types.Capability, matrix *upgrade.Matrix) {
+	t.Helper()
+	platform := types.NewTestPlatform(t)
+	defer platform.Teardown()
+	var report *upgrade.Report
+	defer func() {
+		matrix.Add(version, report, t.Failed())
+	}()
+	scenario := upgradeScenario(platform)
+	utils.SetupTestPlatformFrom(t, platform, version, func() {
+		t.Run("UpgradeSeed", func(t *testing.T) {
+			seedUpgrade(t, platform, capabilities, scenario)
+		})
+	})
+	teststructure.RunTestStage(t, "TEST", func() {
+		utils.CheckCapabilities(t, platform, domain, capabilities)
+		t.Run("UpgradeVerify", func(t *testing.T) {
+			report = verifyUpgrade(t, platform, scenario)
+		})
+	})
+}
+
+// upgradeScenario returns the scenario that seeds data into the capabilities before an upgrade and checks it
+// afterwards.
+func upgradeScenario(platform *types.TestPlatform) *upgrade.Scenario {
+	return upgrade.NewScenario(
+		keycloakUpgradeCapability(platform),
+		gitLabUpgradeCapability(platform),
+		sonarQubeUpgradeCapability(platform),
+		mattermostUpgradeCapability(platform),
+		nexusUpgradeCapability(platform),
+		jiraUpgradeCapability(platform),
+	)
+}
+
+// seedUpgrade waits for the capabilities the scenario seeds to be ready on the version that is upgraded from, and
+// seeds them.
+func seedUpgrade(t *testing.T, platform *types.TestPlatform, capabilities []types.Capability, scenario *upgrade.Scenario) {
+	t.Helper()
+	var seeded []types.Capability
+	for _, capability := range capabilities {
+		for _, name := range scenario.Names() {
+			if capability.Name == name {
+				seeded = append(seeded, capability)
+			}
+		}
+	}
+	utils.CheckCapabilities(t, platform, domain, seeded)
+	scenario.Seed(t)
+}
+
+// verifyUpgrade checks the data the scenario seeded before the upgrade, saves the report of what was lost or changed
+// as an artifact, and fails if anything was. It returns the report.
+func verifyUpgrade(t *testing.T, platform *types.TestPlatform, scenario *upgrade.Scenario) *upgrade.Report {
+	t.Helper()
+	report := scenario.Verify(t)
+	path, err := platform.SaveArtifact(upgradeReportFile, []byte(report.String()))
+	if err != nil {
+		t.Errorf("unable to save the upgrade report: %v", err)
+	}
+	logger.Default.Logf(t, "Saved the upgrade report to %s:\n%s", path, report)
+	if !report.OK() {
+		t.Error(report.String())
+	}
+
+	return report
+}
+
+// keycloakUpgradeCapability seeds a user of the realm that is allowed to log in to every capability.
+func keycloakUpgradeCapability(platform *types.TestPlatform) upgrade.Capability {
+	username := "e2e-upgrade-" + strings.ToLower(random.UniqueId())
+	records := func(user *keycloak.User, groups []keycloak.Group) upgrade.Records {
+		paths := make([]string, 0, len(groups))
+		for _, group := range groups {
+			paths = append(paths, group.Path)
+		}
+		sort.Strings(paths)
+
+		return upgrade.Records{
+			"email of user " + username:   user.Email,
+			"name of user " + username:    user.FirstName + " " + user.LastName,
+			"enabled of user " + username: strconv.FormatBool(user.Enabled),
+			"groups of user " + username:  strings.Join(paths, ","),
+		}
+	}
+
+	return upgrade.Capability{
+		Name: "Keycloak",
+		Seed: func(t *testing.T) upgrade.Records {
+			t.Helper()
+			admin := utils.KeycloakAdmin(t, platform, domain)
+			password := fmt.Sprintf("%s!%s#%s", random.UniqueId(), random.UniqueId(), random.UniqueId())
+			id, err := admin.CreateUser(keycloak.Realm, keycloak.User{
+				Username:      username,
+				Email:         fmt.Sprintf("%s@%s", username, domain),
+				FirstName:     "E2E",
+				LastName:      "Upgrade",
+				Enabled:       true,
+				EmailVerified: true,
+				Credentials:   []keycloak.Credential{{Type: "password", Value: password}},
+			})
+			require.NoError(t, err)
+			require.NoError(t, admin.AddUserToGroup(keycloak.Realm, id, keycloak.AuthorizedGroupID))
+			user, err := admin.GetUser(keycloak.Realm, id)
+			require.NoError(t, err)
+			groups, err := admin.UserGroups(keycloak.Realm, id)
+			require.NoError(t, err)
+
+			return records(user, groups)
+		},
+		Verify: func(t *testing.T, seeded upgrade.Records) upgrade.Records {
+			t.Helper()
+			admin := utils.KeycloakAdmin(t, platform, domain)
+			user, err := admin.FindUser(keycloak.Realm, username)
+			if errors.Is(err, keycloak.ErrNotFound) {
+				return nil
+			}
+			require.NoError(t, err)
+			groups, err := admin.UserGroups(keycloak.Realm, user.ID)
+			require.NoError(t, err)
+
+			return records(user, groups)
+		},
+	}
+}
+
+// gitLabUpgradeCapability seeds a project with a commit on its main branch.
+func gitLabUpgradeCapability(platform *types.TestPlatform) upgrade.Capability {
+	files := map[string][]byte{
+		"README.md":     []byte("# Upgrade\n\nSeeded before an upgrade, to check that the upgrade keeps it.\n"),
+		"data/seed.txt": []byte(random.UniqueId() + "\n"),
+	}
+	var projectPath string
+
+	return upgrade.Capability{
+		Name: "GitLab",
+		Seed: func(t *testing.T) upgrade.Records {
+			t.Helper()
+			client := utils.GitLabAdmin(t, platform, domain)
+			project, err := client.CreateProject("e2e-upgrade-" + strings.ToLower(random.UniqueId()))
+			require.NoError(t, err)
+			projectPath = project.PathWithNamespace
+			sha := utils.PushToGitLab(t, platform, client, project, files)
+			records := upgrade.Records{"main branch of " + projectPath: sha}
+			for path, content := range files {
+				records[fmt.Sprintf("file %s of %s", path, projectPath)] = fingerprint(content)
+			}
+
+			return records
+		},
+		Verify: func(t *testing.T, seeded upgrade.Records) upgrade.Records {
+			t.Helper()
+			client := utils.GitLabAdmin(t, platform, domain)
+			project, err := client.Project(projectPath)
+			if errors.Is(err, gitlab.ErrNotFound) {
+				return nil
+			}
+			require.NoError(t, err)
+			found := make(upgrade.Records)
+			sha, err := client.BranchCommit(project.ID, "main")
+			if !errors.Is(err, gitlab.ErrNotFound) {
+				require.NoError(t, err)
+				found["main branch of "+projectPath] = sha
+			}
+			for path := range files {
+				content, err := client.RawFile(project.ID, path, "main")
+				if !errors.Is(err, gitlab.ErrNotFound) {
+					require.NoError(t, err)
+					found[fmt.Sprintf("file %s of %s", path, projectPath)] = fingerprint(content)
+				}
+			}
+
+			return found
+		},
+	}
+}
+
+// sonarQubeUpgradeCapability seeds a project, which is only kept in the database of SonarQube.
+func sonarQubeUpgradeCapability(platform *types.TestPlatform) upgrade.Capability {
+	projectKey := "e2e-upgrade-" + strings.ToLower(random.UniqueId())
+	item := "project " + projectKey
+
+	return upgrade.Capability{
+		Name: "SonarQube",
+		Seed: func(t *testing.T) upgrade.Records {
+			t.Helper()
+			name := "E2E Upgrade " + random.UniqueId()
+			require.NoError(t, utils.SonarQubeAdmin(t, platform, domain).CreateProject(projectKey, name))
+
+			return upgrade.Records{item: name}
+		},
+		Verify: func(t *testing.T, seeded upgrade.Records) upgrade.Records {
+			t.Helper()
+			name, err := utils.SonarQubeAdmin(t, platform, domain).ProjectName(projectKey)
+			if errors.Is(err, sonarqube.ErrNotFound) {
+				return nil
+			}
+			require.NoError(t, err)
+
+			return upgrade.Records{item: name}
+		},
+	}
+}
+
+// mattermostUpgradeCapability seeds a team with a channel that has a post and a post with a file.
+func mattermostUpgradeCapability(platform *types.TestPlatform) upgrade.Capability {
+	teamName := "e2e-upgrade-" + strings.ToLower(random.UniqueId())
+	channelName := "upgrade"
+	content := []byte(random.UniqueId() + "\n")
+	// fileIDs are the IDs of the files attached to the posts, by the ID of the post
+	fileIDs := make(map[string]string)
+	postItem := func(postID string) string {
+		return fmt.Sprintf("post %s in ~%s of %s", postID, channelName, teamName)
+	}
+	fileItem := func(postID string) string {
+		return fmt.Sprintf("file of post %s in ~%s of %s", postID, channelName, teamName)
+	}
+
+	return upgrade.Capability{
+		Name: "Mattermost",
+		Seed: func(t *testing.T) upgrade.Records {
+			t.Helper()
+			admin := utils.MattermostAdmin(t, platform, domain)
+			team, err := admin.CreateTeam(mattermost.Team{Name: teamName, DisplayName: "E2E Upgrade", Type: "O"})
+			require.NoError(t, err)
+			channel, err := admin.CreateChannel(mattermost.Channel{TeamID: team.ID, Name: channelName, DisplayName: "Upgrade", Type: "O"})
+			require.NoError(t, err)
+			records := upgrade.Records{"team " + teamName: team.DisplayName}
+			post, err := admin.CreatePost(mattermost.Post{ChannelID: channel.ID, Message: "Posted before the upgrade"})
+			require.NoError(t, err)
+			records[postItem(post.ID)] = post.Message
+			file, err := admin.UploadFile(channel.ID, "seed.txt", content)
+			require.NoError(t, err)
+			post, err = admin.CreatePost(mattermost.Post{ChannelID: channel.ID, Message: "Attached before the upgrade", FileIDs: []string{file.ID}})
+			require.NoError(t, err)
+			records[postItem(post.ID)] = post.Message
+			records[fileItem(post.ID)] = fingerprint(content)
+			fileIDs[post.ID] = file.ID
+
+			return records
+		},
+		Verify: func(t *testing.T, seeded upgrade.Records) upgrade.Records {
+			t.Helper()
+			admin := utils.MattermostAdmin(t, platform, domain)
+			team, err := admin.TeamByName(teamName)
+			if errors.Is(err, mattermost.ErrNotFound) {
+				return nil
+			}
+			require.NoError(t, err)
+			found := upgrade.Records{"team " + teamName: team.DisplayName}
+			channel, err := admin.ChannelByName(team.ID, channelName)
+			if errors.Is(err, mattermost.ErrNotFound) {
+				return found
+			}
+			require.NoError(t, err)
+			posts, err := admin.ChannelPosts(channel.ID)
+			require.NoError(t, err)
+			for id, post := range posts {
+				found[postItem(id)] = post.Message
+				for _, fileID := range post.FileIDs {
+					if fileID != fileIDs[id] {
+						continue
+					}
+					attached, err := admin.File(fileID)
+					if !errors.Is(err, mattermost.ErrNotFound) {
+						require.NoError(t, err)
+						found[fileItem(id)] = fingerprint(attached)
+					}
+				}
+			}
+
+			return found
+		},
+	}
+}
+
+// nexusUpgradeCapability seeds a raw repository with a file.
+func nexusUpgradeCapability(platform *types.TestPlatform) upgrade.Capability {
+	repository := "e2e-upgrade-" + strings.ToLower(random.UniqueId())
+	path := "seed/data.txt"
+	item := fmt.Sprintf("file %s of %s", path, repository)
+
+	return upgrade.Capability{
+		Name: "Nexus",
+		Seed: func(t *testing.T) upgrade.Records {
+			t.Helper()
+			client := utils.NexusAdmin(t, platform, domain)
+			require.NoError(t, client.CreateRawHostedRepository(repository))
+			content := []byte(random.UniqueId() + "\n")
+			require.NoError(t, client.Upload(repository, path, content))
+
+			return upgrade.Records{item: fingerprint(content)}
+		},
+		Verify: func(t *testing.T, seeded upgrade.Records) upgrade.Records {
+			t.Helper()
+			content, err := utils.NexusAdmin(t, platform, domain).Download(repository, path)
+			if errors.Is(err, nexus.ErrNotFound) {
+				return nil
+			}
+			require.NoError(t, err)
+
+			return upgrade.Records{item: fingerprint(content)}
+		},
+	}
+}
+
+// jiraUpgradeCapability seeds an issue in a new project. Jira can only be seeded when it hasn't been set up yet and
+// JIRA_LICENSE holds a license, since its REST API is only there once it is set up, by the admin the seed sets it up
+// with.
+func jiraUpgradeCapability(platform *types.TestPlatform) upgrade.Capability {
+	var app atlassianApp
+	for _, candidate := range atlassianApps {
+		if candidate.name == "jira" {
+			app = candidate
+		}
+	}
+	baseURL := fmt.Sprintf("https://%s.%s", app.host, domain)
+	password := fmt.Sprintf("%s!%s#%s", random.UniqueId(), random.UniqueId(), random.UniqueId())
+	var key string
+	records := func(issue *atlassian.Issue) upgrade.Records {
+		return upgrade.Records{
+			"summary of issue " + key:     issue.Fields.Summary,
+			"description of issue " + key: issue.Fields.Description,
+		}
+	}
+
+	return upgrade.Capability{
+		Name: "Jira",
+		Seed: func(t *testing.T) upgrade.Records {
+			t.Helper()
+			status := utils.WaitForAtlassianState(t, platform.HTTPClient(), baseURL, atlassianStartTimeout, atlassian.StateRunning, atlassian.StateFirstRun)
+			if status.State == atlassian.StateRunning {
+				t.Skipf("%s was already set up, so the password of its admin is unknown", baseURL)
+			}
+			license := os.Getenv(app.licenseEnv)
+			if license == "" {
+				t.Skipf("%s can't be set up without a license, set %s to one to seed it", baseURL, app.licenseEnv)
+			}
+			completeAtlassianSetup(t, platform, app, license, password)
+
+			jira := atlassian.NewJira(platform.HTTPClient(), baseURL, atlassianAdmin, password)
+			require.NoError(t, jira.CreateProject(upgradeJiraProject, "E2E Upgrade"))
+			var err error
+			key, err = jira.CreateTask(upgradeJiraProject, "Created before the upgrade", "Seeded "+random.UniqueId())
+			require.NoError(t, err)
+			issue, err := jira.Issue(key)
+			require.NoError(t, err)
+
+			return records(issue)
+		},
+		Verify: func(t *testing.T, seeded upgrade.Records) upgrade.Records {
+			t.Helper()
+			utils.WaitForAtlassianState(t, platform.HTTPClient(), baseURL, atlassianStartTimeout, atlassian.StateRunning)
+			issue, err := atlassian.NewJira(platform.HTTPClient(), baseURL, atlassianAdmin, password).Issue(key)
+			if errors.Is(err, atlassian.ErrNotFound) {
+				return nil
+			}
+			require.NoError(t, err)
+
+			return records(issue)
+		},
+	}
+}
+
+// fingerprint returns the checksum of content, for the records of seeded files.
+func fingerprint(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
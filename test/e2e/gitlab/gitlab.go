@@ -0,0 +1,228 @@
+// Package gitlab is a client for the parts of the GitLab API that the e2e tests use to create projects and follow
+// their CI pipelines.
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotFound is returned when the API says what was asked for doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Client calls the GitLab API with a personal access token.
+type Client struct {
+	baseURL string
+	http    *http.Client
+	token   string
+}
+
+// NewClient returns a client for the GitLab at baseURL, such as https://gitlab.bigbang.dev, that authenticates with a
+// personal access token. It makes its requests with httpClient.
+func NewClient(httpClient *http.Client, baseURL string, token string) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), http: httpClient, token: token}
+}
+
+// Token returns the personal access token of the client, which can also be used to push over HTTPS.
+func (client *Client) Token() string {
+	return client.token
+}
+
+// Project is a GitLab project.
+type Project struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+	WebURL            string `json:"web_url"`
+}
+
+// Pipeline is a CI pipeline of a project.
+type Pipeline struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Ref    string `json:"ref"`
+	SHA    string `json:"sha"`
+	WebURL string `json:"web_url"`
+}
+
+// Finished returns true if the pipeline won't run any more jobs.
+func (pipeline Pipeline) Finished() bool {
+	switch pipeline.Status {
+	case "success", "failed", "canceled", "skipped":
+		return true
+	default:
+		return false
+	}
+}
+
+// Job is a job of a pipeline.
+type Job struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Stage  string  `json:"stage"`
+	Status string  `json:"status"`
+	WebURL string  `json:"web_url"`
+	Runner *Runner `json:"runner"`
+}
+
+// Runner is the runner that picked up a job.
+type Runner struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+}
+
+// CreateProject creates a private project without any commits in the namespace of the user of the token.
+func (client *Client) CreateProject(name string) (*Project, error) {
+	project := new(Project)
+	body := map[string]interface{}{"name": name, "visibility": "private", "initialize_with_readme": false}
+	if _, err := client.do(http.MethodPost, "/projects", body, project); err != nil {
+		return nil, fmt.Errorf("unable to create project %s: %w", name, err)
+	}
+
+	return project, nil
+}
+
+// Project returns the project with the given path, such as root/my-project.
+func (client *Client) Project(path string) (*Project, error) {
+	project := new(Project)
+	if _, err := client.do(http.MethodGet, "/projects/"+url.PathEscape(path), nil, project); err != nil {
+		return nil, fmt.Errorf("unable to get project %s: %w", path, err)
+	}
+
+	return project, nil
+}
+
+// DeleteProject deletes a project. GitLab deletes it in the background, after this returns.
+func (client *Client) DeleteProject(projectID int) error {
+	if _, err := client.do(http.MethodDelete, fmt.Sprintf("/projects/%d", projectID), nil, nil); err != nil {
+		return fmt.Errorf("unable to delete project %d: %w", projectID, err)
+	}
+
+	return nil
+}
+
+// CreateProjectVariable adds a CI/CD variable to a project, which every job of its pipelines gets in its environment.
+// A masked variable is hidden in job logs, which GitLab only allows for values that are safe to search for.
+func (client *Client) CreateProjectVariable(projectID int, key string, value string, masked bool) error {
+	variable := map[string]interface{}{"key": key, "value": value, "masked": masked, "protected": false}
+	if _, err := client.do(http.MethodPost, fmt.Sprintf("/projects/%d/variables", projectID), variable, nil); err != nil {
+		return fmt.Errorf("unable to add variable %s to project %d: %w", key, projectID, err)
+	}
+
+	return nil
+}
+
+// BranchCommit returns the SHA of the commit a branch of a project points to.
+func (client *Client) BranchCommit(projectID int, branch string) (string, error) {
+	var found struct {
+		Commit struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+	}
+	if _, err := client.do(http.MethodGet, fmt.Sprintf("/projects/%d/repository/branches/%s", projectID, url.PathEscape(branch)), nil, &found); err != nil {
+		return "", fmt.Errorf("unable to get branch %s of project %d: %w", branch, projectID, err)
+	}
+
+	return found.Commit.ID, nil
+}
+
+// RawFile returns the content of a file in the repository of a project, at a branch, tag or commit.
+func (client *Client) RawFile(projectID int, path string, ref string) ([]byte, error) {
+	query := url.Values{"ref": {ref}}
+	content, err := client.do(http.MethodGet, fmt.Sprintf("/projects/%d/repository/files/%s/raw?%s", projectID, url.PathEscape(path), query.Encode()), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s of project %d at %s: %w", path, projectID, ref, err)
+	}
+
+	return content, nil
+}
+
+// Pipelines returns the pipelines of a project that ran for a commit, newest first.
+func (client *Client) Pipelines(projectID int, sha string) ([]Pipeline, error) {
+	var pipelines []Pipeline
+	query := url.Values{"sha": {sha}}
+	if _, err := client.do(http.MethodGet, fmt.Sprintf("/projects/%d/pipelines?%s", projectID, query.Encode()), nil, &pipelines); err != nil {
+		return nil, fmt.Errorf("unable to list pipelines of project %d: %w", projectID, err)
+	}
+
+	return pipelines, nil
+}
+
+// PipelineJobs returns the jobs of a pipeline.
+func (client *Client) PipelineJobs(projectID int, pipelineID int) ([]Job, error) {
+	var jobs []Job
+	if _, err := client.do(http.MethodGet, fmt.Sprintf("/projects/%d/pipelines/%d/jobs", projectID, pipelineID), nil, &jobs); err != nil {
+		return nil, fmt.Errorf("unable to list jobs of pipeline %d: %w", pipelineID, err)
+	}
+
+	return jobs, nil
+}
+
+// JobLog returns the log of a job, as shown on its page.
+func (client *Client) JobLog(projectID int, jobID int) (string, error) {
+	content, err := client.do(http.MethodGet, fmt.Sprintf("/projects/%d/jobs/%d/trace", projectID, jobID), nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to get log of job %d: %w", jobID, err)
+	}
+
+	return string(content), nil
+}
+
+// RevokeToken revokes the personal access token of the client, after which the client can't be used any more.
+func (client *Client) RevokeToken() error {
+	if _, err := client.do(http.MethodDelete, "/personal_access_tokens/self", nil, nil); err != nil {
+		return fmt.Errorf("unable to revoke personal access token: %w", err)
+	}
+
+	return nil
+}
+
+// do calls the API with body encoded as JSON, decodes the JSON response into out if it isn't nil, and returns the
+// content of the response. Responses that aren't a 2xx are returned as an error.
+func (client *Client) do(method string, apiPath string, body interface{}, out interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request, err := http.NewRequest(method, client.baseURL+"/api/v4"+apiPath, reader) //nolint:noctx
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+	request.Header.Set("PRIVATE-TOKEN", client.token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := client.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to %s %s: %w", method, apiPath, err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response of %s %s: %w", method, apiPath, err)
+	}
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s %s: %w", method, apiPath, ErrNotFound)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s returned HTTP %d: %s", method, apiPath, response.StatusCode, content)
+	}
+	if out != nil {
+		if err := json.Unmarshal(content, out); err != nil {
+			return nil, fmt.Errorf("unable to parse response of %s %s: %w", method, apiPath, err)
+		}
+	}
+
+	return content, nil
+}
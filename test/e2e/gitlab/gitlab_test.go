@@ -0,0 +1,59 @@
+package gitlab_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/gitlab"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/7/pipelines", func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("PRIVATE-TOKEN") != "glpat-test" {
+			http.Error(writer, `{"message":"401 Unauthorized"}`, http.StatusUnauthorized)
+
+			return
+		}
+		fmt.Fprintf(writer, `[{"id": 3, "status": "running", "sha": %q}]`, request.URL.Query().Get("sha"))
+	})
+	mux.HandleFunc("/api/v4/projects/7/jobs/4/trace", func(writer http.ResponseWriter, request *http.Request) {
+		fmt.Fprint(writer, "Using Kubernetes namespace: gitlab-runner-sandbox\n")
+	})
+	mux.HandleFunc("/api/v4/projects/", func(writer http.ResponseWriter, request *http.Request) {
+		// The path of a project is a single segment of the API path
+		if request.URL.EscapedPath() != "/api/v4/projects/root%2Fe2e-test" {
+			http.NotFound(writer, request)
+
+			return
+		}
+		fmt.Fprint(writer, `{"id": 7, "path_with_namespace": "root/e2e-test"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := gitlab.NewClient(server.Client(), server.URL+"/", "glpat-test")
+
+	pipelines, err := client.Pipelines(7, "abc")
+	require.NoError(t, err)
+	require.Equal(t, []gitlab.Pipeline{{ID: 3, Status: "running", SHA: "abc"}}, pipelines)
+	require.False(t, pipelines[0].Finished())
+
+	project, err := client.Project("root/e2e-test")
+	require.NoError(t, err)
+	require.Equal(t, &gitlab.Project{ID: 7, PathWithNamespace: "root/e2e-test"}, project)
+
+	jobLog, err := client.JobLog(7, 4)
+	require.NoError(t, err)
+	require.Equal(t, "Using Kubernetes namespace: gitlab-runner-sandbox\n", jobLog)
+
+	_, err = client.PipelineJobs(7, 3)
+	require.True(t, errors.Is(err, gitlab.ErrNotFound), "got %v", err)
+
+	_, err = gitlab.NewClient(server.Client(), server.URL, "wrong").Pipelines(7, "abc")
+	require.ErrorContains(t, err, "HTTP 401")
+}
@@ -0,0 +1,63 @@
+package keycloak
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// RealmClient is a client of a realm, such as the one GitLab logs in with, as represented by the admin API.
+type RealmClient struct {
+	// ID is the internal ID of the client that the admin API uses in its paths
+	ID string `json:"id,omitempty"`
+	// ClientID is the ID applications use to identify themselves, such as dev_00eb8904-5b88-4c68-ad67-cec0d2e07aa6_gitlab
+	ClientID                  string            `json:"clientId"`
+	Name                      string            `json:"name,omitempty"`
+	Protocol                  string            `json:"protocol,omitempty"`
+	Enabled                   bool              `json:"enabled"`
+	PublicClient              bool              `json:"publicClient"`
+	ConsentRequired           bool              `json:"consentRequired"`
+	StandardFlowEnabled       bool              `json:"standardFlowEnabled"`
+	DirectAccessGrantsEnabled bool              `json:"directAccessGrantsEnabled"`
+	RedirectURIs              []string          `json:"redirectUris,omitempty"`
+	DefaultClientScopes       []string          `json:"defaultClientScopes,omitempty"`
+	Attributes                map[string]string `json:"attributes,omitempty"`
+}
+
+// FindClient returns the client of the realm with the given client ID, or ErrNotFound if there is none.
+func (client *Client) FindClient(realm string, clientID string) (*RealmClient, error) {
+	var clients []RealmClient
+	query := url.Values{"clientId": {clientID}}
+	if _, err := client.do(http.MethodGet, realmPath(realm, "clients")+"?"+query.Encode(), nil, &clients); err != nil {
+		return nil, fmt.Errorf("unable to find client %s: %w", clientID, err)
+	}
+	for _, realmClient := range clients {
+		if realmClient.ClientID == clientID {
+			return &realmClient, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to find client %s: %w", clientID, ErrNotFound)
+}
+
+// Clients returns every client of the realm.
+func (client *Client) Clients(realm string) ([]RealmClient, error) {
+	var clients []RealmClient
+	if _, err := client.do(http.MethodGet, realmPath(realm, "clients"), nil, &clients); err != nil {
+		return nil, fmt.Errorf("unable to list clients: %w", err)
+	}
+
+	return clients, nil
+}
+
+// ExportRealm returns the realm as Keycloak exports it, in the same format as a realm import file like baby-yoda.json.
+// It includes clients, groups and roles, but not users. Secrets are masked.
+func (client *Client) ExportRealm(realm string) (map[string]interface{}, error) {
+	var export map[string]interface{}
+	query := url.Values{"exportClients": {"true"}, "exportGroupsAndRoles": {"true"}}
+	if _, err := client.do(http.MethodPost, realmPath(realm, "partial-export")+"?"+query.Encode(), nil, &export); err != nil {
+		return nil, fmt.Errorf("unable to export realm %s: %w", realm, err)
+	}
+
+	return export, nil
+}
@@ -0,0 +1,97 @@
+package keycloak
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Fixtures creates users, groups and roles in a realm for a test, and remembers them so Cleanup can delete them
+// again. Anything that is only changed rather than created, like the membership of an existing group, goes away with
+// the user or group it belongs to.
+type Fixtures struct {
+	client *Client
+	realm  string
+
+	mu       sync.Mutex
+	cleanups []fixtureCleanup
+}
+
+// fixtureCleanup deletes something Fixtures created.
+type fixtureCleanup struct {
+	description string
+	remove      func() error
+}
+
+// Fixtures returns fixtures that are created in the realm with this client.
+func (client *Client) Fixtures(realm string) *Fixtures {
+	return &Fixtures{client: client, realm: realm}
+}
+
+// Client returns the client the fixtures are created with, for anything Fixtures doesn't create itself.
+func (fixtures *Fixtures) Client() *Client {
+	return fixtures.client
+}
+
+// Realm returns the realm the fixtures are created in.
+func (fixtures *Fixtures) Realm() string {
+	return fixtures.realm
+}
+
+// CreateUser creates a user like Client.CreateUser and returns its ID. The user is deleted by Cleanup.
+func (fixtures *Fixtures) CreateUser(user User) (string, error) {
+	id, err := fixtures.client.CreateUser(fixtures.realm, user)
+	if id != "" {
+		// The user exists even if clearing its required actions failed
+		fixtures.add("user "+user.Username, func() error { return fixtures.client.DeleteUser(fixtures.realm, id) })
+	}
+
+	return id, err
+}
+
+// CreateGroup creates a top level group and returns its ID. The group is deleted by Cleanup.
+func (fixtures *Fixtures) CreateGroup(group Group) (string, error) {
+	id, err := fixtures.client.CreateGroup(fixtures.realm, group)
+	if err != nil {
+		return "", err
+	}
+	fixtures.add("group "+group.Name, func() error { return fixtures.client.DeleteGroup(fixtures.realm, id) })
+
+	return id, nil
+}
+
+// CreateRealmRole creates a realm role and returns it with its ID, so it can be mapped to users and groups. The role
+// is deleted by Cleanup.
+func (fixtures *Fixtures) CreateRealmRole(role Role) (*Role, error) {
+	if err := fixtures.client.CreateRealmRole(fixtures.realm, role); err != nil {
+		return nil, err
+	}
+	fixtures.add("realm role "+role.Name, func() error { return fixtures.client.DeleteRealmRole(fixtures.realm, role.Name) })
+
+	return fixtures.client.RealmRole(fixtures.realm, role.Name)
+}
+
+// Cleanup deletes everything the fixtures created, newest first, and forgets about it. Anything that is already gone
+// is skipped. It carries on past failures and returns them all together.
+func (fixtures *Fixtures) Cleanup() error {
+	fixtures.mu.Lock()
+	cleanups := fixtures.cleanups
+	fixtures.cleanups = nil
+	fixtures.mu.Unlock()
+
+	var errs []error
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		if err := cleanups[i].remove(); err != nil && !errors.Is(err, ErrNotFound) {
+			errs = append(errs, fmt.Errorf("unable to clean up %s: %w", cleanups[i].description, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// add remembers how to delete something that was created.
+func (fixtures *Fixtures) add(description string, remove func() error) {
+	fixtures.mu.Lock()
+	defer fixtures.mu.Unlock()
+	fixtures.cleanups = append(fixtures.cleanups, fixtureCleanup{description: description, remove: remove})
+}
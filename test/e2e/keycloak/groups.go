@@ -0,0 +1,88 @@
+package keycloak
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Group is a Keycloak group, as represented by the admin API.
+type Group struct {
+	ID          string              `json:"id,omitempty"`
+	Name        string              `json:"name"`
+	Path        string              `json:"path,omitempty"`
+	Attributes  map[string][]string `json:"attributes,omitempty"`
+	RealmRoles  []string            `json:"realmRoles,omitempty"`
+	ClientRoles map[string][]string `json:"clientRoles,omitempty"`
+	SubGroups   []Group             `json:"subGroups,omitempty"`
+}
+
+// CreateGroup creates a top level group in the realm and returns its ID.
+func (client *Client) CreateGroup(realm string, group Group) (string, error) {
+	response, err := client.do(http.MethodPost, realmPath(realm, "groups"), group, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create group %s: %w", group.Name, err)
+	}
+
+	return path.Base(response.Header.Get("Location")), nil
+}
+
+// Groups returns the groups of the realm, with their subgroups.
+func (client *Client) Groups(realm string) ([]Group, error) {
+	var groups []Group
+	if _, err := client.do(http.MethodGet, realmPath(realm, "groups")+"?briefRepresentation=false", nil, &groups); err != nil {
+		return nil, fmt.Errorf("unable to list groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// FindGroup returns the group with the given path, such as "/Impact Level 2 Authorized", or ErrNotFound if there is
+// none.
+func (client *Client) FindGroup(realm string, groupPath string) (*Group, error) {
+	group := new(Group)
+	segments := append([]string{"group-by-path"}, strings.Split(strings.TrimPrefix(groupPath, "/"), "/")...)
+	if _, err := client.do(http.MethodGet, realmPath(realm, segments...), nil, group); err != nil {
+		return nil, fmt.Errorf("unable to find group %s: %w", groupPath, err)
+	}
+
+	return group, nil
+}
+
+// DeleteGroup deletes a group and its subgroups from the realm.
+func (client *Client) DeleteGroup(realm string, groupID string) error {
+	if _, err := client.do(http.MethodDelete, realmPath(realm, "groups", groupID), nil, nil); err != nil {
+		return fmt.Errorf("unable to delete group %s: %w", groupID, err)
+	}
+
+	return nil
+}
+
+// AddUserToGroup makes a user a member of a group of the realm.
+func (client *Client) AddUserToGroup(realm string, userID string, groupID string) error {
+	if _, err := client.do(http.MethodPut, realmPath(realm, "users", userID, "groups", groupID), nil, nil); err != nil {
+		return fmt.Errorf("unable to add user %s to group %s: %w", userID, groupID, err)
+	}
+
+	return nil
+}
+
+// RemoveUserFromGroup ends the membership of a user in a group of the realm.
+func (client *Client) RemoveUserFromGroup(realm string, userID string, groupID string) error {
+	if _, err := client.do(http.MethodDelete, realmPath(realm, "users", userID, "groups", groupID), nil, nil); err != nil {
+		return fmt.Errorf("unable to remove user %s from group %s: %w", userID, groupID, err)
+	}
+
+	return nil
+}
+
+// UserGroups returns the groups a user is a member of.
+func (client *Client) UserGroups(realm string, userID string) ([]Group, error) {
+	var groups []Group
+	if _, err := client.do(http.MethodGet, realmPath(realm, "users", userID, "groups"), nil, &groups); err != nil {
+		return nil, fmt.Errorf("unable to list groups of user %s: %w", userID, err)
+	}
+
+	return groups, nil
+}
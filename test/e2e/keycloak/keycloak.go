@@ -0,0 +1,145 @@
+// Package keycloak is a client for the Keycloak admin API, and logs in through Keycloak's login pages, for the e2e
+// tests of the capabilities that use Keycloak for SSO. Tests create what they need in the realm through Fixtures, which
+// deletes it again afterwards.
+package keycloak
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Realm is the realm the software factory's capabilities use for SSO
+	Realm = "baby-yoda"
+	// AuthorizedGroupID is the ID of the "Impact Level 2 Authorized" group of the realm. The realm only lets members of
+	// this group log in to the clients whose IDs contain it, which is every client of the software factory.
+	AuthorizedGroupID = "00eb8904-5b88-4c68-ad67-cec0d2e07aa6"
+	// maxLoginSteps is how many Keycloak pages Login will submit before giving up
+	maxLoginSteps = 5
+	// tokenExpiryMargin is how long before it expires an admin access token gets replaced
+	tokenExpiryMargin = 10 * time.Second
+)
+
+// ErrNotFound is returned when the admin API says what was asked for doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Client calls the Keycloak admin API as a user of the master realm.
+type Client struct {
+	baseURL  string
+	http     *http.Client
+	username string
+	password string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewClient returns a client for the Keycloak at baseURL, such as https://keycloak.bigbang.dev, that logs in to the
+// admin API with the username and password of a user of the master realm. It makes its requests with httpClient.
+func NewClient(httpClient *http.Client, baseURL string, username string, password string) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), http: httpClient, username: username, password: password}
+}
+
+// do calls the admin API with body encoded as JSON, and decodes the JSON response into out if it isn't nil. Responses
+// that aren't a 2xx are returned as an error.
+func (client *Client) do(method string, apiPath string, body interface{}, out interface{}) (*http.Response, error) {
+	token, err := client.accessToken()
+	if err != nil {
+		return nil, err
+	}
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request, err := http.NewRequest(method, client.baseURL+"/auth"+apiPath, reader) //nolint:noctx
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := client.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to %s %s: %w", method, apiPath, err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response of %s %s: %w", method, apiPath, err)
+	}
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s %s: %w", method, apiPath, ErrNotFound)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s returned HTTP %d: %s", method, apiPath, response.StatusCode, content)
+	}
+	if out != nil {
+		if err := json.Unmarshal(content, out); err != nil {
+			return nil, fmt.Errorf("unable to parse response of %s %s: %w", method, apiPath, err)
+		}
+	}
+
+	return response, nil
+}
+
+// realmPath returns the path of the admin API of a realm, followed by the given path segments, escaping each of them.
+func realmPath(realm string, segments ...string) string {
+	apiPath := "/admin/realms/" + url.PathEscape(realm)
+	for _, segment := range segments {
+		apiPath += "/" + url.PathEscape(segment)
+	}
+
+	return apiPath
+}
+
+// accessToken returns an access token for the admin API, logging in again when the last one is about to expire. The
+// tokens of the master realm only last a minute by default.
+func (client *Client) accessToken() (string, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.token != "" && time.Now().Before(client.expiresAt) {
+		return client.token, nil
+	}
+
+	form := url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"admin-cli"},
+		"username":   {client.username},
+		"password":   {client.password},
+	}
+	response, err := client.http.PostForm(client.baseURL+"/auth/realms/master/protocol/openid-connect/token", form) //nolint:noctx
+	if err != nil {
+		return "", fmt.Errorf("unable to log in to the keycloak admin api: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		content, _ := io.ReadAll(response.Body)
+
+		return "", fmt.Errorf("unable to log in to the keycloak admin api, it returned HTTP %d: %s", response.StatusCode, content)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("unable to parse keycloak access token: %w", err)
+	}
+	client.token = token.AccessToken
+	client.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenExpiryMargin)
+
+	return client.token, nil
+}
@@ -0,0 +1,129 @@
+package keycloak_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/keycloak"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKeycloak is just enough of the Keycloak admin API to create and delete users, groups and realm roles.
+type fakeKeycloak struct {
+	mu      sync.Mutex
+	logins  int
+	objects map[string]bool
+	calls   []string
+}
+
+func (fake *fakeKeycloak) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if request.URL.Path == "/auth/realms/master/protocol/openid-connect/token" {
+		_ = request.ParseForm()
+		if request.PostForm.Get("username") != "admin" || request.PostForm.Get("password") != "secret" {
+			http.Error(writer, "invalid credentials", http.StatusUnauthorized)
+
+			return
+		}
+		fake.logins++
+		// Expires within the client's margin, so every call logs in again
+		_ = json.NewEncoder(writer).Encode(map[string]interface{}{"access_token": "token", "expires_in": 1})
+
+		return
+	}
+	if request.Header.Get("Authorization") != "Bearer token" {
+		http.Error(writer, "unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+	fake.calls = append(fake.calls, request.Method+" "+request.URL.EscapedPath())
+	const prefix = "/auth/admin/realms/test/"
+	path := strings.TrimPrefix(request.URL.EscapedPath(), prefix)
+	switch request.Method {
+	case http.MethodPost:
+		var body struct {
+			Username string `json:"username"`
+			Name     string `json:"name"`
+		}
+		_ = json.NewDecoder(request.Body).Decode(&body)
+		id := path + "/" + body.Username + body.Name
+		fake.objects[id] = true
+		writer.Header().Set("Location", "http://keycloak"+prefix+id)
+		writer.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		if !fake.objects[path] {
+			http.NotFound(writer, request)
+
+			return
+		}
+		_ = json.NewEncoder(writer).Encode(map[string]string{"id": path, "name": path})
+	case http.MethodPut:
+		writer.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if !fake.objects[path] {
+			http.NotFound(writer, request)
+
+			return
+		}
+		delete(fake.objects, path)
+		writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestFixturesCleanup(t *testing.T) {
+	t.Parallel()
+	fake := &fakeKeycloak{objects: make(map[string]bool)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := keycloak.NewClient(server.Client(), server.URL+"/", "admin", "secret")
+	fixtures := client.Fixtures("test")
+
+	userID, err := fixtures.CreateUser(keycloak.User{Username: "alice"})
+	require.NoError(t, err)
+	require.Equal(t, "alice", userID)
+	groupID, err := fixtures.CreateGroup(keycloak.Group{Name: "crew"})
+	require.NoError(t, err)
+	require.Equal(t, "crew", groupID)
+	role, err := fixtures.CreateRealmRole(keycloak.Role{Name: "pilot"})
+	require.NoError(t, err)
+	require.Equal(t, "roles/pilot", role.ID)
+	require.Equal(t, 5, fake.logins, "the expiring token should be replaced for every call")
+
+	// Something the test deleted itself is skipped
+	require.NoError(t, client.DeleteGroup("test", groupID))
+	fake.calls = nil
+	require.NoError(t, fixtures.Cleanup())
+	require.Equal(t, []string{
+		"DELETE /auth/admin/realms/test/roles/pilot",
+		"DELETE /auth/admin/realms/test/groups/crew",
+		"DELETE /auth/admin/realms/test/users/alice",
+	}, fake.calls)
+	require.Empty(t, fake.objects)
+
+	fake.calls = nil
+	require.NoError(t, fixtures.Cleanup())
+	require.Empty(t, fake.calls, "cleaned up fixtures should be forgotten")
+}
+
+func TestNotFound(t *testing.T) {
+	t.Parallel()
+	fake := &fakeKeycloak{objects: make(map[string]bool)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := keycloak.NewClient(server.Client(), server.URL, "admin", "secret")
+
+	_, err := client.GetUser("test", "bob")
+	require.True(t, errors.Is(err, keycloak.ErrNotFound), "got %v", err)
+	_, err = client.RealmRole("test", "pilot")
+	require.True(t, errors.Is(err, keycloak.ErrNotFound), "got %v", err)
+
+	client = keycloak.NewClient(server.Client(), server.URL, "admin", "wrong")
+	_, err = client.GetUser("test", "bob")
+	require.ErrorContains(t, err, "HTTP 401")
+}
@@ -0,0 +1,41 @@
+package keycloak
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/browser"
+)
+
+// Login fills in the Keycloak pages the browser was sent to, starting with page, until it is sent back to the
+// application. It logs in with the username and password, accepts the terms and conditions and consent pages the realm
+// shows, and posts SAML responses back to the application. It returns the page of the application the browser ended up
+// on.
+func Login(session *browser.Browser, page *browser.Page, username string, password string) (*browser.Page, error) {
+	var err error
+	loggedIn := false
+	for step := 0; step < maxLoginSteps; step++ {
+		if !strings.HasPrefix(page.URL.Path, "/auth/realms/") {
+			return page, nil
+		}
+		if form, ok := page.FormWithField("password"); ok {
+			if loggedIn {
+				return nil, fmt.Errorf("keycloak rejected the password of %s on page %q", username, page.Title())
+			}
+			loggedIn = true
+			page, err = session.Submit(form, map[string]string{"username": username, "password": password}, "")
+		} else if form, ok := page.FormWithButton("accept"); ok {
+			page, err = session.Submit(form, nil, "accept")
+		} else if form, ok := page.FormWithField("SAMLResponse"); ok {
+			// A browser posts the SAML response back to the application with JavaScript
+			page, err = session.Submit(form, nil, "")
+		} else {
+			return nil, fmt.Errorf("stuck on keycloak page %q at %s", page.Title(), page.URL)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("still on keycloak page %q at %s after %d steps", page.Title(), page.URL, maxLoginSteps)
+}
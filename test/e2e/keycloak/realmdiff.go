@@ -0,0 +1,222 @@
+package keycloak
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ignoredRealmFields are fields Keycloak generates or masks, so they differ between a realm file and the realm that was
+// imported from it without anything being wrong.
+var ignoredRealmFields = map[string]bool{"id": true, "containerId": true, "secret": true}
+
+// listKeys are the fields that identify the objects in the lists of a realm, such as clients by their client ID, in
+// order of preference. Lists of objects without any of them are compared by position.
+var listKeys = []string{"clientId", "name", "alias"}
+
+// Difference is something that differs between the realm file and the realm.
+type Difference struct {
+	// Path locates what differs, such as clients[gitlab].redirectUris
+	Path string
+	// Expected is what the realm file has, or nil if only the realm has it
+	Expected interface{}
+	// Actual is what the realm has, or nil if only the realm file has it
+	Actual interface{}
+}
+
+// String describes the difference, such as "clients[gitlab].redirectUris: missing https://gitlab.bigbang.dev/*".
+func (difference Difference) String() string {
+	switch {
+	case difference.Actual == nil:
+		return fmt.Sprintf("%s: missing %v", difference.Path, format(difference.Expected))
+	case difference.Expected == nil:
+		return fmt.Sprintf("%s: unexpected %v", difference.Path, format(difference.Actual))
+	default:
+		return fmt.Sprintf("%s: expected %v, got %v", difference.Path, format(difference.Expected), format(difference.Actual))
+	}
+}
+
+// DiffRealm compares the clients, with their redirect URIs and protocol mappers, and the roles of a realm, as returned
+// by ExportRealm, with the realm file it was imported from. Generated IDs and masked secrets are ignored, lists of
+// strings are compared as sets, and lists of objects are matched up by their client ID or name. Fields that only the
+// realm has are defaults Keycloak filled in and are ignored, but clients, mappers and roles that only the realm has
+// are differences. The differences are sorted by path.
+func DiffRealm(expected map[string]interface{}, actual map[string]interface{}) []Difference {
+	var differences []Difference
+	for _, field := range []string{"clients", "roles"} {
+		differences = append(differences, diffValues(field, expected[field], actual[field])...)
+	}
+	sort.SliceStable(differences, func(i, j int) bool { return differences[i].Path < differences[j].Path })
+
+	return differences
+}
+
+// diffValues compares two decoded JSON values at path.
+func diffValues(path string, expected interface{}, actual interface{}) []Difference {
+	if expected == nil {
+		return nil
+	}
+	if actual == nil {
+		return []Difference{{Path: path, Expected: expected}}
+	}
+	switch expectedValue := expected.(type) {
+	case map[string]interface{}:
+		actualValue, ok := actual.(map[string]interface{})
+		if !ok {
+			return []Difference{{Path: path, Expected: expected, Actual: actual}}
+		}
+
+		return diffObjects(path, expectedValue, actualValue)
+	case []interface{}:
+		actualValue, ok := actual.([]interface{})
+		if !ok {
+			return []Difference{{Path: path, Expected: expected, Actual: actual}}
+		}
+
+		return diffLists(path, expectedValue, actualValue)
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			return []Difference{{Path: path, Expected: expected, Actual: actual}}
+		}
+
+		return nil
+	}
+}
+
+// diffObjects compares the fields of the expected object with the same fields of the actual one.
+func diffObjects(path string, expected map[string]interface{}, actual map[string]interface{}) []Difference {
+	var differences []Difference
+	for _, field := range sortedKeys(expected) {
+		if ignoredRealmFields[field] {
+			continue
+		}
+		differences = append(differences, diffValues(path+"."+field, expected[field], actual[field])...)
+	}
+
+	return differences
+}
+
+// diffLists compares two lists, as sets if they hold strings and by key if they hold objects that have one.
+func diffLists(path string, expected []interface{}, actual []interface{}) []Difference {
+	if key := listKey(expected, actual); key != "" {
+		return diffKeyedLists(path, key, expected, actual)
+	}
+	if isStrings(expected) && isStrings(actual) {
+		var differences []Difference
+		for _, item := range missingFrom(expected, actual) {
+			differences = append(differences, Difference{Path: path, Expected: item})
+		}
+		for _, item := range missingFrom(actual, expected) {
+			differences = append(differences, Difference{Path: path, Actual: item})
+		}
+
+		return differences
+	}
+	if len(expected) != len(actual) {
+		return []Difference{{Path: path, Expected: expected, Actual: actual}}
+	}
+	var differences []Difference
+	for i := range expected {
+		differences = append(differences, diffValues(fmt.Sprintf("%s[%d]", path, i), expected[i], actual[i])...)
+	}
+
+	return differences
+}
+
+// diffKeyedLists matches up the objects of two lists by key and compares them.
+func diffKeyedLists(path string, key string, expected []interface{}, actual []interface{}) []Difference {
+	actualByKey := make(map[string]interface{})
+	for _, item := range actual {
+		actualByKey[fmt.Sprint(item.(map[string]interface{})[key])] = item //nolint:forcetypeassert
+	}
+	var differences []Difference
+	seen := make(map[string]bool)
+	for _, item := range expected {
+		id := fmt.Sprint(item.(map[string]interface{})[key]) //nolint:forcetypeassert
+		seen[id] = true
+		itemPath := fmt.Sprintf("%s[%s]", path, id)
+		if _, ok := actualByKey[id]; !ok {
+			differences = append(differences, Difference{Path: itemPath, Expected: id})
+
+			continue
+		}
+		differences = append(differences, diffValues(itemPath, item, actualByKey[id])...)
+	}
+	for _, item := range actual {
+		id := fmt.Sprint(item.(map[string]interface{})[key]) //nolint:forcetypeassert
+		if !seen[id] {
+			differences = append(differences, Difference{Path: fmt.Sprintf("%s[%s]", path, id), Actual: id})
+		}
+	}
+
+	return differences
+}
+
+// listKey returns the field that identifies every object in both lists, or an empty string if the lists don't only
+// hold objects with one of the listKeys.
+func listKey(lists ...[]interface{}) string {
+	for _, key := range listKeys {
+		found := true
+		for _, list := range lists {
+			for _, item := range list {
+				object, ok := item.(map[string]interface{})
+				if !ok || object[key] == nil {
+					found = false
+				}
+			}
+		}
+		if found && (len(lists[0]) > 0 || len(lists[1]) > 0) {
+			return key
+		}
+	}
+
+	return ""
+}
+
+// isStrings returns true if the list only holds strings.
+func isStrings(list []interface{}) bool {
+	for _, item := range list {
+		if _, ok := item.(string); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// missingFrom returns the items of list that other doesn't have.
+func missingFrom(list []interface{}, other []interface{}) []interface{} {
+	have := make(map[interface{}]bool)
+	for _, item := range other {
+		have[item] = true
+	}
+	var missing []interface{}
+	for _, item := range list {
+		if !have[item] {
+			missing = append(missing, item)
+		}
+	}
+
+	return missing
+}
+
+// sortedKeys returns the keys of an object in order, so differences are found in the same order every time.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// format renders a value of a difference on one line, quoting strings so empty ones are visible.
+func format(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return fmt.Sprintf("%q", text)
+	}
+
+	return strings.ReplaceAll(fmt.Sprint(value), "\n", " ")
+}
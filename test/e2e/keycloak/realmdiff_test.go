@@ -0,0 +1,73 @@
+package keycloak_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/keycloak"
+	"github.com/stretchr/testify/require"
+)
+
+// realm decodes a realm like ExportRealm does.
+func realm(t *testing.T, content string) map[string]interface{} {
+	t.Helper()
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(content), &decoded))
+
+	return decoded
+}
+
+func TestDiffRealm(t *testing.T) {
+	t.Parallel()
+	expected := realm(t, `{
+  "clients": [
+    {"id": "1", "clientId": "gitlab", "secret": "abc", "enabled": true,
+     "redirectUris": ["https://gitlab.bigbang.dev/*", "https://code.bigbang.dev/*"],
+     "protocolMappers": [{"id": "2", "name": "email", "config": {"claim.name": "email"}}]},
+    {"id": "3", "clientId": "sonarqube", "enabled": true}
+  ],
+  "roles": {
+    "realm": [{"id": "4", "name": "offline_access", "composite": false, "containerId": "baby-yoda"}],
+    "client": {"gitlab": [{"id": "5", "name": "admin"}]}
+  },
+  "displayName": "Baby Yoda"
+}`)
+	actual := realm(t, `{
+  "clients": [
+    {"id": "6", "clientId": "sonarqube", "enabled": false, "fullScopeAllowed": true},
+    {"id": "7", "clientId": "gitlab", "secret": "**********", "enabled": true,
+     "redirectUris": ["https://code.bigbang.dev/*", "https://evil.example.com/*"],
+     "protocolMappers": [{"id": "8", "name": "email", "config": {"claim.name": "mail"}}, {"id": "9", "name": "groups"}]},
+    {"id": "10", "clientId": "mattermost"}
+  ],
+  "roles": {
+    "realm": [{"id": "11", "name": "offline_access", "composite": false, "containerId": "other"}],
+    "client": {"gitlab": []}
+  },
+  "displayName": "Mando"
+}`)
+
+	var lines []string
+	for _, difference := range keycloak.DiffRealm(expected, actual) {
+		lines = append(lines, difference.String())
+	}
+	require.Equal(t, []string{
+		`clients[gitlab].protocolMappers[email].config.claim.name: expected "email", got "mail"`,
+		`clients[gitlab].protocolMappers[groups]: unexpected "groups"`,
+		`clients[gitlab].redirectUris: missing "https://gitlab.bigbang.dev/*"`,
+		`clients[gitlab].redirectUris: unexpected "https://evil.example.com/*"`,
+		`clients[mattermost]: unexpected "mattermost"`,
+		`clients[sonarqube].enabled: expected true, got false`,
+		`roles.client.gitlab[admin]: missing "admin"`,
+	}, lines)
+}
+
+func TestDiffRealmFile(t *testing.T) {
+	t.Parallel()
+	content, err := os.ReadFile("../../../packages/idam-realm/files/baby-yoda.json")
+	require.NoError(t, err)
+	expected := realm(t, string(content))
+	require.NotEmpty(t, expected["clients"])
+	require.Empty(t, keycloak.DiffRealm(expected, realm(t, string(content))))
+}
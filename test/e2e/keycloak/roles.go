@@ -0,0 +1,101 @@
+package keycloak
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Role is a realm or client role, as represented by the admin API.
+type Role struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Composite   bool   `json:"composite"`
+	ClientRole  bool   `json:"clientRole"`
+	ContainerID string `json:"containerId,omitempty"`
+}
+
+// CreateRealmRole creates a role in the realm.
+func (client *Client) CreateRealmRole(realm string, role Role) error {
+	if _, err := client.do(http.MethodPost, realmPath(realm, "roles"), role, nil); err != nil {
+		return fmt.Errorf("unable to create realm role %s: %w", role.Name, err)
+	}
+
+	return nil
+}
+
+// RealmRole returns the realm role with the given name, or ErrNotFound if there is none.
+func (client *Client) RealmRole(realm string, name string) (*Role, error) {
+	role := new(Role)
+	if _, err := client.do(http.MethodGet, realmPath(realm, "roles", name), nil, role); err != nil {
+		return nil, fmt.Errorf("unable to get realm role %s: %w", name, err)
+	}
+
+	return role, nil
+}
+
+// DeleteRealmRole deletes a role from the realm.
+func (client *Client) DeleteRealmRole(realm string, name string) error {
+	if _, err := client.do(http.MethodDelete, realmPath(realm, "roles", name), nil, nil); err != nil {
+		return fmt.Errorf("unable to delete realm role %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// ClientRole returns the role with the given name of a client, identified by its ID rather than its client ID, or
+// ErrNotFound if there is none.
+func (client *Client) ClientRole(realm string, clientID string, name string) (*Role, error) {
+	role := new(Role)
+	if _, err := client.do(http.MethodGet, realmPath(realm, "clients", clientID, "roles", name), nil, role); err != nil {
+		return nil, fmt.Errorf("unable to get role %s of client %s: %w", name, clientID, err)
+	}
+
+	return role, nil
+}
+
+// UserRealmRoles returns the realm roles mapped directly to a user.
+func (client *Client) UserRealmRoles(realm string, userID string) ([]Role, error) {
+	var roles []Role
+	if _, err := client.do(http.MethodGet, realmPath(realm, "users", userID, "role-mappings", "realm"), nil, &roles); err != nil {
+		return nil, fmt.Errorf("unable to list realm roles of user %s: %w", userID, err)
+	}
+
+	return roles, nil
+}
+
+// AddRealmRolesToUser maps realm roles to a user. The roles need their ID and name, as returned by RealmRole.
+func (client *Client) AddRealmRolesToUser(realm string, userID string, roles ...Role) error {
+	if _, err := client.do(http.MethodPost, realmPath(realm, "users", userID, "role-mappings", "realm"), roles, nil); err != nil {
+		return fmt.Errorf("unable to add realm roles to user %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+// RemoveRealmRolesFromUser removes realm roles that are mapped directly to a user.
+func (client *Client) RemoveRealmRolesFromUser(realm string, userID string, roles ...Role) error {
+	if _, err := client.do(http.MethodDelete, realmPath(realm, "users", userID, "role-mappings", "realm"), roles, nil); err != nil {
+		return fmt.Errorf("unable to remove realm roles from user %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+// AddClientRolesToUser maps roles of a client, identified by its ID rather than its client ID, to a user.
+func (client *Client) AddClientRolesToUser(realm string, userID string, clientID string, roles ...Role) error {
+	if _, err := client.do(http.MethodPost, realmPath(realm, "users", userID, "role-mappings", "clients", clientID), roles, nil); err != nil {
+		return fmt.Errorf("unable to add roles of client %s to user %s: %w", clientID, userID, err)
+	}
+
+	return nil
+}
+
+// AddRealmRolesToGroup maps realm roles to a group, and so to all of its members.
+func (client *Client) AddRealmRolesToGroup(realm string, groupID string, roles ...Role) error {
+	if _, err := client.do(http.MethodPost, realmPath(realm, "groups", groupID, "role-mappings", "realm"), roles, nil); err != nil {
+		return fmt.Errorf("unable to add realm roles to group %s: %w", groupID, err)
+	}
+
+	return nil
+}
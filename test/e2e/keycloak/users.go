@@ -0,0 +1,104 @@
+package keycloak
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+// User is a Keycloak user, as represented by the admin API.
+type User struct {
+	ID              string              `json:"id,omitempty"`
+	Username        string              `json:"username"`
+	Email           string              `json:"email,omitempty"`
+	FirstName       string              `json:"firstName,omitempty"`
+	LastName        string              `json:"lastName,omitempty"`
+	Enabled         bool                `json:"enabled"`
+	EmailVerified   bool                `json:"emailVerified"`
+	RequiredActions []string            `json:"requiredActions"`
+	Attributes      map[string][]string `json:"attributes,omitempty"`
+	Credentials     []Credential        `json:"credentials,omitempty"`
+}
+
+// Credential is a credential of a user, such as a password.
+type Credential struct {
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+	Temporary bool   `json:"temporary"`
+}
+
+// CreateUser creates a user in the realm and returns its ID. Required actions that the realm adds to new users by
+// default, like accepting the terms and conditions and verifying the email address, are cleared so the user can log in
+// right away.
+func (client *Client) CreateUser(realm string, user User) (string, error) {
+	if user.RequiredActions == nil {
+		user.RequiredActions = []string{}
+	}
+	response, err := client.do(http.MethodPost, realmPath(realm, "users"), user, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create user %s: %w", user.Username, err)
+	}
+	id := path.Base(response.Header.Get("Location"))
+	// Default required actions are added when the user is created, whatever it was created with
+	update := map[string]interface{}{"requiredActions": user.RequiredActions, "emailVerified": user.EmailVerified}
+	if _, err := client.do(http.MethodPut, realmPath(realm, "users", id), update, nil); err != nil {
+		return id, fmt.Errorf("unable to clear required actions of user %s: %w", user.Username, err)
+	}
+
+	return id, nil
+}
+
+// GetUser returns the user with the given ID.
+func (client *Client) GetUser(realm string, userID string) (*User, error) {
+	user := new(User)
+	if _, err := client.do(http.MethodGet, realmPath(realm, "users", userID), nil, user); err != nil {
+		return nil, fmt.Errorf("unable to get user %s: %w", userID, err)
+	}
+
+	return user, nil
+}
+
+// FindUser returns the user with exactly the given username, or ErrNotFound if there is none.
+func (client *Client) FindUser(realm string, username string) (*User, error) {
+	var users []User
+	query := url.Values{"username": {username}, "exact": {"true"}}
+	if _, err := client.do(http.MethodGet, realmPath(realm, "users")+"?"+query.Encode(), nil, &users); err != nil {
+		return nil, fmt.Errorf("unable to find user %s: %w", username, err)
+	}
+	for _, user := range users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to find user %s: %w", username, ErrNotFound)
+}
+
+// UpdateUser replaces the representation of a user with user. Fields that are left empty are left as they are.
+func (client *Client) UpdateUser(realm string, user User) error {
+	if _, err := client.do(http.MethodPut, realmPath(realm, "users", user.ID), user, nil); err != nil {
+		return fmt.Errorf("unable to update user %s: %w", user.Username, err)
+	}
+
+	return nil
+}
+
+// SetPassword sets the password of a user. A temporary password has to be changed the next time the user logs in.
+func (client *Client) SetPassword(realm string, userID string, password string, temporary bool) error {
+	credential := Credential{Type: "password", Value: password, Temporary: temporary}
+	if _, err := client.do(http.MethodPut, realmPath(realm, "users", userID, "reset-password"), credential, nil); err != nil {
+		return fmt.Errorf("unable to set password of user %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+// DeleteUser deletes a user from the realm.
+func (client *Client) DeleteUser(realm string, userID string) error {
+	if _, err := client.do(http.MethodDelete, realmPath(realm, "users", userID), nil, nil); err != nil {
+		return fmt.Errorf("unable to delete user %s: %w", userID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,316 @@
+// Package mattermost is a client for the parts of the Mattermost REST API that the e2e tests use to create teams,
+// channels and users, and to post messages with files.
+package mattermost
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// ErrNotFound is returned when the API says what was asked for doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Client calls the Mattermost API as a user that has logged in.
+type Client struct {
+	baseURL string
+	http    *http.Client
+	token   string
+}
+
+// Team is a Mattermost team.
+type Team struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	// Type is "O" for an open team and "I" for one people have to be invited to
+	Type string `json:"type"`
+}
+
+// Channel is a channel of a team.
+type Channel struct {
+	ID          string `json:"id,omitempty"`
+	TeamID      string `json:"team_id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	// Type is "O" for a public channel and "P" for a private one
+	Type string `json:"type"`
+}
+
+// User is a Mattermost user.
+type User struct {
+	ID       string `json:"id,omitempty"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password,omitempty"`
+	Roles    string `json:"roles,omitempty"`
+}
+
+// Post is a message in a channel.
+type Post struct {
+	ID        string   `json:"id,omitempty"`
+	ChannelID string   `json:"channel_id"`
+	UserID    string   `json:"user_id,omitempty"`
+	Message   string   `json:"message"`
+	FileIDs   []string `json:"file_ids,omitempty"`
+}
+
+// FileInfo describes a file that was uploaded.
+type FileInfo struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mime_type"`
+}
+
+// Login logs in to the Mattermost at baseURL, such as https://chat.bigbang.dev, with the username and password of a
+// user, and returns a client that makes its requests as that user with httpClient.
+func Login(httpClient *http.Client, baseURL string, username string, password string) (*Client, error) {
+	client := &Client{baseURL: strings.TrimSuffix(baseURL, "/"), http: httpClient}
+	body, err := json.Marshal(map[string]string{"login_id": username, "password": password})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal login: %w", err)
+	}
+	response, err := client.http.Post(client.baseURL+"/api/v4/users/login", "application/json", bytes.NewReader(body)) //nolint:noctx
+	if err != nil {
+		return nil, fmt.Errorf("unable to log in to mattermost as %s: %w", username, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		content, _ := io.ReadAll(response.Body)
+
+		return nil, fmt.Errorf("unable to log in to mattermost as %s, it returned HTTP %d: %s", username, response.StatusCode, content)
+	}
+	client.token = response.Header.Get("Token")
+
+	return client, nil
+}
+
+// Me returns the user the client is logged in as.
+func (client *Client) Me() (*User, error) {
+	user := new(User)
+	if err := client.do(http.MethodGet, "/users/me", nil, user); err != nil {
+		return nil, fmt.Errorf("unable to get the current user: %w", err)
+	}
+
+	return user, nil
+}
+
+// CreateTeam creates a team, which the user of the client becomes an admin of.
+func (client *Client) CreateTeam(team Team) (*Team, error) {
+	created := new(Team)
+	if err := client.do(http.MethodPost, "/teams", team, created); err != nil {
+		return nil, fmt.Errorf("unable to create team %s: %w", team.Name, err)
+	}
+
+	return created, nil
+}
+
+// Team returns the team with the given ID.
+func (client *Client) Team(teamID string) (*Team, error) {
+	team := new(Team)
+	if err := client.do(http.MethodGet, "/teams/"+teamID, nil, team); err != nil {
+		return nil, fmt.Errorf("unable to get team %s: %w", teamID, err)
+	}
+
+	return team, nil
+}
+
+// TeamByName returns the team with the given name.
+func (client *Client) TeamByName(name string) (*Team, error) {
+	team := new(Team)
+	if err := client.do(http.MethodGet, "/teams/name/"+name, nil, team); err != nil {
+		return nil, fmt.Errorf("unable to get team %s: %w", name, err)
+	}
+
+	return team, nil
+}
+
+// DeleteTeam archives a team. Mattermost only deletes it for good if the API is allowed to.
+func (client *Client) DeleteTeam(teamID string) error {
+	if err := client.do(http.MethodDelete, "/teams/"+teamID, nil, nil); err != nil {
+		return fmt.Errorf("unable to delete team %s: %w", teamID, err)
+	}
+
+	return nil
+}
+
+// AddTeamMember adds a user to a team.
+func (client *Client) AddTeamMember(teamID string, userID string) error {
+	member := map[string]string{"team_id": teamID, "user_id": userID}
+	if err := client.do(http.MethodPost, "/teams/"+teamID+"/members", member, nil); err != nil {
+		return fmt.Errorf("unable to add user %s to team %s: %w", userID, teamID, err)
+	}
+
+	return nil
+}
+
+// CreateChannel creates a channel in a team.
+func (client *Client) CreateChannel(channel Channel) (*Channel, error) {
+	created := new(Channel)
+	if err := client.do(http.MethodPost, "/channels", channel, created); err != nil {
+		return nil, fmt.Errorf("unable to create channel %s: %w", channel.Name, err)
+	}
+
+	return created, nil
+}
+
+// ChannelByName returns the channel of a team with the given name.
+func (client *Client) ChannelByName(teamID string, name string) (*Channel, error) {
+	channel := new(Channel)
+	if err := client.do(http.MethodGet, "/teams/"+teamID+"/channels/name/"+name, nil, channel); err != nil {
+		return nil, fmt.Errorf("unable to get channel %s: %w", name, err)
+	}
+
+	return channel, nil
+}
+
+// AddChannelMember adds a user, who has to be a member of the channel's team, to a channel.
+func (client *Client) AddChannelMember(channelID string, userID string) error {
+	if err := client.do(http.MethodPost, "/channels/"+channelID+"/members", map[string]string{"user_id": userID}, nil); err != nil {
+		return fmt.Errorf("unable to add user %s to channel %s: %w", userID, channelID, err)
+	}
+
+	return nil
+}
+
+// CreateUser creates a user. Only an admin can do that when open sign up is turned off.
+func (client *Client) CreateUser(user User) (*User, error) {
+	created := new(User)
+	if err := client.do(http.MethodPost, "/users", user, created); err != nil {
+		return nil, fmt.Errorf("unable to create user %s: %w", user.Username, err)
+	}
+
+	return created, nil
+}
+
+// DeactivateUser deactivates a user, which is as far as the API goes unless it is allowed to delete users for good.
+func (client *Client) DeactivateUser(userID string) error {
+	if err := client.do(http.MethodDelete, "/users/"+userID, nil, nil); err != nil {
+		return fmt.Errorf("unable to deactivate user %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+// UploadFile uploads a file to a channel, to be attached to a post.
+func (client *Client) UploadFile(channelID string, name string, content []byte) (*FileInfo, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("channel_id", channelID); err != nil {
+		return nil, fmt.Errorf("unable to write upload of %s: %w", name, err)
+	}
+	part, err := writer.CreateFormFile("files", name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write upload of %s: %w", name, err)
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, fmt.Errorf("unable to write upload of %s: %w", name, err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("unable to write upload of %s: %w", name, err)
+	}
+	var uploaded struct {
+		FileInfos []FileInfo `json:"file_infos"`
+	}
+	if err := client.send(http.MethodPost, "/files", writer.FormDataContentType(), &body, &uploaded); err != nil {
+		return nil, fmt.Errorf("unable to upload %s: %w", name, err)
+	}
+	if len(uploaded.FileInfos) != 1 {
+		return nil, fmt.Errorf("uploading %s returned %d files", name, len(uploaded.FileInfos))
+	}
+
+	return &uploaded.FileInfos[0], nil
+}
+
+// File downloads the content of a file.
+func (client *Client) File(fileID string) ([]byte, error) {
+	var content bytes.Buffer
+	if err := client.send(http.MethodGet, "/files/"+fileID, "", nil, &content); err != nil {
+		return nil, fmt.Errorf("unable to download file %s: %w", fileID, err)
+	}
+
+	return content.Bytes(), nil
+}
+
+// CreatePost posts a message.
+func (client *Client) CreatePost(post Post) (*Post, error) {
+	created := new(Post)
+	if err := client.do(http.MethodPost, "/posts", post, created); err != nil {
+		return nil, fmt.Errorf("unable to create post in channel %s: %w", post.ChannelID, err)
+	}
+
+	return created, nil
+}
+
+// ChannelPosts returns the most recent posts of a channel, by their ID.
+func (client *Client) ChannelPosts(channelID string) (map[string]Post, error) {
+	var posts struct {
+		Posts map[string]Post `json:"posts"`
+	}
+	if err := client.do(http.MethodGet, "/channels/"+channelID+"/posts", nil, &posts); err != nil {
+		return nil, fmt.Errorf("unable to get posts of channel %s: %w", channelID, err)
+	}
+
+	return posts.Posts, nil
+}
+
+// do calls the API with body encoded as JSON, and decodes the JSON response into out if it isn't nil.
+func (client *Client) do(method string, apiPath string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("unable to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(encoded)
+		contentType = "application/json"
+	}
+
+	return client.send(method, apiPath, contentType, reader, out)
+}
+
+// send calls the API with body as it is. It decodes the JSON response into out, or copies the response into it if
+// it is a bytes.Buffer. Responses that aren't a 2xx are returned as an error.
+func (client *Client) send(method string, apiPath string, contentType string, body io.Reader, out interface{}) error {
+	request, err := http.NewRequest(method, client.baseURL+"/api/v4"+apiPath, body) //nolint:noctx
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+client.token)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	response, err := client.http.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to %s %s: %w", method, apiPath, err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response of %s %s: %w", method, apiPath, err)
+	}
+	if response.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s %s: %w", method, apiPath, ErrNotFound)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned HTTP %d: %s", method, apiPath, response.StatusCode, content)
+	}
+	switch out := out.(type) {
+	case nil:
+	case *bytes.Buffer:
+		out.Write(content)
+	default:
+		if err := json.Unmarshal(content, out); err != nil {
+			return fmt.Errorf("unable to parse response of %s %s: %w", method, apiPath, err)
+		}
+	}
+
+	return nil
+}
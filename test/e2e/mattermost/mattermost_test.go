@@ -0,0 +1,61 @@
+package mattermost_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/mattermost"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadAndDownload(t *testing.T) {
+	t.Parallel()
+	files := make(map[string][]byte)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/users/login", func(writer http.ResponseWriter, request *http.Request) {
+		var login map[string]string
+		_ = json.NewDecoder(request.Body).Decode(&login)
+		if login["login_id"] != "alice" || login["password"] != "secret" {
+			http.Error(writer, `{"id":"api.user.login.invalid_credentials_email_username"}`, http.StatusUnauthorized)
+
+			return
+		}
+		writer.Header().Set("Token", "session-token")
+		_, _ = writer.Write([]byte(`{"id":"alice-id","username":"alice"}`))
+	})
+	mux.HandleFunc("/api/v4/files", func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer session-token" || request.FormValue("channel_id") != "town-square" {
+			http.Error(writer, "bad request", http.StatusBadRequest)
+
+			return
+		}
+		file, header, err := request.FormFile("files")
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+		content, _ := io.ReadAll(file)
+		files["file-id"] = content
+		_ = json.NewEncoder(writer).Encode(map[string]interface{}{"file_infos": []map[string]interface{}{{"id": "file-id", "name": header.Filename, "size": len(content)}}})
+	})
+	mux.HandleFunc("/api/v4/files/file-id", func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write(files["file-id"])
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	_, err := mattermost.Login(server.Client(), server.URL, "alice", "wrong")
+	require.ErrorContains(t, err, "HTTP 401")
+	client, err := mattermost.Login(server.Client(), server.URL, "alice", "secret")
+	require.NoError(t, err)
+	file, err := client.UploadFile("town-square", "e2e.txt", []byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, mattermost.FileInfo{ID: "file-id", Name: "e2e.txt", Size: 5}, *file)
+	content, err := client.File(file.ID)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), content)
+}
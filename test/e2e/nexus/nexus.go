@@ -0,0 +1,186 @@
+// Package nexus is a client for the parts of the Nexus Repository Manager that the e2e tests use: the REST API to
+// manage hosted repositories, the repositories themselves to upload and download artifacts, and the Docker registry
+// API of hosted Docker repositories.
+package nexus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotFound is returned when Nexus says what was asked for doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Client calls Nexus as a user with basic auth.
+type Client struct {
+	baseURL  string
+	http     *http.Client
+	username string
+	password string
+}
+
+// Asset is a file in a repository.
+type Asset struct {
+	Path        string            `json:"path"`
+	DownloadURL string            `json:"downloadUrl"`
+	Checksum    map[string]string `json:"checksum"`
+}
+
+// NewClient returns a client for the Nexus at baseURL, such as https://nexus.bigbang.dev, that authenticates as a
+// user with basic auth. It makes its requests with httpClient.
+func NewClient(httpClient *http.Client, baseURL string, username string, password string) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), http: httpClient, username: username, password: password}
+}
+
+// CreateRawHostedRepository creates a hosted repository for files of any kind in the default blob store.
+func (client *Client) CreateRawHostedRepository(name string) error {
+	return client.createRepository("raw", name, nil)
+}
+
+// CreateMavenHostedRepository creates a hosted repository for Maven releases in the default blob store.
+func (client *Client) CreateMavenHostedRepository(name string) error {
+	return client.createRepository("maven", name, map[string]interface{}{
+		"maven": map[string]interface{}{"versionPolicy": "RELEASE", "layoutPolicy": "STRICT", "contentDisposition": "ATTACHMENT"},
+	})
+}
+
+// CreateDockerHostedRepository creates a hosted Docker repository in the default blob store. It doesn't get a port of
+// its own, so its registry API is reached under the path of the repository, see Registry.
+func (client *Client) CreateDockerHostedRepository(name string) error {
+	return client.createRepository("docker", name, map[string]interface{}{
+		"docker": map[string]interface{}{"v1Enabled": false, "forceBasicAuth": true},
+	})
+}
+
+// DeleteRepository deletes a repository and everything in it.
+func (client *Client) DeleteRepository(name string) error {
+	if _, err := client.do(http.MethodDelete, "/service/rest/v1/repositories/"+url.PathEscape(name), "", nil, nil); err != nil {
+		return fmt.Errorf("unable to delete repository %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Upload puts a file into a raw or Maven repository at path.
+func (client *Client) Upload(repository string, path string, content []byte) error {
+	if _, err := client.do(http.MethodPut, client.repositoryPath(repository, path), "application/octet-stream", bytes.NewReader(content), nil); err != nil {
+		return fmt.Errorf("unable to upload %s to %s: %w", path, repository, err)
+	}
+
+	return nil
+}
+
+// Download gets a file from a repository.
+func (client *Client) Download(repository string, path string) ([]byte, error) {
+	content, err := client.do(http.MethodGet, client.repositoryPath(repository, path), "", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s from %s: %w", path, repository, err)
+	}
+
+	return content, nil
+}
+
+// Assets returns the files in a repository, with the checksums Nexus computed for them.
+func (client *Client) Assets(repository string) ([]Asset, error) {
+	var assets []Asset
+	continuation := ""
+	for {
+		query := url.Values{"repository": {repository}}
+		if continuation != "" {
+			query.Set("continuationToken", continuation)
+		}
+		var page struct {
+			Items             []Asset `json:"items"`
+			ContinuationToken string  `json:"continuationToken"`
+		}
+		if _, err := client.do(http.MethodGet, "/service/rest/v1/assets?"+query.Encode(), "", nil, &page); err != nil {
+			return nil, fmt.Errorf("unable to list assets of %s: %w", repository, err)
+		}
+		assets = append(assets, page.Items...)
+		if page.ContinuationToken == "" {
+			return assets, nil
+		}
+		continuation = page.ContinuationToken
+	}
+}
+
+// createRepository creates a hosted repository of the given format, with the settings specific to the format.
+func (client *Client) createRepository(format string, name string, settings map[string]interface{}) error {
+	repository := map[string]interface{}{
+		"name":    name,
+		"online":  true,
+		"storage": map[string]interface{}{"blobStoreName": "default", "strictContentTypeValidation": true, "writePolicy": "allow"},
+	}
+	for key, value := range settings {
+		repository[key] = value
+	}
+	body, err := json.Marshal(repository)
+	if err != nil {
+		return fmt.Errorf("unable to marshal repository %s: %w", name, err)
+	}
+	if _, err := client.do(http.MethodPost, "/service/rest/v1/repositories/"+format+"/hosted", "application/json", bytes.NewReader(body), nil); err != nil {
+		return fmt.Errorf("unable to create %s repository %s: %w", format, name, err)
+	}
+
+	return nil
+}
+
+// repositoryPath returns the path of a file in a repository.
+func (client *Client) repositoryPath(repository string, path string) string {
+	return "/repository/" + url.PathEscape(repository) + "/" + strings.TrimPrefix(path, "/")
+}
+
+// do sends a request to Nexus, decodes the JSON response into out if it isn't nil, and returns the content of the
+// response. Responses that aren't a 2xx are returned as an error.
+func (client *Client) do(method string, path string, contentType string, body io.Reader, out interface{}) ([]byte, error) {
+	content, _, err := client.send(method, client.baseURL+path, contentType, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	if out != nil {
+		if err := json.Unmarshal(content, out); err != nil {
+			return nil, fmt.Errorf("unable to parse response of %s %s: %w", method, path, err)
+		}
+	}
+
+	return content, nil
+}
+
+// send sends a request with basic auth and returns the content and headers of the response. Responses that aren't a
+// 2xx are returned as an error.
+func (client *Client) send(method string, rawURL string, contentType string, headers map[string]string, body io.Reader) ([]byte, http.Header, error) {
+	request, err := http.NewRequest(method, rawURL, body) //nolint:noctx
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create request: %w", err)
+	}
+	request.SetBasicAuth(client.username, client.password)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	response, err := client.http.Do(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to %s %s: %w", method, request.URL.Path, err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read response of %s %s: %w", method, request.URL.Path, err)
+	}
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil, fmt.Errorf("%s %s: %w", method, request.URL.Path, ErrNotFound)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("%s %s returned HTTP %d: %s", method, request.URL.Path, response.StatusCode, content)
+	}
+
+	return content, response.Header, nil
+}
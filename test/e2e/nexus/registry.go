@@ -0,0 +1,185 @@
+package nexus
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// manifestMediaType is the media type of the image manifests pushed by Registry.Push
+	manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	// configMediaType is the media type of an image config
+	configMediaType = "application/vnd.oci.image.config.v1+json"
+	// layerMediaType is the media type of a gzipped layer
+	layerMediaType = "application/vnd.oci.image.layer.v1.tar+gzip"
+)
+
+// Image is an OCI image, as the blobs it is made of.
+type Image struct {
+	// Config is the image config
+	Config []byte
+	// Layers are the gzipped tarballs of the layers, bottom first
+	Layers [][]byte
+}
+
+// Descriptor points to a blob of an image by its digest.
+type Descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int    `json:"size"`
+}
+
+// Manifest is an OCI image manifest.
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// Registry is the Docker registry API of a hosted Docker repository. Nexus serves it under the path of the
+// repository, so it is reached through the same ingress as the rest of Nexus.
+type Registry struct {
+	client  *Client
+	baseURL string
+}
+
+// NewTestImage returns an image with a single layer that only holds a file with the given name and content.
+func NewTestImage(name string, content []byte) (Image, error) {
+	var layer bytes.Buffer
+	var diff bytes.Buffer
+	archive := tar.NewWriter(&diff)
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: time.Unix(0, 0)} //nolint:gomnd
+	if err := archive.WriteHeader(header); err != nil {
+		return Image{}, fmt.Errorf("unable to write layer: %w", err)
+	}
+	if _, err := archive.Write(content); err != nil {
+		return Image{}, fmt.Errorf("unable to write layer: %w", err)
+	}
+	if err := archive.Close(); err != nil {
+		return Image{}, fmt.Errorf("unable to write layer: %w", err)
+	}
+	compressed := gzip.NewWriter(&layer)
+	if _, err := compressed.Write(diff.Bytes()); err != nil {
+		return Image{}, fmt.Errorf("unable to compress layer: %w", err)
+	}
+	if err := compressed.Close(); err != nil {
+		return Image{}, fmt.Errorf("unable to compress layer: %w", err)
+	}
+	config, err := json.Marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"config":       map[string]interface{}{},
+		"rootfs":       map[string]interface{}{"type": "layers", "diff_ids": []string{Digest(diff.Bytes())}},
+	})
+	if err != nil {
+		return Image{}, fmt.Errorf("unable to marshal image config: %w", err)
+	}
+
+	return Image{Config: config, Layers: [][]byte{layer.Bytes()}}, nil
+}
+
+// Digest returns the digest of a blob, such as "sha256:e3b0c442...".
+func Digest(blob []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(blob))
+}
+
+// Registry returns the registry API of a hosted Docker repository.
+func (client *Client) Registry(repository string) *Registry {
+	return &Registry{client: client, baseURL: client.baseURL + "/repository/" + url.PathEscape(repository)}
+}
+
+// Push uploads the blobs of an image and tags its manifest, and returns the digest of the manifest.
+func (registry *Registry) Push(name string, tag string, image Image) (string, error) {
+	manifest := Manifest{
+		SchemaVersion: 2, //nolint:gomnd
+		MediaType:     manifestMediaType,
+		Config:        Descriptor{MediaType: configMediaType, Digest: Digest(image.Config), Size: len(image.Config)},
+	}
+	if err := registry.pushBlob(name, image.Config); err != nil {
+		return "", err
+	}
+	for _, layer := range image.Layers {
+		if err := registry.pushBlob(name, layer); err != nil {
+			return "", err
+		}
+		manifest.Layers = append(manifest.Layers, Descriptor{MediaType: layerMediaType, Digest: Digest(layer), Size: len(layer)})
+	}
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal manifest: %w", err)
+	}
+	if _, _, err := registry.client.send(http.MethodPut, fmt.Sprintf("%s/v2/%s/manifests/%s", registry.baseURL, name, tag), manifestMediaType, nil, bytes.NewReader(content)); err != nil {
+		return "", fmt.Errorf("unable to push manifest of %s:%s: %w", name, tag, err)
+	}
+
+	return Digest(content), nil
+}
+
+// Pull downloads the manifest of a tag and the blobs it points to, checking each against its digest, and returns the
+// image with the digest of its manifest.
+func (registry *Registry) Pull(name string, tag string) (Image, string, error) {
+	content, _, err := registry.client.send(http.MethodGet, fmt.Sprintf("%s/v2/%s/manifests/%s", registry.baseURL, name, tag), "", map[string]string{"Accept": manifestMediaType}, nil)
+	if err != nil {
+		return Image{}, "", fmt.Errorf("unable to pull manifest of %s:%s: %w", name, tag, err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return Image{}, "", fmt.Errorf("unable to parse manifest of %s:%s: %w", name, tag, err)
+	}
+	var image Image
+	if image.Config, err = registry.pullBlob(name, manifest.Config); err != nil {
+		return Image{}, "", err
+	}
+	for _, descriptor := range manifest.Layers {
+		layer, err := registry.pullBlob(name, descriptor)
+		if err != nil {
+			return Image{}, "", err
+		}
+		image.Layers = append(image.Layers, layer)
+	}
+
+	return image, Digest(content), nil
+}
+
+// pushBlob uploads a blob in a single request.
+func (registry *Registry) pushBlob(name string, blob []byte) error {
+	uploadsURL := fmt.Sprintf("%s/v2/%s/blobs/uploads/", registry.baseURL, name)
+	_, headers, err := registry.client.send(http.MethodPost, uploadsURL, "", nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to start upload of %s: %w", Digest(blob), err)
+	}
+	base, _ := url.Parse(uploadsURL)
+	location, err := base.Parse(headers.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("upload of %s has an invalid location: %w", Digest(blob), err)
+	}
+	query := location.Query()
+	query.Set("digest", Digest(blob))
+	location.RawQuery = query.Encode()
+	if _, _, err := registry.client.send(http.MethodPut, location.String(), "application/octet-stream", nil, bytes.NewReader(blob)); err != nil {
+		return fmt.Errorf("unable to upload %s: %w", Digest(blob), err)
+	}
+
+	return nil
+}
+
+// pullBlob downloads a blob and checks it against its descriptor.
+func (registry *Registry) pullBlob(name string, descriptor Descriptor) ([]byte, error) {
+	blob, _, err := registry.client.send(http.MethodGet, fmt.Sprintf("%s/v2/%s/blobs/%s", registry.baseURL, name, descriptor.Digest), "", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to download %s: %w", descriptor.Digest, err)
+	}
+	if digest := Digest(blob); digest != descriptor.Digest || len(blob) != descriptor.Size {
+		return nil, fmt.Errorf("blob %s has digest %s and %d bytes, the manifest says %d bytes", descriptor.Digest, digest, len(blob), descriptor.Size)
+	}
+
+	return blob, nil
+}
@@ -0,0 +1,87 @@
+package nexus_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/nexus"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistry stores blobs and manifests of a hosted Docker repository called "docker-hosted" in memory.
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+}
+
+func (fake *fakeRegistry) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if username, password, _ := request.BasicAuth(); username != "admin" || password != "secret" {
+		http.Error(writer, "unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+	path := strings.TrimPrefix(request.URL.Path, "/repository/docker-hosted/v2/e2e/hello/")
+	content, _ := io.ReadAll(request.Body)
+	switch {
+	case request.Method == http.MethodPost && path == "blobs/uploads/":
+		writer.Header().Set("Location", "/repository/docker-hosted/v2/e2e/hello/blobs/uploads/upload-1?_state=abc")
+		writer.WriteHeader(http.StatusAccepted)
+	case request.Method == http.MethodPut && path == "blobs/uploads/upload-1":
+		if request.URL.Query().Get("_state") != "abc" || nexus.Digest(content) != request.URL.Query().Get("digest") {
+			http.Error(writer, "digest mismatch", http.StatusBadRequest)
+
+			return
+		}
+		fake.blobs[nexus.Digest(content)] = content
+		writer.WriteHeader(http.StatusCreated)
+	case request.Method == http.MethodPut && strings.HasPrefix(path, "manifests/"):
+		fake.manifests[strings.TrimPrefix(path, "manifests/")] = content
+		writer.WriteHeader(http.StatusCreated)
+	case request.Method == http.MethodGet && strings.HasPrefix(path, "manifests/"):
+		_, _ = writer.Write(fake.manifests[strings.TrimPrefix(path, "manifests/")])
+	case request.Method == http.MethodGet && strings.HasPrefix(path, "blobs/"):
+		blob, ok := fake.blobs[strings.TrimPrefix(path, "blobs/")]
+		if !ok {
+			http.NotFound(writer, request)
+
+			return
+		}
+		_, _ = writer.Write(blob)
+	default:
+		http.Error(writer, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestRegistryPushAndPull(t *testing.T) {
+	t.Parallel()
+	fake := &fakeRegistry{blobs: make(map[string][]byte), manifests: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	registry := nexus.NewClient(server.Client(), server.URL, "admin", "secret").Registry("docker-hosted")
+
+	image, err := nexus.NewTestImage("hello.txt", []byte("hello\n"))
+	require.NoError(t, err)
+	pushed, err := registry.Push("e2e/hello", "1.0.0", image)
+	require.NoError(t, err)
+	require.Len(t, fake.blobs, 2)
+	require.Equal(t, nexus.Digest(fake.manifests["1.0.0"]), pushed)
+
+	pulled, digest, err := registry.Pull("e2e/hello", "1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, pushed, digest)
+	require.Equal(t, image, pulled)
+
+	// A blob that doesn't match its digest is rejected
+	for digest := range fake.blobs {
+		fake.blobs[digest] = append(fake.blobs[digest], '!')
+	}
+	_, _, err = registry.Pull("e2e/hello", "1.0.0")
+	require.ErrorContains(t, err, "the manifest says")
+}
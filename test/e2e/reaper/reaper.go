@@ -0,0 +1,370 @@
+// Package reaper finds and destroys AWS infrastructure left behind by e2e test runs that never got to tear down
+package reaper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+const (
+	// RunPrefix is the prefix of the name of every run of the test harness, made up of the namespace and stage that
+	// SetupTestPlatform passes to Terraform. Key pairs and security groups are named after the run, and instances are
+	// named after the run with a "-public" suffix.
+	RunPrefix = "uds-swf-terratest-"
+	// RunTag is the tag Terraform puts on every resource it creates, holding the name of the run.
+	RunTag = "uds-swf/run"
+)
+
+// Kinds of resources that belong to a run.
+const (
+	KindInstance      = "instance"
+	KindVpc           = "vpc"
+	KindSecurityGroup = "security-group"
+	KindKeyPair       = "key-pair"
+)
+
+// hourlyInstanceCost is the approximate on-demand cost in USD per hour of the instance types the harness uses.
+var hourlyInstanceCost = map[string]float64{
+	"m6i.12xlarge":  2.304,
+	"m6id.12xlarge": 2.8476,
+	"m5.12xlarge":   2.304,
+}
+
+// hourlyVolumeCost is the approximate cost in USD per hour of the 400 GiB gp3 root volume with 16000 IOPS and 500 MiB/s
+// of throughput that every test instance gets.
+const hourlyVolumeCost = 0.153
+
+// Resource is a single piece of AWS infrastructure that belongs to a run.
+type Resource struct {
+	Kind string
+	ID   string
+	// InstanceType is only set for instances
+	InstanceType string
+	// CreatedAt is zero for resources AWS doesn't report a creation time for
+	CreatedAt time.Time
+}
+
+// Run is all the infrastructure left behind by one run of the test harness.
+type Run struct {
+	Name      string
+	Resources []Resource
+}
+
+// CreatedAt returns the earliest creation time of the run's resources. It is zero if none of them have one.
+func (run Run) CreatedAt() time.Time {
+	var createdAt time.Time
+	for _, resource := range run.Resources {
+		if !resource.CreatedAt.IsZero() && (createdAt.IsZero() || resource.CreatedAt.Before(createdAt)) {
+			createdAt = resource.CreatedAt
+		}
+	}
+
+	return createdAt
+}
+
+// HourlyCost returns the estimated cost in USD per hour of keeping the run's instances around, and whether the cost of
+// every instance type was known.
+func (run Run) HourlyCost() (float64, bool) {
+	cost := 0.0
+	known := true
+	for _, resource := range run.Resources {
+		if resource.Kind != KindInstance {
+			continue
+		}
+		instanceCost, ok := hourlyInstanceCost[resource.InstanceType]
+		known = known && ok
+		cost += instanceCost + hourlyVolumeCost
+	}
+
+	return cost, known
+}
+
+// Reaper finds and destroys runs using the EC2 API.
+type Reaper struct {
+	EC2 ec2iface.EC2API
+	// Now returns the current time, it is a field so tests can control it
+	Now func() time.Time
+}
+
+// New creates a Reaper that uses the given EC2 client.
+func New(client ec2iface.EC2API) *Reaper {
+	return &Reaper{EC2: client, Now: time.Now}
+}
+
+// Find returns the runs that are older than the threshold, sorted by name. A run's age comes from its key pair and
+// instances. A run with neither is always returned, since the key pair is created before and deleted after everything
+// else, so infrastructure without one can only be left over from a run that crashed.
+func (reaper *Reaper) Find(olderThan time.Duration) ([]Run, error) {
+	runs := make(map[string]*Run)
+	add := func(name string, resource Resource) {
+		if runs[name] == nil {
+			runs[name] = &Run{Name: name}
+		}
+		runs[name].Resources = append(runs[name].Resources, resource)
+	}
+	vpcRuns := make(map[string]string)
+
+	instances, err := reaper.instances()
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances {
+		name := runName(instance.Tags, strings.TrimSuffix(tagValue(instance.Tags, "Name"), "-public"))
+		if name == "" {
+			continue
+		}
+		add(name, Resource{Kind: KindInstance, ID: aws.StringValue(instance.InstanceId), InstanceType: aws.StringValue(instance.InstanceType), CreatedAt: aws.TimeValue(instance.LaunchTime)})
+		if instance.VpcId != nil {
+			vpcRuns[aws.StringValue(instance.VpcId)] = name
+		}
+	}
+
+	groups, err := reaper.securityGroups()
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		name := runName(group.Tags, aws.StringValue(group.GroupName))
+		if name == "" {
+			continue
+		}
+		add(name, Resource{Kind: KindSecurityGroup, ID: aws.StringValue(group.GroupId)})
+		if group.VpcId != nil {
+			vpcRuns[aws.StringValue(group.VpcId)] = name
+		}
+	}
+
+	// VPCs created before every resource was tagged with the run are only found through their instances and security groups
+	vpcs, err := reaper.vpcs()
+	if err != nil {
+		return nil, err
+	}
+	for _, vpc := range vpcs {
+		name := runName(vpc.Tags, "")
+		if name == "" {
+			name = vpcRuns[aws.StringValue(vpc.VpcId)]
+		}
+		if name == "" {
+			continue
+		}
+		add(name, Resource{Kind: KindVpc, ID: aws.StringValue(vpc.VpcId)})
+	}
+
+	keyPairs, err := reaper.EC2.DescribeKeyPairs(&ec2.DescribeKeyPairsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe key pairs: %w", err)
+	}
+	for _, keyPair := range keyPairs.KeyPairs {
+		name := runName(keyPair.Tags, aws.StringValue(keyPair.KeyName))
+		if name == "" {
+			continue
+		}
+		add(name, Resource{Kind: KindKeyPair, ID: aws.StringValue(keyPair.KeyName), CreatedAt: aws.TimeValue(keyPair.CreateTime)})
+	}
+
+	cutoff := reaper.Now().Add(-olderThan)
+	var old []Run
+	for _, run := range runs {
+		createdAt := run.CreatedAt()
+		if createdAt.IsZero() || createdAt.Before(cutoff) {
+			old = append(old, *run)
+		}
+	}
+	sort.Slice(old, func(i, j int) bool { return old[i].Name < old[j].Name })
+
+	return old, nil
+}
+
+// Destroy removes all of a run's infrastructure. Instances go first since everything else depends on them, and key
+// pairs go last, matching the order Teardown uses, so a partially destroyed run is still found by Find.
+func (reaper *Reaper) Destroy(run Run) error {
+	var instanceIDs []*string
+	for _, resource := range resourcesOfKind(run, KindInstance) {
+		instanceIDs = append(instanceIDs, aws.String(resource.ID))
+	}
+	if len(instanceIDs) > 0 {
+		if _, err := reaper.EC2.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: instanceIDs}); err != nil {
+			return fmt.Errorf("unable to terminate instances of %s: %w", run.Name, err)
+		}
+		if err := reaper.EC2.WaitUntilInstanceTerminated(&ec2.DescribeInstancesInput{InstanceIds: instanceIDs}); err != nil {
+			return fmt.Errorf("unable to wait for instances of %s to terminate: %w", run.Name, err)
+		}
+	}
+
+	for _, resource := range resourcesOfKind(run, KindSecurityGroup) {
+		if _, err := reaper.EC2.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{GroupId: aws.String(resource.ID)}); err != nil {
+			return fmt.Errorf("unable to delete security group %s of %s: %w", resource.ID, run.Name, err)
+		}
+	}
+
+	for _, resource := range resourcesOfKind(run, KindVpc) {
+		if err := reaper.destroyVpc(resource.ID); err != nil {
+			return fmt.Errorf("unable to delete vpc %s of %s: %w", resource.ID, run.Name, err)
+		}
+	}
+
+	for _, resource := range resourcesOfKind(run, KindKeyPair) {
+		if _, err := reaper.EC2.DeleteKeyPair(&ec2.DeleteKeyPairInput{KeyName: aws.String(resource.ID)}); err != nil {
+			return fmt.Errorf("unable to delete key pair %s of %s: %w", resource.ID, run.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// destroyVpc deletes a VPC along with the internet gateways, subnets and route tables Terraform created in it.
+func (reaper *Reaper) destroyVpc(vpcID string) error {
+	filter := []*ec2.Filter{{Name: aws.String("vpc-id"), Values: []*string{aws.String(vpcID)}}}
+
+	gateways, err := reaper.EC2.DescribeInternetGateways(&ec2.DescribeInternetGatewaysInput{
+		Filters: []*ec2.Filter{{Name: aws.String("attachment.vpc-id"), Values: []*string{aws.String(vpcID)}}},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to describe internet gateways: %w", err)
+	}
+	for _, gateway := range gateways.InternetGateways {
+		if _, err := reaper.EC2.DetachInternetGateway(&ec2.DetachInternetGatewayInput{InternetGatewayId: gateway.InternetGatewayId, VpcId: aws.String(vpcID)}); err != nil {
+			return fmt.Errorf("unable to detach internet gateway: %w", err)
+		}
+		if _, err := reaper.EC2.DeleteInternetGateway(&ec2.DeleteInternetGatewayInput{InternetGatewayId: gateway.InternetGatewayId}); err != nil {
+			return fmt.Errorf("unable to delete internet gateway: %w", err)
+		}
+	}
+
+	subnets, err := reaper.EC2.DescribeSubnets(&ec2.DescribeSubnetsInput{Filters: filter})
+	if err != nil {
+		return fmt.Errorf("unable to describe subnets: %w", err)
+	}
+	for _, subnet := range subnets.Subnets {
+		if _, err := reaper.EC2.DeleteSubnet(&ec2.DeleteSubnetInput{SubnetId: subnet.SubnetId}); err != nil {
+			return fmt.Errorf("unable to delete subnet: %w", err)
+		}
+	}
+
+	routeTables, err := reaper.EC2.DescribeRouteTables(&ec2.DescribeRouteTablesInput{Filters: filter})
+	if err != nil {
+		return fmt.Errorf("unable to describe route tables: %w", err)
+	}
+	for _, routeTable := range routeTables.RouteTables {
+		if isMainRouteTable(routeTable) {
+			continue
+		}
+		if _, err := reaper.EC2.DeleteRouteTable(&ec2.DeleteRouteTableInput{RouteTableId: routeTable.RouteTableId}); err != nil {
+			return fmt.Errorf("unable to delete route table: %w", err)
+		}
+	}
+
+	if _, err := reaper.EC2.DeleteVpc(&ec2.DeleteVpcInput{VpcId: aws.String(vpcID)}); err != nil {
+		return fmt.Errorf("unable to delete vpc: %w", err)
+	}
+
+	return nil
+}
+
+// instances returns every instance that hasn't been terminated.
+func (reaper *Reaper) instances() ([]*ec2.Instance, error) {
+	var instances []*ec2.Instance
+	input := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{"pending", "running", "stopping", "stopped"})}},
+	}
+	for {
+		output, err := reaper.EC2.DescribeInstances(input)
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe instances: %w", err)
+		}
+		for _, reservation := range output.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+		if aws.StringValue(output.NextToken) == "" {
+			return instances, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// securityGroups returns every security group.
+func (reaper *Reaper) securityGroups() ([]*ec2.SecurityGroup, error) {
+	var groups []*ec2.SecurityGroup
+	input := &ec2.DescribeSecurityGroupsInput{}
+	for {
+		output, err := reaper.EC2.DescribeSecurityGroups(input)
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe security groups: %w", err)
+		}
+		groups = append(groups, output.SecurityGroups...)
+		if aws.StringValue(output.NextToken) == "" {
+			return groups, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// vpcs returns every VPC.
+func (reaper *Reaper) vpcs() ([]*ec2.Vpc, error) {
+	var vpcs []*ec2.Vpc
+	input := &ec2.DescribeVpcsInput{}
+	for {
+		output, err := reaper.EC2.DescribeVpcs(input)
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe vpcs: %w", err)
+		}
+		vpcs = append(vpcs, output.Vpcs...)
+		if aws.StringValue(output.NextToken) == "" {
+			return vpcs, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// runName returns the name of the run a resource belongs to, from its run tag or otherwise from its name. It returns
+// an empty string if the resource doesn't belong to the test harness.
+func runName(tags []*ec2.Tag, name string) string {
+	if run := tagValue(tags, RunTag); run != "" {
+		return run
+	}
+	if strings.HasPrefix(name, RunPrefix) {
+		return name
+	}
+
+	return ""
+}
+
+// tagValue returns the value of the tag with the given key, or an empty string.
+func tagValue(tags []*ec2.Tag, key string) string {
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) == key {
+			return aws.StringValue(tag.Value)
+		}
+	}
+
+	return ""
+}
+
+// isMainRouteTable returns true for the route table AWS creates with the VPC, which is deleted along with it.
+func isMainRouteTable(routeTable *ec2.RouteTable) bool {
+	for _, association := range routeTable.Associations {
+		if aws.BoolValue(association.Main) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resourcesOfKind returns the run's resources of the given kind.
+func resourcesOfKind(run Run, kind string) []Resource {
+	var resources []Resource
+	for _, resource := range run.Resources {
+		if resource.Kind == kind {
+			resources = append(resources, resource)
+		}
+	}
+
+	return resources
+}
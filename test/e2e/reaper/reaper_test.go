@@ -0,0 +1,170 @@
+package reaper_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/reaper"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEC2 is a local stand-in for the EC2 API that holds a fixed set of resources and records what gets deleted.
+type fakeEC2 struct {
+	ec2iface.EC2API
+	instances      []*ec2.Instance
+	securityGroups []*ec2.SecurityGroup
+	vpcs           []*ec2.Vpc
+	keyPairs       []*ec2.KeyPairInfo
+	deleted        []string
+}
+
+func (fake *fakeEC2) DescribeInstances(*ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: fake.instances}}}, nil
+}
+
+func (fake *fakeEC2) DescribeSecurityGroups(*ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: fake.securityGroups}, nil
+}
+
+func (fake *fakeEC2) DescribeVpcs(*ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	return &ec2.DescribeVpcsOutput{Vpcs: fake.vpcs}, nil
+}
+
+func (fake *fakeEC2) DescribeKeyPairs(*ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error) {
+	return &ec2.DescribeKeyPairsOutput{KeyPairs: fake.keyPairs}, nil
+}
+
+func (fake *fakeEC2) TerminateInstances(input *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+	for _, id := range input.InstanceIds {
+		fake.deleted = append(fake.deleted, aws.StringValue(id))
+	}
+
+	return &ec2.TerminateInstancesOutput{}, nil
+}
+
+func (fake *fakeEC2) WaitUntilInstanceTerminated(*ec2.DescribeInstancesInput) error {
+	return nil
+}
+
+func (fake *fakeEC2) DeleteSecurityGroup(input *ec2.DeleteSecurityGroupInput) (*ec2.DeleteSecurityGroupOutput, error) {
+	fake.deleted = append(fake.deleted, aws.StringValue(input.GroupId))
+
+	return &ec2.DeleteSecurityGroupOutput{}, nil
+}
+
+func (fake *fakeEC2) DescribeInternetGateways(*ec2.DescribeInternetGatewaysInput) (*ec2.DescribeInternetGatewaysOutput, error) {
+	return &ec2.DescribeInternetGatewaysOutput{InternetGateways: []*ec2.InternetGateway{{InternetGatewayId: aws.String("igw-1")}}}, nil
+}
+
+func (fake *fakeEC2) DetachInternetGateway(*ec2.DetachInternetGatewayInput) (*ec2.DetachInternetGatewayOutput, error) {
+	return &ec2.DetachInternetGatewayOutput{}, nil
+}
+
+func (fake *fakeEC2) DeleteInternetGateway(input *ec2.DeleteInternetGatewayInput) (*ec2.DeleteInternetGatewayOutput, error) {
+	fake.deleted = append(fake.deleted, aws.StringValue(input.InternetGatewayId))
+
+	return &ec2.DeleteInternetGatewayOutput{}, nil
+}
+
+func (fake *fakeEC2) DescribeSubnets(*ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	return &ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{{SubnetId: aws.String("subnet-1")}}}, nil
+}
+
+func (fake *fakeEC2) DeleteSubnet(input *ec2.DeleteSubnetInput) (*ec2.DeleteSubnetOutput, error) {
+	fake.deleted = append(fake.deleted, aws.StringValue(input.SubnetId))
+
+	return &ec2.DeleteSubnetOutput{}, nil
+}
+
+func (fake *fakeEC2) DescribeRouteTables(*ec2.DescribeRouteTablesInput) (*ec2.DescribeRouteTablesOutput, error) {
+	return &ec2.DescribeRouteTablesOutput{RouteTables: []*ec2.RouteTable{
+		{RouteTableId: aws.String("rtb-main"), Associations: []*ec2.RouteTableAssociation{{Main: aws.Bool(true)}}},
+		{RouteTableId: aws.String("rtb-public")},
+	}}, nil
+}
+
+func (fake *fakeEC2) DeleteRouteTable(input *ec2.DeleteRouteTableInput) (*ec2.DeleteRouteTableOutput, error) {
+	fake.deleted = append(fake.deleted, aws.StringValue(input.RouteTableId))
+
+	return &ec2.DeleteRouteTableOutput{}, nil
+}
+
+func (fake *fakeEC2) DeleteVpc(input *ec2.DeleteVpcInput) (*ec2.DeleteVpcOutput, error) {
+	fake.deleted = append(fake.deleted, aws.StringValue(input.VpcId))
+
+	return &ec2.DeleteVpcOutput{}, nil
+}
+
+func (fake *fakeEC2) DeleteKeyPair(input *ec2.DeleteKeyPairInput) (*ec2.DeleteKeyPairOutput, error) {
+	fake.deleted = append(fake.deleted, aws.StringValue(input.KeyName))
+
+	return &ec2.DeleteKeyPairOutput{}, nil
+}
+
+func tags(keyValues ...string) []*ec2.Tag {
+	var tags []*ec2.Tag
+	for i := 0; i < len(keyValues); i += 2 {
+		tags = append(tags, &ec2.Tag{Key: aws.String(keyValues[i]), Value: aws.String(keyValues[i+1])})
+	}
+
+	return tags
+}
+
+func TestReaper(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	fake := &fakeEC2{
+		instances: []*ec2.Instance{
+			// A crashed run from yesterday, found by its legacy name tag
+			{InstanceId: aws.String("i-old"), InstanceType: aws.String("m6i.12xlarge"), LaunchTime: aws.Time(now.Add(-26 * time.Hour)), VpcId: aws.String("vpc-old"), Tags: tags("Name", "uds-swf-terratest-e2e-old-public")},
+			// A run that is still going
+			{InstanceId: aws.String("i-new"), InstanceType: aws.String("m6i.12xlarge"), LaunchTime: aws.Time(now.Add(-1 * time.Hour)), VpcId: aws.String("vpc-new"), Tags: tags("Name", "uds-swf-terratest-e2e-new-public", reaper.RunTag, "uds-swf-terratest-e2e-new")},
+			// Something that has nothing to do with the tests
+			{InstanceId: aws.String("i-other"), InstanceType: aws.String("t3.micro"), LaunchTime: aws.Time(now.Add(-100 * time.Hour)), Tags: tags("Name", "bastion")},
+		},
+		securityGroups: []*ec2.SecurityGroup{
+			{GroupId: aws.String("sg-old"), GroupName: aws.String("uds-swf-terratest-e2e-old"), VpcId: aws.String("vpc-old")},
+			{GroupId: aws.String("sg-new"), GroupName: aws.String("uds-swf-terratest-e2e-new"), VpcId: aws.String("vpc-new")},
+			{GroupId: aws.String("sg-default"), GroupName: aws.String("default"), VpcId: aws.String("vpc-old")},
+		},
+		vpcs: []*ec2.Vpc{
+			{VpcId: aws.String("vpc-old"), Tags: tags("Name", "terratest-vpc")},
+			{VpcId: aws.String("vpc-new"), Tags: tags("Name", "terratest-vpc", reaper.RunTag, "uds-swf-terratest-e2e-new")},
+			// Left behind by a run that crashed while Terraform was still creating things, after its key pair was deleted
+			{VpcId: aws.String("vpc-orphan"), Tags: tags("Name", "terratest-vpc", reaper.RunTag, "uds-swf-terratest-e2e-orphan")},
+			{VpcId: aws.String("vpc-other"), Tags: tags("Name", "production")},
+		},
+		keyPairs: []*ec2.KeyPairInfo{
+			{KeyName: aws.String("uds-swf-terratest-e2e-old"), CreateTime: aws.Time(now.Add(-26 * time.Hour))},
+			{KeyName: aws.String("uds-swf-terratest-e2e-new"), CreateTime: aws.Time(now.Add(-1 * time.Hour))},
+			{KeyName: aws.String("someones-laptop")},
+		},
+	}
+	r := reaper.New(fake)
+	r.Now = func() time.Time { return now }
+
+	runs, err := r.Find(6 * time.Hour)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+
+	require.Equal(t, "uds-swf-terratest-e2e-old", runs[0].Name)
+	require.Equal(t, now.Add(-26*time.Hour), runs[0].CreatedAt())
+	hourly, known := runs[0].HourlyCost()
+	require.True(t, known)
+	require.InDelta(t, 2.457, hourly, 0.001)
+	require.ElementsMatch(t, []reaper.Resource{
+		{Kind: reaper.KindInstance, ID: "i-old", InstanceType: "m6i.12xlarge", CreatedAt: now.Add(-26 * time.Hour)},
+		{Kind: reaper.KindSecurityGroup, ID: "sg-old"},
+		{Kind: reaper.KindVpc, ID: "vpc-old"},
+		{Kind: reaper.KindKeyPair, ID: "uds-swf-terratest-e2e-old", CreatedAt: now.Add(-26 * time.Hour)},
+	}, runs[0].Resources)
+
+	require.Equal(t, "uds-swf-terratest-e2e-orphan", runs[1].Name)
+	require.True(t, runs[1].CreatedAt().IsZero())
+
+	require.NoError(t, r.Destroy(runs[0]))
+	require.Equal(t, []string{"i-old", "sg-old", "igw-1", "subnet-1", "rtb-public", "vpc-old", "uds-swf-terratest-e2e-old"}, fake.deleted)
+}
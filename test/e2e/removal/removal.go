@@ -0,0 +1,124 @@
+// Package removal checks that removing the software factory bundle cleans up what the packages of the repo created,
+// apart from what is known to be retained.
+package removal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// RetainedFile lists what is expected to stay after the bundle is removed, relative to the root of the repo.
+	RetainedFile = "test/e2e/retained.yaml"
+	// KindHostsBlock is the kind of the hosts block software-factory-idam-dns adds to the Corefile of CoreDNS, between
+	// #swf-begin and #swf-end. It is named after the ConfigMap that holds the Corefile.
+	KindHostsBlock = "HostsBlock"
+)
+
+// Retained is a resource that is expected to stay after the bundle is removed.
+type Retained struct {
+	bundle.Resource `yaml:",inline"`
+	// Reason is why the resource stays
+	Reason string `yaml:"reason"`
+}
+
+// Result is what was still there after the bundle was removed.
+type Result struct {
+	// Leftovers are the resources that are still there without being listed as retained
+	Leftovers []bundle.Resource
+	// Retained are the resources that are still there and listed as retained
+	Retained []Retained
+}
+
+// ReadRetained returns the resources in RetainedFile. Every one of them needs a reason.
+func ReadRetained(repoRoot string) ([]Retained, error) {
+	var file struct {
+		Retained []Retained `yaml:"retained"`
+	}
+	content, err := os.ReadFile(filepath.Join(repoRoot, RetainedFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", RetainedFile, err)
+	}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", RetainedFile, err)
+	}
+	for _, retained := range file.Retained {
+		if retained.Kind == "" || retained.Name == "" || retained.Reason == "" {
+			return nil, fmt.Errorf("every resource in %s needs a kind, a name and a reason, found %+v", RetainedFile, retained)
+		}
+	}
+
+	return file.Retained, nil
+}
+
+// Check asks present whether each of the expected resources is still there, and sorts the ones that are into
+// leftovers and retained resources.
+func Check(expected []bundle.Resource, retained []Retained, present func(bundle.Resource) (bool, error)) (*Result, error) {
+	reasons := make(map[bundle.Resource]Retained, len(retained))
+	for _, resource := range retained {
+		reasons[resource.Resource] = resource
+	}
+	result := new(Result)
+	for _, resource := range expected {
+		found, err := present(resource)
+		if err != nil {
+			return nil, fmt.Errorf("unable to check %s: %w", resource, err)
+		}
+		if !found {
+			continue
+		}
+		if kept, ok := reasons[resource]; ok {
+			result.Retained = append(result.Retained, kept)
+		} else {
+			result.Leftovers = append(result.Leftovers, resource)
+		}
+	}
+
+	return result, nil
+}
+
+// OK returns true if nothing was left over that isn't listed as retained.
+func (result *Result) OK() bool {
+	return len(result.Leftovers) == 0
+}
+
+// String returns the leftovers grouped by kind and namespace, followed by the retained resources with their reason.
+func (result *Result) String() string {
+	var text strings.Builder
+	if result.OK() {
+		text.WriteString("Nothing was left over after the bundle was removed\n")
+	} else {
+		text.WriteString("Left over after the bundle was removed:\n")
+		groups := make(map[string][]string)
+		for _, leftover := range result.Leftovers {
+			group := leftover.Kind
+			if leftover.Namespace != "" {
+				group = fmt.Sprintf("%s in %s", leftover.Kind, leftover.Namespace)
+			}
+			groups[group] = append(groups[group], leftover.Name)
+		}
+		names := make([]string, 0, len(groups))
+		for group := range groups {
+			names = append(names, group)
+		}
+		sort.Strings(names)
+		for _, group := range names {
+			sort.Strings(groups[group])
+			fmt.Fprintf(&text, "  %s: %s\n", group, strings.Join(groups[group], ", "))
+		}
+	}
+	if len(result.Retained) > 0 {
+		text.WriteString("Retained:\n")
+		for _, retained := range result.Retained {
+			fmt.Fprintf(&text, "  %s: %s\n", retained.Resource, retained.Reason)
+		}
+	}
+
+	return text.String()
+}
@@ -0,0 +1,51 @@
+package removal_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/removal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRetained(t *testing.T) {
+	t.Parallel()
+	retained, err := removal.ReadRetained("../../..")
+	require.NoError(t, err)
+	require.NotEmpty(t, retained)
+	require.Equal(t, bundle.Resource{Kind: removal.KindHostsBlock, Namespace: "kube-system", Name: "coredns"}, retained[0].Resource)
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	hosts := bundle.Resource{Kind: removal.KindHostsBlock, Namespace: "kube-system", Name: "coredns"}
+	expected := []bundle.Resource{
+		{Kind: "Namespace", Name: "gitlab"},
+		{Kind: "Namespace", Name: "jira"},
+		{Kind: "PolicyException", Namespace: "keycloak", Name: "keycloak-postgres-registry-exception"},
+		{Kind: "PolicyException", Namespace: "keycloak", Name: "keycloak-postgres-external-names-exception"},
+		{Kind: "Secret", Namespace: "gitlab", Name: "gitlab-sso-provider"},
+		hosts,
+	}
+	retained := []removal.Retained{{Resource: hosts, Reason: "no onRemove action"}}
+	left := map[string]bool{"jira": true, "keycloak-postgres-registry-exception": true, "keycloak-postgres-external-names-exception": true, "coredns": true}
+
+	result, err := removal.Check(expected, retained, func(resource bundle.Resource) (bool, error) {
+		return left[resource.Name], nil
+	})
+	require.NoError(t, err)
+	require.False(t, result.OK())
+	require.Equal(t, retained, result.Retained)
+	require.Equal(t, `Left over after the bundle was removed:
+  Namespace: jira
+  PolicyException in keycloak: keycloak-postgres-external-names-exception, keycloak-postgres-registry-exception
+Retained:
+  HostsBlock kube-system/coredns: no onRemove action
+`, result.String())
+
+	_, err = removal.Check(expected, nil, func(resource bundle.Resource) (bool, error) {
+		return false, errors.New("connection refused")
+	})
+	require.ErrorContains(t, err, "unable to check Namespace gitlab: connection refused")
+}
@@ -0,0 +1,171 @@
+// Package sonarqube is a client for the parts of the SonarQube Web API that the e2e tests use to create tokens and
+// projects, and follow analyses.
+package sonarqube
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrNotFound is returned when the API says what was asked for doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// The statuses of a background task, such as the one that processes an analysis report.
+const (
+	TaskPending    = "PENDING"
+	TaskInProgress = "IN_PROGRESS"
+	TaskSuccess    = "SUCCESS"
+	TaskFailed     = "FAILED"
+	TaskCanceled   = "CANCELED"
+)
+
+// Client calls the SonarQube Web API as a user with basic auth. A token is used as the username with an empty password.
+type Client struct {
+	baseURL  string
+	http     *http.Client
+	username string
+	password string
+}
+
+// Task is a background task of the compute engine, such as processing an analysis report.
+type Task struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	Status       string `json:"status"`
+	ComponentKey string `json:"componentKey"`
+	AnalysisID   string `json:"analysisId"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// NewClient returns a client for the SonarQube at baseURL, such as https://sonarqube.bigbang.dev, that authenticates
+// with a username and password. It makes its requests with httpClient.
+func NewClient(httpClient *http.Client, baseURL string, username string, password string) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), http: httpClient, username: username, password: password}
+}
+
+// GenerateToken generates a token for the user of the client, with the permissions of that user, and returns it.
+func (client *Client) GenerateToken(name string) (string, error) {
+	var token struct {
+		Token string `json:"token"`
+	}
+	if err := client.do(http.MethodPost, "/api/user_tokens/generate", url.Values{"name": {name}}, &token); err != nil {
+		return "", fmt.Errorf("unable to generate token %s: %w", name, err)
+	}
+
+	return token.Token, nil
+}
+
+// RevokeToken revokes a token of the user of the client by its name.
+func (client *Client) RevokeToken(name string) error {
+	if err := client.do(http.MethodPost, "/api/user_tokens/revoke", url.Values{"name": {name}}, nil); err != nil {
+		return fmt.Errorf("unable to revoke token %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Tasks returns the background tasks of a project that have finished or are still queued, newest first.
+func (client *Client) Tasks(projectKey string) ([]Task, error) {
+	var activity struct {
+		Tasks []Task `json:"tasks"`
+	}
+	query := url.Values{"component": {projectKey}, "status": {strings.Join([]string{TaskPending, TaskInProgress, TaskSuccess, TaskFailed, TaskCanceled}, ",")}}
+	if err := client.do(http.MethodGet, "/api/ce/activity", query, &activity); err != nil {
+		return nil, fmt.Errorf("unable to get tasks of project %s: %w", projectKey, err)
+	}
+
+	return activity.Tasks, nil
+}
+
+// QualityGateStatus returns the status of the quality gate for the latest analysis of a project, such as OK or ERROR,
+// or NONE if the project doesn't have a quality gate.
+func (client *Client) QualityGateStatus(projectKey string) (string, error) {
+	var status struct {
+		ProjectStatus struct {
+			Status string `json:"status"`
+		} `json:"projectStatus"`
+	}
+	if err := client.do(http.MethodGet, "/api/qualitygates/project_status", url.Values{"projectKey": {projectKey}}, &status); err != nil {
+		return "", fmt.Errorf("unable to get quality gate status of project %s: %w", projectKey, err)
+	}
+
+	return status.ProjectStatus.Status, nil
+}
+
+// CreateProject creates a project without analyses, with a key and a name.
+func (client *Client) CreateProject(projectKey string, name string) error {
+	if err := client.do(http.MethodPost, "/api/projects/create", url.Values{"project": {projectKey}, "name": {name}}, nil); err != nil {
+		return fmt.Errorf("unable to create project %s: %w", projectKey, err)
+	}
+
+	return nil
+}
+
+// ProjectName returns the name of a project, or ErrNotFound if there is no project with that key.
+func (client *Client) ProjectName(projectKey string) (string, error) {
+	var component struct {
+		Component struct {
+			Name string `json:"name"`
+		} `json:"component"`
+	}
+	if err := client.do(http.MethodGet, "/api/components/show", url.Values{"component": {projectKey}}, &component); err != nil {
+		return "", fmt.Errorf("unable to get project %s: %w", projectKey, err)
+	}
+
+	return component.Component.Name, nil
+}
+
+// DeleteProject deletes a project and its analyses.
+func (client *Client) DeleteProject(projectKey string) error {
+	if err := client.do(http.MethodPost, "/api/projects/delete", url.Values{"project": {projectKey}}, nil); err != nil {
+		return fmt.Errorf("unable to delete project %s: %w", projectKey, err)
+	}
+
+	return nil
+}
+
+// do calls the Web API with params, in the query for GET requests and as a form otherwise, and decodes the JSON
+// response into out if it isn't nil. Responses that aren't a 2xx are returned as an error.
+func (client *Client) do(method string, apiPath string, params url.Values, out interface{}) error {
+	var request *http.Request
+	var err error
+	if method == http.MethodGet {
+		request, err = http.NewRequest(method, client.baseURL+apiPath+"?"+params.Encode(), nil) //nolint:noctx
+	} else {
+		request, err = http.NewRequest(method, client.baseURL+apiPath, strings.NewReader(params.Encode())) //nolint:noctx
+		if request != nil {
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	request.SetBasicAuth(client.username, client.password)
+	response, err := client.http.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to %s %s: %w", method, apiPath, err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response of %s %s: %w", method, apiPath, err)
+	}
+	if response.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s %s: %w", method, apiPath, ErrNotFound)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned HTTP %d: %s", method, apiPath, response.StatusCode, content)
+	}
+	if out != nil {
+		if err := json.Unmarshal(content, out); err != nil {
+			return fmt.Errorf("unable to parse response of %s %s: %w", method, apiPath, err)
+		}
+	}
+
+	return nil
+}
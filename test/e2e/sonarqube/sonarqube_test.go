@@ -0,0 +1,68 @@
+package sonarqube_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/sonarqube"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	t.Parallel()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/user_tokens/generate", func(writer http.ResponseWriter, request *http.Request) {
+		if username, password, _ := request.BasicAuth(); username != "admin" || password != "secret" || request.Method != http.MethodPost {
+			http.Error(writer, `{"errors":[{"msg":"Unauthorized"}]}`, http.StatusUnauthorized)
+
+			return
+		}
+		fmt.Fprintf(writer, `{"name":%q,"token":"squ_123"}`, request.PostFormValue("name"))
+	})
+	mux.HandleFunc("/api/ce/activity", func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Query().Get("component") != "e2e" {
+			http.NotFound(writer, request)
+
+			return
+		}
+		fmt.Fprint(writer, `{"tasks":[{"id":"AY1","type":"REPORT","status":"SUCCESS","componentKey":"e2e","analysisId":"AY2"}]}`)
+	})
+	projects := make(map[string]string)
+	mux.HandleFunc("/api/projects/create", func(writer http.ResponseWriter, request *http.Request) {
+		projects[request.PostFormValue("project")] = request.PostFormValue("name")
+		fmt.Fprintf(writer, `{"project":{"key":%q,"name":%q}}`, request.PostFormValue("project"), request.PostFormValue("name"))
+	})
+	mux.HandleFunc("/api/components/show", func(writer http.ResponseWriter, request *http.Request) {
+		name, ok := projects[request.URL.Query().Get("component")]
+		if !ok {
+			http.Error(writer, `{"errors":[{"msg":"Component key 'missing' not found"}]}`, http.StatusNotFound)
+
+			return
+		}
+		fmt.Fprintf(writer, `{"component":{"key":%q,"name":%q,"qualifier":"TRK"}}`, request.URL.Query().Get("component"), name)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := sonarqube.NewClient(server.Client(), server.URL, "admin", "secret")
+
+	token, err := client.GenerateToken("e2e")
+	require.NoError(t, err)
+	require.Equal(t, "squ_123", token)
+	_, err = sonarqube.NewClient(server.Client(), server.URL, token, "").GenerateToken("e2e")
+	require.ErrorContains(t, err, "HTTP 401")
+
+	tasks, err := client.Tasks("e2e")
+	require.NoError(t, err)
+	require.Equal(t, []sonarqube.Task{{ID: "AY1", Type: "REPORT", Status: sonarqube.TaskSuccess, ComponentKey: "e2e", AnalysisID: "AY2"}}, tasks)
+	_, err = client.Tasks("missing")
+	require.ErrorIs(t, err, sonarqube.ErrNotFound)
+
+	require.NoError(t, client.CreateProject("e2e-upgrade", "E2E Upgrade"))
+	name, err := client.ProjectName("e2e-upgrade")
+	require.NoError(t, err)
+	require.Equal(t, "E2E Upgrade", name)
+	_, err = client.ProjectName("missing")
+	require.ErrorIs(t, err, sonarqube.ErrNotFound)
+}
@@ -0,0 +1,263 @@
+// Package tlsinspect finds out which TLS protocol versions and cipher suites a server accepts, and which certificate it
+// presents, and checks them against a policy. It replaces grepping the output of sslscan.
+package tlsinspect
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DialFunc opens a connection to address, such as TestPlatform.DialRemote or net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network string, address string) (net.Conn, error)
+
+// handshakeTimeout is how long a single TLS handshake may take.
+const handshakeTimeout = 15 * time.Second
+
+// versions are the protocol versions that are checked, oldest first. crypto/tls can't speak SSLv3 or older, so a
+// server that rejects TLS 1.0 is assumed to reject those as well.
+var versions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// Report is what a server accepted and presented during the TLS handshakes made by Inspect.
+type Report struct {
+	// Hostname is the name that was sent with SNI and that the certificate is verified against
+	Hostname string
+	// Versions are the protocol versions the server accepted, oldest first
+	Versions []uint16
+	// CipherSuites are the cipher suites the server accepted, by protocol version. For TLS 1.3 it only holds the suite
+	// that was negotiated, since crypto/tls doesn't allow choosing TLS 1.3 suites.
+	CipherSuites map[uint16][]uint16
+	// Certificates is the chain the server presented, leaf first
+	Certificates []*x509.Certificate
+}
+
+// Inspect connects to address with dial and makes a TLS handshake for each protocol version, and for each cipher
+// suite of every accepted version before TLS 1.3, to find out what the server accepts. The hostname is sent with SNI.
+// Handshakes the server rejects with an alert are expected. Failing to connect, and handshakes that time out or end
+// without an alert are errors, since they say nothing about what the server accepts.
+func Inspect(ctx context.Context, dial DialFunc, address string, hostname string) (*Report, error) {
+	report := &Report{Hostname: hostname, CipherSuites: make(map[uint16][]uint16)}
+	for _, version := range versions {
+		state, err := handshake(ctx, dial, address, hostname, version, suitesFor(version))
+		if err != nil {
+			return nil, err
+		}
+		if state == nil {
+			continue
+		}
+		report.Versions = append(report.Versions, version)
+		report.Certificates = state.PeerCertificates
+		if version == tls.VersionTLS13 {
+			report.CipherSuites[version] = []uint16{state.CipherSuite}
+
+			continue
+		}
+		for _, suite := range suitesFor(version) {
+			state, err := handshake(ctx, dial, address, hostname, version, []uint16{suite})
+			if err != nil {
+				return nil, err
+			}
+			if state != nil {
+				report.CipherSuites[version] = append(report.CipherSuites[version], suite)
+			}
+		}
+	}
+	if len(report.Versions) == 0 {
+		return nil, fmt.Errorf("%s (%s) did not accept any TLS handshake", hostname, address)
+	}
+
+	return report, nil
+}
+
+// Policy is what a server is expected to accept and present.
+type Policy struct {
+	// MinVersion is the oldest protocol version the server may accept
+	MinVersion uint16
+	// AllowedCipherSuites are the cipher suites the server may accept
+	AllowedCipherSuites []uint16
+	// MaxCertificateAge is how long ago the leaf certificate may have been issued
+	MaxCertificateAge time.Duration
+	// MinRemainingValidity is how long the leaf certificate must still be valid for
+	MinRemainingValidity time.Duration
+	// Roots are the certificate authorities the chain must lead to. The system roots are used if it is nil.
+	Roots *x509.CertPool
+}
+
+// DefaultPolicy requires TLS 1.2 or newer with forward secret AEAD cipher suites only, and a certificate issued within
+// the maximum validity of a public certificate that is valid for at least another day.
+func DefaultPolicy() Policy {
+	return Policy{
+		MinVersion: tls.VersionTLS12,
+		AllowedCipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_AES_128_GCM_SHA256,
+			tls.TLS_AES_256_GCM_SHA384,
+			tls.TLS_CHACHA20_POLY1305_SHA256,
+		},
+		MaxCertificateAge:    398 * 24 * time.Hour, //nolint:gomnd
+		MinRemainingValidity: 24 * time.Hour,
+	}
+}
+
+// Check compares the report with the policy at the given time. Besides the policy, every name in the certificate must
+// be domain or one of its subdomains, and the certificate chain must be valid for the report's hostname. It returns an
+// error listing every violation, grouped so it reads as a diff between the policy and what the server does.
+func (policy Policy) Check(report *Report, domain string, now time.Time) error {
+	var protocols, suites, certificate []string
+	for _, version := range report.Versions {
+		if version < policy.MinVersion {
+			protocols = append(protocols, fmt.Sprintf("- %s is accepted, the policy minimum is %s", VersionName(version), VersionName(policy.MinVersion)))
+		}
+	}
+	allowed := make(map[uint16]bool)
+	for _, suite := range policy.AllowedCipherSuites {
+		allowed[suite] = true
+	}
+	for _, version := range report.Versions {
+		for _, suite := range report.CipherSuites[version] {
+			if !allowed[suite] {
+				suites = append(suites, fmt.Sprintf("- %s is accepted with %s, it isn't allowed", tls.CipherSuiteName(suite), VersionName(version)))
+			}
+		}
+	}
+	certificate = policy.checkCertificates(report, domain, now)
+
+	var sections []string
+	for _, section := range []struct {
+		title      string
+		violations []string
+	}{{"protocol versions", protocols}, {"cipher suites", suites}, {"certificate", certificate}} {
+		if len(section.violations) > 0 {
+			sections = append(sections, fmt.Sprintf("  %s:\n    %s", section.title, strings.Join(section.violations, "\n    ")))
+		}
+	}
+	if len(sections) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s does not meet the TLS policy:\n%s", report.Hostname, strings.Join(sections, "\n"))
+}
+
+// checkCertificates lists what is wrong with the certificate chain in the report.
+func (policy Policy) checkCertificates(report *Report, domain string, now time.Time) []string {
+	if len(report.Certificates) == 0 {
+		return []string{"- no certificate was presented"}
+	}
+	var violations []string
+	leaf := report.Certificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range report.Certificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       report.Hostname,
+		Intermediates: intermediates,
+		Roots:         policy.Roots,
+		CurrentTime:   now,
+	})
+	if err != nil {
+		violations = append(violations, fmt.Sprintf("- chain is not valid for %s: %v", report.Hostname, err))
+	}
+	for _, name := range leaf.DNSNames {
+		if name != domain && !strings.HasSuffix(name, "."+domain) {
+			violations = append(violations, fmt.Sprintf("- name %s is not in domain %s", name, domain))
+		}
+	}
+	if age := now.Sub(leaf.NotBefore); age > policy.MaxCertificateAge {
+		violations = append(violations, fmt.Sprintf("- issued %s, %s ago, the policy maximum age is %s", leaf.NotBefore.Format(time.RFC3339), days(age), days(policy.MaxCertificateAge)))
+	}
+	if remaining := leaf.NotAfter.Sub(now); remaining < policy.MinRemainingValidity {
+		violations = append(violations, fmt.Sprintf("- expires %s, %s from now, the policy minimum is %s", leaf.NotAfter.Format(time.RFC3339), days(remaining), days(policy.MinRemainingValidity)))
+	}
+
+	return violations
+}
+
+// days formats a duration as a whole number of days, such as "398 days".
+func days(duration time.Duration) string {
+	count := int(duration / (24 * time.Hour))
+	if count == 1 {
+		return "1 day"
+	}
+
+	return fmt.Sprintf("%d days", count)
+}
+
+// VersionName returns the name of a TLS protocol version, such as "TLS 1.2".
+func VersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
+
+// suitesFor returns every cipher suite crypto/tls knows, secure or not, that can be used with the protocol version.
+func suitesFor(version uint16) []uint16 {
+	var suites []uint16
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		for _, supported := range suite.SupportedVersions {
+			if supported == version {
+				suites = append(suites, suite.ID)
+			}
+		}
+	}
+	sort.Slice(suites, func(i, j int) bool { return suites[i] < suites[j] })
+
+	return suites
+}
+
+// handshake makes a TLS handshake offering only the given protocol version and cipher suites. It returns nil if the
+// server rejected the handshake with an alert, and an error if it couldn't be connected to or the handshake failed in
+// any other way, such as timing out.
+func handshake(ctx context.Context, dial DialFunc, address string, hostname string, version uint16, suites []uint16) (*tls.ConnectionState, error) {
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", address, err)
+	}
+	defer conn.Close()
+	client := tls.Client(conn, &tls.Config{
+		ServerName: hostname,
+		MinVersion: version,
+		MaxVersion: version,
+		// Only the suites for TLS 1.2 and older can be chosen, crypto/tls ignores this for TLS 1.3
+		CipherSuites: suites,
+		// The chain is verified separately by Policy.Check, so that an invalid certificate is reported with the rest
+		InsecureSkipVerify: true, //nolint:gosec
+	})
+	if err := client.HandshakeContext(ctx); err != nil {
+		// crypto/tls reports an alert sent by the server, such as handshake_failure or protocol_version, as a remote error
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "remote error" {
+			return nil, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("handshake with %s did not finish: %w", address, ctx.Err())
+		}
+
+		return nil, fmt.Errorf("handshake with %s failed without an alert from the server: %w", address, err)
+	}
+	state := client.ConnectionState()
+
+	return &state, nil
+}
@@ -0,0 +1,113 @@
+package tlsinspect_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/tlsinspect"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{
+		MinVersion: tls.VersionTLS11,
+		MaxVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+		},
+	}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	dialer := &net.Dialer{}
+	report, err := tlsinspect.Inspect(context.Background(), dialer.DialContext, server.Listener.Addr().String(), "gitlab.example.com")
+	require.NoError(t, err)
+	require.Equal(t, []uint16{tls.VersionTLS11, tls.VersionTLS12}, report.Versions)
+	require.Equal(t, []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA}, report.CipherSuites[tls.VersionTLS11])
+	require.Equal(t, []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, report.CipherSuites[tls.VersionTLS12])
+
+	policy := tlsinspect.DefaultPolicy()
+	policy.Roots = x509.NewCertPool()
+	policy.Roots.AddCert(server.Certificate())
+	// The certificate of httptest is valid from 1970 until 2084
+	now := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	err = policy.Check(report, "example.com", now)
+	require.EqualError(t, err, `gitlab.example.com does not meet the TLS policy:
+  protocol versions:
+    - TLS 1.1 is accepted, the policy minimum is TLS 1.2
+  cipher suites:
+    - TLS_RSA_WITH_AES_128_CBC_SHA is accepted with TLS 1.1, it isn't allowed
+    - TLS_RSA_WITH_AES_128_CBC_SHA is accepted with TLS 1.2, it isn't allowed
+  certificate:
+    - issued 1970-01-01T00:00:00Z, 19631 days ago, the policy maximum age is 398 days`)
+
+	report.Versions = []uint16{tls.VersionTLS12}
+	report.CipherSuites[tls.VersionTLS12] = []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	policy.MaxCertificateAge = 100 * 365 * 24 * time.Hour
+	require.NoError(t, policy.Check(report, "example.com", now))
+
+	err = policy.Check(report, "bigbang.dev", now)
+	require.ErrorContains(t, err, "- name example.com is not in domain bigbang.dev")
+}
+
+func TestInspectWithoutAlert(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		serve func(conn net.Conn)
+		err   string
+	}{
+		{
+			name: "timeout",
+			serve: func(conn net.Conn) {
+				// Never answer, and keep the connection open until the client gives up
+				_, _ = io.Copy(io.Discard, conn)
+			},
+			err: "did not finish: context deadline exceeded",
+		},
+		{
+			name:  "closed",
+			serve: func(conn net.Conn) {},
+			err:   "failed without an alert from the server",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer listener.Close()
+			go func() {
+				for {
+					conn, err := listener.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						defer conn.Close()
+						test.serve(conn)
+					}()
+				}
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			dialer := &net.Dialer{}
+			_, err = tlsinspect.Inspect(ctx, dialer.DialContext, listener.Addr().String(), "gitlab.example.com")
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Package tools provisions the CLI tools the e2e tests need on the test host at pinned, checksum-verified versions
+package tools
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LockFile is the path, relative to the root of the repo, of the checked-in file that pins the SHA-256 checksum of
+// every tool that gets installed on the test host.
+const LockFile = "test/e2e/tools.lock.json"
+
+// Tool is a single pinned binary that gets installed on the test host.
+type Tool struct {
+	// Name is the name of the tool as it appears in .tool-versions or, uppercased and suffixed with _VERSION, in the Makefile
+	Name string `json:"name"`
+	// Version is the pinned version of the tool
+	Version string `json:"version"`
+	// URL is the location the binary is downloaded from
+	URL string `json:"url"`
+	// SHA256 is the expected hex encoded SHA-256 checksum of the downloaded binary
+	SHA256 string `json:"sha256"`
+	// Binary is the name the tool is installed as in /usr/local/bin
+	Binary string `json:"binary"`
+}
+
+// Lock is the contents of the lock file.
+type Lock struct {
+	Tools []Tool `json:"tools"`
+}
+
+// definition describes where to download a tool from and what to call it once it is installed.
+type definition struct {
+	urlTemplate string
+	binary      string
+}
+
+// definitions are all the tools that get installed on the test host. The URL templates are passed the version of the
+// tool, always prefixed with a "v".
+var definitions = map[string]definition{
+	"k3d": {
+		urlTemplate: "https://github.com/k3d-io/k3d/releases/download/%[1]s/k3d-linux-amd64",
+		binary:      "k3d",
+	},
+	"kubectl": {
+		urlTemplate: "https://dl.k8s.io/release/%[1]s/bin/linux/amd64/kubectl",
+		binary:      "kubectl",
+	},
+	"zarf": {
+		urlTemplate: "https://github.com/defenseunicorns/zarf/releases/download/%[1]s/zarf_%[1]s_Linux_amd64",
+		binary:      "zarf",
+	},
+	"uds-cli": {
+		urlTemplate: "https://github.com/defenseunicorns/uds-cli/releases/download/%[1]s/uds-cli_%[1]s_Linux_amd64",
+		binary:      "uds",
+	},
+}
+
+// makefilePinPattern matches version pins in the Makefile such as "ZARF_VERSION := v0.31.4".
+var makefilePinPattern = regexp.MustCompile(`^([A-Z0-9_]+)_VERSION\s*:?=\s*(\S+)\s*$`)
+
+// ReadPins returns the pinned version of every tool listed in the .tool-versions file and the Makefile at the root of
+// the repo, keyed by tool name. Makefile pins are named by lowercasing them and replacing underscores with dashes, so
+// UDS_CLI_VERSION becomes "uds-cli". Versions are normalized to always start with a "v".
+func ReadPins(repoRoot string) (map[string]string, error) {
+	pins := make(map[string]string)
+
+	err := scanLines(filepath.Join(repoRoot, ".tool-versions"), func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && !strings.HasPrefix(fields[0], "#") { //nolint:gomnd
+			pins[fields[0]] = normalizeVersion(fields[1])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanLines(filepath.Join(repoRoot, "Makefile"), func(line string) {
+		matches := makefilePinPattern.FindStringSubmatch(line)
+		if matches != nil {
+			name := strings.ReplaceAll(strings.ToLower(matches[1]), "_", "-")
+			pins[name] = normalizeVersion(matches[2])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pins, nil
+}
+
+// ReadLock reads the lock file from the root of the repo.
+func ReadLock(repoRoot string) (*Lock, error) {
+	bytes, err := os.ReadFile(filepath.Join(repoRoot, LockFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read lock file: %w", err)
+	}
+	lock := new(Lock)
+	if err := json.Unmarshal(bytes, lock); err != nil {
+		return nil, fmt.Errorf("unable to parse lock file: %w", err)
+	}
+
+	return lock, nil
+}
+
+// WriteLock writes the lock file to the root of the repo.
+func WriteLock(repoRoot string, lock *Lock) error {
+	bytes, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal lock file: %w", err)
+	}
+	bytes = append(bytes, '\n')
+	if err := os.WriteFile(filepath.Join(repoRoot, LockFile), bytes, 0644); err != nil { //nolint:gosec,gomnd
+		return fmt.Errorf("unable to write lock file: %w", err)
+	}
+
+	return nil
+}
+
+// Resolve returns the tools to install on the test host. Every tool must be pinned in .tool-versions or the Makefile,
+// and must have a lock file entry for exactly that version and URL with a checksum. A lock file that has fallen behind
+// the pins is an error rather than something to work around, since the whole point is reproducible test runs.
+func Resolve(repoRoot string) ([]Tool, error) {
+	pins, err := ReadPins(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := ReadLock(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	locked := make(map[string]Tool, len(lock.Tools))
+	for _, tool := range lock.Tools {
+		locked[tool.Name] = tool
+	}
+
+	tools := make([]Tool, 0, len(definitions))
+	for _, name := range names() {
+		expected, err := pinnedTool(name, pins)
+		if err != nil {
+			return nil, err
+		}
+		tool, ok := locked[name]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("tool %v is not in %v, run `make test-tools-lock`", name, LockFile)
+		case tool.Version != expected.Version || tool.URL != expected.URL:
+			return nil, fmt.Errorf("tool %v is pinned to %v but %v has %v, run `make test-tools-lock`", name, expected.Version, LockFile, tool.Version)
+		case tool.SHA256 == "":
+			return nil, fmt.Errorf("tool %v has no checksum in %v, run `make test-tools-lock`", name, LockFile)
+		}
+		tools = append(tools, tool)
+	}
+
+	return tools, nil
+}
+
+// GenerateLock downloads every pinned tool and records its checksum.
+func GenerateLock(repoRoot string, client *http.Client) (*Lock, error) {
+	pins, err := ReadPins(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	lock := new(Lock)
+	for _, name := range names() {
+		tool, err := pinnedTool(name, pins)
+		if err != nil {
+			return nil, err
+		}
+		tool.SHA256, err = checksum(client, tool.URL)
+		if err != nil {
+			return nil, err
+		}
+		lock.Tools = append(lock.Tools, tool)
+	}
+
+	return lock, nil
+}
+
+// InstallCommand returns the shell command that downloads the tool on the test host, verifies its checksum, and
+// installs it into /usr/local/bin. It is meant to be run as root.
+func (tool Tool) InstallCommand() string {
+	download := fmt.Sprintf("/tmp/tools/%v-%v", tool.Binary, tool.Version)
+
+	return fmt.Sprintf(`mkdir -p /tmp/tools && curl -fsSL -o %[1]v %[2]v && echo "%[3]v  %[1]v" | sha256sum --check --strict - && install -o root -g root -m 0755 %[1]v /usr/local/bin/%[4]v`,
+		download, tool.URL, tool.SHA256, tool.Binary)
+}
+
+// CheckCommand returns a shell command that succeeds only if exactly this version of the tool is already installed,
+// which is the case on hosts launched from a golden image.
+func (tool Tool) CheckCommand() string {
+	return fmt.Sprintf(`echo "%v  /usr/local/bin/%v" | sha256sum --check --strict --status -`, tool.SHA256, tool.Binary)
+}
+
+// pinnedTool builds the lock file entry (minus the checksum) for the pinned version of a tool.
+func pinnedTool(name string, pins map[string]string) (Tool, error) {
+	version, ok := pins[name]
+	if !ok {
+		return Tool{}, fmt.Errorf("tool %v is not pinned in .tool-versions or the Makefile", name)
+	}
+	def := definitions[name]
+
+	return Tool{
+		Name:    name,
+		Version: version,
+		URL:     fmt.Sprintf(def.urlTemplate, version),
+		Binary:  def.binary,
+	}, nil
+}
+
+// checksum downloads the file at url and returns its hex encoded SHA-256 checksum.
+func checksum(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url) //nolint:noctx
+	if err != nil {
+		return "", fmt.Errorf("unable to download %v: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to download %v: %v", url, resp.Status)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", fmt.Errorf("unable to download %v: %w", url, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// names returns the names of all the tool definitions in a stable order.
+func names() []string {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// normalizeVersion makes sure the version starts with a "v", since that is how every tool tags its releases.
+func normalizeVersion(version string) string {
+	if strings.HasPrefix(version, "v") {
+		return version
+	}
+
+	return "v" + version
+}
+
+// scanLines calls fn with every line of the file at path.
+func scanLines(path string, fn func(line string)) error {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("unable to open %v: %w", path, err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fn(strings.TrimSpace(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read %v: %w", path, err)
+	}
+
+	return nil
+}
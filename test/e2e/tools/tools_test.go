@@ -0,0 +1,79 @@
+package tools_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/tools"
+	"github.com/stretchr/testify/require"
+)
+
+// writeRepo creates the files Resolve reads in a temporary repo root.
+func writeRepo(t *testing.T, toolVersions string, makefile string, lock *tools.Lock) string {
+	t.Helper()
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".tool-versions"), []byte(toolVersions), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "Makefile"), []byte(makefile), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(tools.LockFile)), 0750))
+	require.NoError(t, tools.WriteLock(root, lock))
+
+	return root
+}
+
+func lockedTool(name string, version string, url string, binary string) tools.Tool {
+	return tools.Tool{Name: name, Version: version, URL: url, SHA256: "abc123", Binary: binary}
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+	lock := &tools.Lock{Tools: []tools.Tool{
+		lockedTool("k3d", "v5.6.0", "https://github.com/k3d-io/k3d/releases/download/v5.6.0/k3d-linux-amd64", "k3d"),
+		lockedTool("kubectl", "v1.26.5", "https://dl.k8s.io/release/v1.26.5/bin/linux/amd64/kubectl", "kubectl"),
+		lockedTool("uds-cli", "v0.5.1", "https://github.com/defenseunicorns/uds-cli/releases/download/v0.5.1/uds-cli_v0.5.1_Linux_amd64", "uds"),
+		lockedTool("zarf", "v0.31.4", "https://github.com/defenseunicorns/zarf/releases/download/v0.31.4/zarf_v0.31.4_Linux_amd64", "zarf"),
+	}}
+	makefile := "UDS_CLI_VERSION := v0.5.1\n\nZARF_VERSION := v0.31.4\nK3D_VERSION := v5.6.0\n"
+
+	t.Run("matching pins", func(t *testing.T) {
+		t.Parallel()
+		root := writeRepo(t, "golang 1.21.0\nkubectl 1.26.5\n", makefile, lock)
+		resolved, err := tools.Resolve(root)
+		require.NoError(t, err)
+		require.Equal(t, lock.Tools, resolved)
+	})
+
+	t.Run("stale lock", func(t *testing.T) {
+		t.Parallel()
+		root := writeRepo(t, "kubectl 1.28.3\n", makefile, lock)
+		_, err := tools.Resolve(root)
+		require.ErrorContains(t, err, "kubectl is pinned to v1.28.3")
+	})
+
+	t.Run("missing pin", func(t *testing.T) {
+		t.Parallel()
+		root := writeRepo(t, "golang 1.21.0\n", makefile, lock)
+		_, err := tools.Resolve(root)
+		require.ErrorContains(t, err, "kubectl is not pinned")
+	})
+
+	t.Run("missing checksum", func(t *testing.T) {
+		t.Parallel()
+		unchecked := &tools.Lock{Tools: append([]tools.Tool{}, lock.Tools...)}
+		unchecked.Tools[0].SHA256 = ""
+		root := writeRepo(t, "kubectl 1.26.5\n", makefile, unchecked)
+		_, err := tools.Resolve(root)
+		require.ErrorContains(t, err, "k3d has no checksum")
+	})
+}
+
+// TestRepoLock checks the lock file of the repo itself, so that a lock that is out of date or missing checksums fails
+// here rather than in the setup of every e2e run.
+func TestRepoLock(t *testing.T) {
+	t.Parallel()
+	resolved, err := tools.Resolve("../../..")
+	require.NoError(t, err)
+	for _, tool := range resolved {
+		require.Regexp(t, `^[0-9a-f]{64}$`, tool.SHA256, "checksum of %s in %s", tool.Name, tools.LockFile)
+	}
+}
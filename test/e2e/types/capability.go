@@ -0,0 +1,39 @@
+package types
+
+import "fmt"
+
+// Capability describes what it means for one capability of the software factory to be up and running: its workloads
+// have rolled out, and if it is exposed through an ingress gateway, its health endpoint returns the expected status.
+type Capability struct {
+	// Name is used as the name of the subtest that checks the capability
+	Name string `yaml:"name"`
+	// Workloads are the Deployments and StatefulSets that must be ready
+	Workloads []Workload `yaml:"workloads"`
+	// Host is the hostname the capability is exposed on, without the domain. It is empty for capabilities that aren't
+	// exposed, like the GitLab Runner.
+	Host string `yaml:"host,omitempty"`
+	// HealthPath is the path that is requested to check the health of the capability, such as "/-/health"
+	HealthPath string `yaml:"healthPath,omitempty"`
+	// ExpectedStatus is the HTTP status code the health path returns once the capability is healthy, after redirects
+	ExpectedStatus int `yaml:"expectedStatus,omitempty"`
+}
+
+// Workload is a Kubernetes workload that belongs to a capability.
+type Workload struct {
+	// Kind is either "deployment" or "statefulset"
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+	// SkipRollout only waits for the workload to exist, for workloads managed by an operator that replaces them
+	SkipRollout bool `yaml:"skipRollout,omitempty"`
+}
+
+// String returns the workload the way kubectl refers to it, such as "deployment/gitlab-runner".
+func (workload Workload) String() string {
+	return fmt.Sprintf("%s/%s", workload.Kind, workload.Name)
+}
+
+// URL returns the URL of the health endpoint of the capability on the given domain.
+func (capability Capability) URL(domain string) string {
+	return fmt.Sprintf("https://%s.%s%s", capability.Host, domain, capability.HealthPath)
+}
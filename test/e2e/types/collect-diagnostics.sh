@@ -0,0 +1,38 @@
+#!/bin/bash
+# Collects diagnostics from the test host and cluster into /tmp/diagnostics.tar.gz. Nothing here is allowed to stop the
+# script, since the cluster is usually in a bad state when this runs and we want as much as we can get out of it.
+
+OUT=/tmp/diagnostics
+rm -rf "${OUT}" /tmp/diagnostics.tar.gz
+mkdir -p "${OUT}/describe" "${OUT}/logs" "${OUT}/deploy-logs"
+
+kubectl get nodes -o wide > "${OUT}/nodes.txt" 2>&1
+kubectl get all -A -o wide > "${OUT}/get-all.txt" 2>&1
+kubectl get events -A --sort-by=.lastTimestamp > "${OUT}/events.txt" 2>&1
+
+# Describe every pod that isn't ready, skipping completed job pods
+kubectl get pods -A --no-headers -o custom-columns='NAMESPACE:.metadata.namespace,NAME:.metadata.name,PHASE:.status.phase,READY:.status.containerStatuses[*].ready' 2>/dev/null |
+  while read -r namespace name phase ready; do
+    if [[ "${phase}" != "Succeeded" && ( "${ready}" == *false* || "${ready}" == "<none>" ) ]]; then
+      kubectl describe pod -n "${namespace}" "${name}" > "${OUT}/describe/${namespace}_${name}.txt" 2>&1
+    fi
+  done
+
+# Logs of every container, plus the previous instance of any container that restarted
+kubectl get pods -A -o jsonpath='{range .items[*]}{.metadata.namespace}{" "}{.metadata.name}{" "}{range .spec.initContainers[*]}{.name}{","}{end}{range .spec.containers[*]}{.name}{","}{end}{"\n"}{end}' 2>/dev/null |
+  while read -r namespace name containers; do
+    for container in ${containers//,/ }; do
+      log="${OUT}/logs/${namespace}_${name}_${container}"
+      kubectl logs -n "${namespace}" "${name}" -c "${container}" > "${log}.log" 2>&1
+      kubectl logs -n "${namespace}" "${name}" -c "${container}" --previous > "${log}.previous.log" 2>/dev/null || rm -f "${log}.previous.log"
+    done
+  done
+
+# Zarf and UDS write their logs to the temp dir
+cp /tmp/zarf-*.log /tmp/uds-*.log "${OUT}/deploy-logs/" 2>/dev/null
+
+kubectl get configmap -n kube-system coredns -o jsonpath='{.data.Corefile}' > "${OUT}/Corefile" 2>&1
+cp /etc/hosts "${OUT}/hosts"
+
+tar -czf /tmp/diagnostics.tar.gz -C /tmp diagnostics
+chmod 644 /tmp/diagnostics.tar.gz
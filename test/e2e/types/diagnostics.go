@@ -0,0 +1,104 @@
+package types
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	teststructure "github.com/gruntwork-io/terratest/modules/test-structure"
+)
+
+// collectDiagnosticsScript gathers everything we'd want to look at after a failed run into an archive on the test host.
+//
+//go:embed collect-diagnostics.sh
+var collectDiagnosticsScript []byte
+
+// CollectDiagnostics gathers the state of the cluster, the logs of every container, the Zarf and UDS deploy logs, the
+// CoreDNS Corefile and /etc/hosts from the test host, and downloads them as an archive into artifactsDir. It returns
+// the path of the archive.
+func (platform *TestPlatform) CollectDiagnostics(artifactsDir string) (string, error) {
+	localScript := filepath.Join(platform.TestFolder, "collect-diagnostics.sh")
+	if err := os.WriteFile(localScript, collectDiagnosticsScript, 0600); err != nil { //nolint:gomnd
+		return "", fmt.Errorf("unable to write diagnostics script: %w", err)
+	}
+	if err := platform.CopyFileOverScp(localScript, "/tmp/collect-diagnostics.sh", 0644); err != nil { //nolint:gomnd
+		return "", err
+	}
+	if output, err := platform.RunSSHCommandAsSudo(`bash /tmp/collect-diagnostics.sh`); err != nil {
+		return "", fmt.Errorf("unable to collect diagnostics: %w: %s", err, output)
+	}
+
+	if err := os.MkdirAll(artifactsDir, 0750); err != nil { //nolint:gomnd
+		return "", fmt.Errorf("unable to create artifacts folder: %w", err)
+	}
+	name := strings.ReplaceAll(platform.T.Name(), "/", "_")
+	archive := filepath.Join(artifactsDir, fmt.Sprintf("%s-diagnostics-%s.tar.gz", name, time.Now().Format("20060102-150405")))
+	if err := platform.CopyFileFromScp("/tmp/diagnostics.tar.gz", archive); err != nil {
+		return "", err
+	}
+
+	return archive, nil
+}
+
+// ArtifactsDir returns the folder that diagnostics and other artifacts of a run are saved in. It is ARTIFACTS_DIR,
+// defaulting to .cache/artifacts in the root of the repo.
+func (platform *TestPlatform) ArtifactsDir() string {
+	return ArtifactsDir(platform.RepoRoot)
+}
+
+// SaveArtifact writes content into ArtifactsDir, under a name prefixed with the name of the test, and returns its path.
+func (platform *TestPlatform) SaveArtifact(name string, content []byte) (string, error) {
+	return SaveArtifact(platform.T, platform.RepoRoot, name, content)
+}
+
+// ArtifactsDir returns the folder that artifacts are saved in for the repo at repoRoot, see the method of the same
+// name of TestPlatform.
+func ArtifactsDir(repoRoot string) string {
+	if artifactsDir, present := os.LookupEnv("ARTIFACTS_DIR"); present {
+		return artifactsDir
+	}
+
+	return filepath.Join(repoRoot, ".cache", "artifacts")
+}
+
+// SaveArtifact saves an artifact of a test that doesn't have a platform of its own, see the method of the same name of
+// TestPlatform.
+func SaveArtifact(t *testing.T, repoRoot string, name string, content []byte) (string, error) {
+	artifactsDir := ArtifactsDir(repoRoot)
+	if err := os.MkdirAll(artifactsDir, 0750); err != nil { //nolint:gomnd
+		return "", fmt.Errorf("unable to create artifacts folder: %w", err)
+	}
+	path := filepath.Join(artifactsDir, fmt.Sprintf("%s-%s", strings.ReplaceAll(t.Name(), "/", "_"), name))
+	if err := os.WriteFile(path, content, 0600); err != nil { //nolint:gomnd
+		return "", fmt.Errorf("unable to save artifact %s: %w", name, err)
+	}
+
+	return path, nil
+}
+
+// collectDiagnosticsBeforeTeardown collects diagnostics into ArtifactsDir if the test failed, or always if env var
+// COLLECT_DIAGNOSTICS is "always". Set COLLECT_DIAGNOSTICS to "never" to turn it off. A failure to collect diagnostics
+// is logged rather than failing the test, since it would only hide the real failure.
+func (platform *TestPlatform) collectDiagnosticsBeforeTeardown() {
+	mode := os.Getenv("COLLECT_DIAGNOSTICS")
+	if mode == "never" || (mode != "always" && !platform.T.Failed()) {
+		return
+	}
+	if !teststructure.IsTestDataPresent(platform.T, teststructure.FormatTestDataPath(platform.TestFolder, "TerraformOptions.json")) {
+		return
+	}
+	teststructure.RunTestStage(platform.T, "DIAGNOSTICS", func() {
+		archive, err := platform.CollectDiagnostics(platform.ArtifactsDir())
+		if err != nil {
+			logger.Default.Logf(platform.T, "error collecting diagnostics: %v", err)
+
+			return
+		}
+		logger.Default.Logf(platform.T, "Diagnostics saved to %s", archive)
+	})
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// These give the tests in package types_test access to the unexported parts of the teardown.
+var (
+	FinishPendingTeardownsAt = finishPendingTeardowns
+	WatchdogDelay            = watchdogDelay
+	WriteMarker              = writeMarker
+)
+
+const (
+	PendingTeardownDir = pendingTeardownDir
+	PlatformsDir       = platformsDir
+	HeartbeatTimeout   = heartbeatTimeout
+)
+
+// Heartbeat records that the test of the platform was still running at now.
+func (platform *TestPlatform) Heartbeat(now time.Time) error {
+	return platform.heartbeat(now)
+}
+
+// ClearTeardownPending stops the heartbeat and removes the marker and test folder of the platform.
+func (platform *TestPlatform) ClearTeardownPending() error {
+	return platform.clearTeardownPending()
+}
+
+// These give the tests access to the tunnels without an SSH connection, by dialling locally instead.
+var (
+	ForwardTo     = forward
+	NewHTTPClient = httpClient
+)
+
+// NewConnectProxy returns the proxy served by Proxy, making its connections with dial.
+func NewConnectProxy(dial func(ctx context.Context, network string, address string) (net.Conn, error), transport http.RoundTripper) http.Handler {
+	return &connectProxy{dial: dial, transport: transport}
+}
+
+// LogTunnel logs like the tunnels of the platform do.
+func (platform *TestPlatform) LogTunnel(format string, args ...interface{}) {
+	platform.logTunnel(format, args...)
+}
+
+// ListenLocal listens on a local port that CloseTunnels closes.
+func (platform *TestPlatform) ListenLocal() (net.Listener, error) {
+	return platform.listenLocal()
+}
+
+// CloseTunnels closes the listeners of the platform.
+func (platform *TestPlatform) CloseTunnels() {
+	platform.closeTunnels()
+}
+
+// RemoteAPIServer returns where the API server is reached on the test host, given the server of the kubeconfig.
+var RemoteAPIServer = remoteAPIServer
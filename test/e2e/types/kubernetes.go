@@ -0,0 +1,382 @@
+package types
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// Kubernetes holds the clients for a cluster, along with helpers that wait for its resources to become ready.
+type Kubernetes struct {
+	// Clientset is the typed client for the built-in resources
+	Clientset kubernetes.Interface
+	// Dynamic is the client for custom resources
+	Dynamic dynamic.Interface
+}
+
+// Kubernetes returns the clients for the k3d cluster on the server that is created using Terraform. The kubeconfig is
+// fetched from the server the first time, and the API server is reached through an SSH tunnel.
+func (platform *TestPlatform) Kubernetes() (*Kubernetes, error) {
+	platform.kubernetesMu.Lock()
+	defer platform.kubernetesMu.Unlock()
+	if platform.kubernetes != nil {
+		return platform.kubernetes, nil
+	}
+
+	kubeconfig, err := platform.fetchKubeconfig()
+	if err != nil {
+		return nil, err
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse kubeconfig: %w", err)
+	}
+	apiServer, err := remoteAPIServer(config.Host)
+	if err != nil {
+		return nil, err
+	}
+	config.Host = "https://" + apiServer
+	config.Dial = platform.DialRemote
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create kubernetes client: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create dynamic kubernetes client: %w", err)
+	}
+	platform.kubernetes = &Kubernetes{Clientset: clientset, Dynamic: dynamicClient}
+
+	return platform.kubernetes, nil
+}
+
+// remoteAPIServer returns where the API server of the k3d cluster listens on the server that is created using Terraform,
+// given the server of its kubeconfig, such as https://0.0.0.0:6443. k3d publishes the API server on the port in the
+// kubeconfig, which is random unless kubeAPI.hostPort is set, on every address of the server. Connecting to 127.0.0.1
+// rather than whatever host the kubeconfig says keeps the API server's certificate valid.
+func remoteAPIServer(server string) (string, error) {
+	parsed, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse the server of the kubeconfig: %w", err)
+	}
+	port := parsed.Port()
+	if parsed.Hostname() == "" || port == "" {
+		return "", fmt.Errorf("the server of the kubeconfig has no host and port: %q", server)
+	}
+
+	return net.JoinHostPort("127.0.0.1", port), nil
+}
+
+// fetchKubeconfig reads the kubeconfig of the k3d cluster from the server that is created using Terraform. It holds the
+// cluster admin's key, so it is read over its own SSH session rather than with RunSSHCommandAsSudo, which logs what the
+// command prints, and only what the command printed to stderr ends up in the error.
+func (platform *TestPlatform) fetchKubeconfig() ([]byte, error) {
+	client, err := platform.sshClient()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch kubeconfig: %w", err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		platform.closeSSHClient(client)
+
+		return nil, fmt.Errorf("unable to fetch kubeconfig: unable to open ssh session: %w", err)
+	}
+	defer session.Close()
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(`sudo kubectl config view --raw --minify`); err != nil {
+		return nil, fmt.Errorf("unable to fetch kubeconfig: %w: %s", err, stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// WaitForDeploymentExists waits for a Deployment to exist, regardless of whether it is ready.
+func (kube *Kubernetes) WaitForDeploymentExists(namespace string, name string, timeout time.Duration) error {
+	return kube.waitFor(fmt.Sprintf("deployment %s/%s", namespace, name), name, kube.deployments(namespace, name), &appsv1.Deployment{}, timeout, func(runtime.Object) string {
+		return ""
+	})
+}
+
+// WaitForDeploymentReady waits for every replica of the latest generation of a Deployment to be available. On timeout
+// the error says which replicas aren't, along with the Deployment's conditions.
+func (kube *Kubernetes) WaitForDeploymentReady(namespace string, name string, timeout time.Duration) error {
+	return kube.waitFor(fmt.Sprintf("deployment %s/%s", namespace, name), name, kube.deployments(namespace, name), &appsv1.Deployment{}, timeout, func(obj runtime.Object) string {
+		return deploymentUnmet(obj.(*appsv1.Deployment))
+	})
+}
+
+// WaitForStatefulSetExists waits for a StatefulSet to exist, regardless of whether it is ready.
+func (kube *Kubernetes) WaitForStatefulSetExists(namespace string, name string, timeout time.Duration) error {
+	return kube.waitFor(fmt.Sprintf("statefulset %s/%s", namespace, name), name, kube.statefulSets(namespace, name), &appsv1.StatefulSet{}, timeout, func(runtime.Object) string {
+		return ""
+	})
+}
+
+// WaitForStatefulSetReady waits for every replica of a StatefulSet to be ready and running the latest revision. On
+// timeout the error says which replicas aren't.
+func (kube *Kubernetes) WaitForStatefulSetReady(namespace string, name string, timeout time.Duration) error {
+	return kube.waitFor(fmt.Sprintf("statefulset %s/%s", namespace, name), name, kube.statefulSets(namespace, name), &appsv1.StatefulSet{}, timeout, func(obj runtime.Object) string {
+		return statefulSetUnmet(obj.(*appsv1.StatefulSet))
+	})
+}
+
+// WaitForCRCondition waits for the condition of the given type in status.conditions of a custom resource to have the
+// given status, such as "Ready" being "True". On timeout the error lists the conditions the resource had.
+func (kube *Kubernetes) WaitForCRCondition(gvr schema.GroupVersionResource, namespace string, name string, conditionType string, status string, timeout time.Duration) error {
+	return kube.waitFor(fmt.Sprintf("%s %s/%s", gvr.Resource, namespace, name), name, kube.customResources(gvr, namespace, name), &unstructured.Unstructured{}, timeout, func(obj runtime.Object) string {
+		return crConditionUnmet(obj.(*unstructured.Unstructured), conditionType, status)
+	})
+}
+
+// WaitForCRField waits for a string field of a custom resource to have the given value, for operators that report
+// their progress in a field rather than a condition, such as status.state of a Mattermost being "stable".
+func (kube *Kubernetes) WaitForCRField(gvr schema.GroupVersionResource, namespace string, name string, value string, timeout time.Duration, fieldPath ...string) error {
+	return kube.waitFor(fmt.Sprintf("%s %s/%s", gvr.Resource, namespace, name), name, kube.customResources(gvr, namespace, name), &unstructured.Unstructured{}, timeout, func(obj runtime.Object) string {
+		actual, found, _ := unstructured.NestedString(obj.(*unstructured.Unstructured).Object, fieldPath...)
+		switch {
+		case !found:
+			return fmt.Sprintf("%s is not set", strings.Join(fieldPath, "."))
+		case actual != value:
+			return fmt.Sprintf("%s is %q rather than %q", strings.Join(fieldPath, "."), actual, value)
+		default:
+			return ""
+		}
+	})
+}
+
+// WaitForWorkload waits for a workload of a capability to be ready, or only for it to exist if it skips the rollout.
+func (kube *Kubernetes) WaitForWorkload(workload Workload, timeout time.Duration) error {
+	switch {
+	case workload.Kind == "deployment" && workload.SkipRollout:
+		return kube.WaitForDeploymentExists(workload.Namespace, workload.Name, timeout)
+	case workload.Kind == "deployment":
+		return kube.WaitForDeploymentReady(workload.Namespace, workload.Name, timeout)
+	case workload.Kind == "statefulset" && workload.SkipRollout:
+		return kube.WaitForStatefulSetExists(workload.Namespace, workload.Name, timeout)
+	case workload.Kind == "statefulset":
+		return kube.WaitForStatefulSetReady(workload.Namespace, workload.Name, timeout)
+	default:
+		return fmt.Errorf("unknown kind of workload %s", workload)
+	}
+}
+
+// EnvValue returns the value of an environment variable of a container in namespace, reading it from its secret or
+// config map if it comes from one. It returns false if the container doesn't set the variable.
+func (kube *Kubernetes) EnvValue(namespace string, container corev1.Container, name string) (string, bool, error) {
+	for _, env := range container.Env {
+		if env.Name != name {
+			continue
+		}
+		switch {
+		case env.ValueFrom == nil:
+			return env.Value, true, nil
+		case env.ValueFrom.SecretKeyRef != nil:
+			ref := env.ValueFrom.SecretKeyRef
+			secret, err := kube.Clientset.CoreV1().Secrets(namespace).Get(context.Background(), ref.Name, metav1.GetOptions{})
+			if err != nil {
+				return "", false, fmt.Errorf("unable to get secret %s/%s for %s: %w", namespace, ref.Name, name, err)
+			}
+
+			return string(secret.Data[ref.Key]), true, nil
+		case env.ValueFrom.ConfigMapKeyRef != nil:
+			ref := env.ValueFrom.ConfigMapKeyRef
+			configMap, err := kube.Clientset.CoreV1().ConfigMaps(namespace).Get(context.Background(), ref.Name, metav1.GetOptions{})
+			if err != nil {
+				return "", false, fmt.Errorf("unable to get config map %s/%s for %s: %w", namespace, ref.Name, name, err)
+			}
+
+			return configMap.Data[ref.Key], true, nil
+		default:
+			return "", false, fmt.Errorf("%s of container %s comes from a field, which isn't supported", name, container.Name)
+		}
+	}
+
+	return "", false, nil
+}
+
+// deployments returns a ListWatch for the Deployment with the given name.
+func (kube *Kubernetes) deployments(namespace string, name string) cache.ListerWatcher {
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+
+			return kube.Clientset.AppsV1().Deployments(namespace).List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+
+			return kube.Clientset.AppsV1().Deployments(namespace).Watch(context.Background(), options)
+		},
+	}
+}
+
+// statefulSets returns a ListWatch for the StatefulSet with the given name.
+func (kube *Kubernetes) statefulSets(namespace string, name string) cache.ListerWatcher {
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+
+			return kube.Clientset.AppsV1().StatefulSets(namespace).List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+
+			return kube.Clientset.AppsV1().StatefulSets(namespace).Watch(context.Background(), options)
+		},
+	}
+}
+
+// customResources returns a ListWatch for the custom resource with the given name.
+func (kube *Kubernetes) customResources(gvr schema.GroupVersionResource, namespace string, name string) cache.ListerWatcher {
+	resource := kube.Dynamic.Resource(gvr).Namespace(namespace)
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+
+			return resource.List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+
+			return resource.Watch(context.Background(), options)
+		},
+	}
+}
+
+// waitFor watches the object with the given name listed by listWatch until unmet returns an empty string for it. On
+// timeout the error says what unmet last returned, or that the object never existed.
+func (kube *Kubernetes) waitFor(description string, name string, listWatch cache.ListerWatcher, objType runtime.Object, timeout time.Duration, unmet func(runtime.Object) string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	lastUnmet := "it does not exist"
+	_, err := watchtools.UntilWithSync(ctx, listWatch, objType, nil, func(event watch.Event) (bool, error) {
+		if object, err := meta.Accessor(event.Object); err != nil || object.GetName() != name {
+			return false, nil
+		}
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			lastUnmet = unmet(event.Object)
+
+			return lastUnmet == "", nil
+		case watch.Deleted:
+			lastUnmet = "it was deleted"
+		case watch.Bookmark, watch.Error:
+		}
+
+		return false, nil
+	})
+	if errors.Is(err, watchtools.ErrWatchClosed) || ctx.Err() != nil {
+		return fmt.Errorf("%s is not ready after %v: %s", description, timeout, lastUnmet)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to watch %s: %w", description, err)
+	}
+
+	return nil
+}
+
+// deploymentUnmet describes why a Deployment isn't ready yet, or returns an empty string if it is.
+func deploymentUnmet(deployment *appsv1.Deployment) string {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	var unmet []string
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		unmet = append(unmet, fmt.Sprintf("generation %d not observed yet", deployment.Generation))
+	}
+	if deployment.Status.UpdatedReplicas < replicas {
+		unmet = append(unmet, fmt.Sprintf("%d of %d replicas updated", deployment.Status.UpdatedReplicas, replicas))
+	}
+	if deployment.Status.Replicas > deployment.Status.UpdatedReplicas {
+		unmet = append(unmet, fmt.Sprintf("%d old replicas pending termination", deployment.Status.Replicas-deployment.Status.UpdatedReplicas))
+	}
+	if deployment.Status.AvailableReplicas < replicas {
+		unmet = append(unmet, fmt.Sprintf("%d of %d replicas available", deployment.Status.AvailableReplicas, replicas))
+	}
+	if len(unmet) == 0 {
+		return ""
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Status != "True" {
+			unmet = append(unmet, fmt.Sprintf("condition %s=%s (%s): %s", condition.Type, condition.Status, condition.Reason, condition.Message))
+		}
+	}
+
+	return strings.Join(unmet, ", ")
+}
+
+// statefulSetUnmet describes why a StatefulSet isn't ready yet, or returns an empty string if it is.
+func statefulSetUnmet(statefulSet *appsv1.StatefulSet) string {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	var unmet []string
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation {
+		unmet = append(unmet, fmt.Sprintf("generation %d not observed yet", statefulSet.Generation))
+	}
+	if statefulSet.Status.ReadyReplicas < replicas {
+		unmet = append(unmet, fmt.Sprintf("%d of %d replicas ready", statefulSet.Status.ReadyReplicas, replicas))
+	}
+	if statefulSet.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType {
+		if statefulSet.Status.UpdatedReplicas < replicas {
+			unmet = append(unmet, fmt.Sprintf("%d of %d replicas updated", statefulSet.Status.UpdatedReplicas, replicas))
+		}
+		if statefulSet.Status.UpdateRevision != "" && statefulSet.Status.CurrentRevision != statefulSet.Status.UpdateRevision {
+			unmet = append(unmet, fmt.Sprintf("revision %s not rolled out yet, replicas still on %s", statefulSet.Status.UpdateRevision, statefulSet.Status.CurrentRevision))
+		}
+	}
+
+	return strings.Join(unmet, ", ")
+}
+
+// crConditionUnmet describes why a custom resource doesn't have the condition yet, or returns an empty string if it
+// does.
+func crConditionUnmet(obj *unstructured.Unstructured, conditionType string, status string) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	var found []string
+	for _, item := range conditions {
+		condition, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == conditionType && condition["status"] == status {
+			return ""
+		}
+		found = append(found, fmt.Sprintf("%v=%v (%v): %v", condition["type"], condition["status"], condition["reason"], condition["message"]))
+	}
+	if len(found) == 0 {
+		return fmt.Sprintf("condition %s=%s not met, it has no conditions", conditionType, status)
+	}
+
+	return fmt.Sprintf("condition %s=%s not met, it has %s", conditionType, status, strings.Join(found, ", "))
+}
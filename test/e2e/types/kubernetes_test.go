@@ -0,0 +1,172 @@
+package types_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestWaitForDeploymentReady(t *testing.T) {
+	t.Parallel()
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "gitlab-webservice-default", Namespace: "gitlab", Generation: 1},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			Replicas:           2,
+			UpdatedReplicas:    2,
+			AvailableReplicas:  1,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: "False", Reason: "MinimumReplicasUnavailable", Message: "Deployment does not have minimum availability."},
+			},
+		},
+	}
+	clientset := fake.NewSimpleClientset(deployment)
+	kube := &types.Kubernetes{Clientset: clientset}
+
+	err := kube.WaitForDeploymentReady("gitlab", "gitlab-webservice-default", 100*time.Millisecond)
+	require.EqualError(t, err, "deployment gitlab/gitlab-webservice-default is not ready after 100ms: 1 of 2 replicas available, condition Available=False (MinimumReplicasUnavailable): Deployment does not have minimum availability.")
+
+	err = kube.WaitForDeploymentReady("gitlab", "gitlab-runner", 100*time.Millisecond)
+	require.EqualError(t, err, "deployment gitlab/gitlab-runner is not ready after 100ms: it does not exist")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		deployment.Status.AvailableReplicas = 2
+		deployment.Status.Conditions = nil
+		_, _ = clientset.AppsV1().Deployments("gitlab").UpdateStatus(context.Background(), deployment, metav1.UpdateOptions{})
+	}()
+	require.NoError(t, kube.WaitForDeploymentReady("gitlab", "gitlab-webservice-default", 5*time.Second))
+}
+
+func TestWaitForStatefulSetReady(t *testing.T) {
+	t.Parallel()
+	replicas := int32(1)
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "jira", Namespace: "jira", Generation: 2},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status: appsv1.StatefulSetStatus{
+			ObservedGeneration: 2,
+			ReadyReplicas:      1,
+			UpdatedReplicas:    0,
+			CurrentRevision:    "jira-1",
+			UpdateRevision:     "jira-2",
+		},
+	}
+	kube := &types.Kubernetes{Clientset: fake.NewSimpleClientset(statefulSet)}
+
+	err := kube.WaitForStatefulSetReady("jira", "jira", 100*time.Millisecond)
+	require.EqualError(t, err, "statefulset jira/jira is not ready after 100ms: 0 of 1 replicas updated, revision jira-2 not rolled out yet, replicas still on jira-1")
+	require.NoError(t, kube.WaitForStatefulSetExists("jira", "jira", 5*time.Second))
+}
+
+func TestWaitForCRCondition(t *testing.T) {
+	t.Parallel()
+	gvr := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+	widget := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "main", "namespace": "default"},
+		"status": map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "False", "reason": "Reconciling", "message": "still going"},
+		}},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "WidgetList"}, widget)
+	kube := &types.Kubernetes{Dynamic: dynamicClient}
+
+	err := kube.WaitForCRCondition(gvr, "default", "main", "Ready", "True", 100*time.Millisecond)
+	require.EqualError(t, err, "widgets default/main is not ready after 100ms: condition Ready=True not met, it has Ready=False (Reconciling): still going")
+	require.NoError(t, kube.WaitForCRCondition(gvr, "default", "main", "Ready", "False", 5*time.Second))
+}
+
+func TestWaitForCRField(t *testing.T) {
+	t.Parallel()
+	gvr := schema.GroupVersionResource{Group: "installation.mattermost.com", Version: "v1beta1", Resource: "mattermosts"}
+	mattermost := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "installation.mattermost.com/v1beta1",
+		"kind":       "Mattermost",
+		"metadata":   map[string]interface{}{"name": "mattermost", "namespace": "mattermost"},
+		"status":     map[string]interface{}{"state": "reconciling"},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "MattermostList"}, mattermost)
+	kube := &types.Kubernetes{Dynamic: dynamicClient}
+
+	err := kube.WaitForCRField(gvr, "mattermost", "mattermost", "stable", 100*time.Millisecond, "status", "state")
+	require.EqualError(t, err, `mattermosts mattermost/mattermost is not ready after 100ms: status.state is "reconciling" rather than "stable"`)
+	err = kube.WaitForCRField(gvr, "mattermost", "mattermost", "stable", 100*time.Millisecond, "status", "version")
+	require.EqualError(t, err, "mattermosts mattermost/mattermost is not ready after 100ms: status.version is not set")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = unstructured.SetNestedField(mattermost.Object, "stable", "status", "state")
+		_, _ = dynamicClient.Resource(gvr).Namespace("mattermost").UpdateStatus(context.Background(), mattermost, metav1.UpdateOptions{})
+	}()
+	require.NoError(t, kube.WaitForCRField(gvr, "mattermost", "mattermost", "stable", 5*time.Second, "status", "state"))
+}
+
+func TestEnvValue(t *testing.T) {
+	t.Parallel()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "jira-db", Namespace: "jira"},
+		Data:       map[string][]byte{"username": []byte("jira")},
+	}
+	kube := &types.Kubernetes{Clientset: fake.NewSimpleClientset(secret)}
+	container := corev1.Container{Name: "jira", Env: []corev1.EnvVar{
+		{Name: "ATL_JDBC_URL", Value: "jdbc:postgresql://jira-postgres:5432/jiradb"},
+		{Name: "ATL_JDBC_USER", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "jira-db"}, Key: "username"}}},
+	}}
+
+	value, ok, err := kube.EnvValue("jira", container, "ATL_JDBC_URL")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "jdbc:postgresql://jira-postgres:5432/jiradb", value)
+	value, ok, err = kube.EnvValue("jira", container, "ATL_JDBC_USER")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "jira", value)
+	_, ok, err = kube.EnvValue("jira", container, "ATL_DB_TYPE")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestRemoteAPIServer(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		server   string
+		expected string
+		err      string
+	}{
+		{server: "https://0.0.0.0:6443", expected: "127.0.0.1:6443"},
+		{server: "https://0.0.0.0:38457", expected: "127.0.0.1:38457"},
+		{server: "https://host.k3d.internal:45621", expected: "127.0.0.1:45621"},
+		{server: "https://[::]:6443", expected: "127.0.0.1:6443"},
+		{server: "https://0.0.0.0", err: "has no host and port"},
+		{server: "0.0.0.0:6443", err: "unable to parse"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.server, func(t *testing.T) {
+			t.Parallel()
+			apiServer, err := types.RemoteAPIServer(test.server)
+			if test.err != "" {
+				require.ErrorContains(t, err, test.err)
+
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, apiServer)
+		})
+	}
+}
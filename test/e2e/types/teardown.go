@@ -0,0 +1,376 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	teststructure "github.com/gruntwork-io/terratest/modules/test-structure"
+)
+
+const (
+	// pendingTeardownDir is where teardown pending markers are kept, relative to the root of the repo.
+	pendingTeardownDir = ".cache/teardown-pending"
+	// platformsDir is where the Terraform folder of every platform is copied to, relative to the root of the repo. It is
+	// under .cache like the markers, so that the Terraform state outlives the container `make test` runs in.
+	platformsDir = ".cache/platforms"
+	// defaultTeardownMargin is how long before the `go test -timeout` deadline the watchdog tears down the
+	// infrastructure. Override it with env var TEARDOWN_MARGIN.
+	defaultTeardownMargin = 15 * time.Minute
+	// heartbeatInterval is how often a running test updates the heartbeat of its teardown pending markers
+	heartbeatInterval = time.Minute
+	// heartbeatTimeout is how old the heartbeat of a marker has to be for the test that wrote it to be considered dead
+	heartbeatTimeout = 5 * time.Minute
+)
+
+// PendingTeardown is the marker persisted while a platform has infrastructure in AWS that hasn't been torn down yet.
+// If the test process dies before Teardown finishes, the marker lets a later run or the reaper finish the job.
+type PendingTeardown struct {
+	// Name is the name of the EC2 Key Pair, which is also the name every other resource of the platform is based on
+	Name string `json:"name"`
+	// Region is the AWS region the infrastructure lives in
+	Region string `json:"region"`
+	// TestFolder holds the Terraform state and test data of the platform, relative to the root of the repo
+	TestFolder string `json:"testFolder"`
+	// CreatedAt is when the infrastructure started being created
+	CreatedAt time.Time `json:"createdAt"`
+	// Heartbeat is the last time the test that created the infrastructure was known to be running. Unlike a process
+	// ID it means the same thing in every container and on every machine that shares the .cache folder.
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+var (
+	// activePlatforms are the platforms that the signal handler and watchdog tear down
+	activePlatforms   = make(map[*TestPlatform]struct{})
+	activePlatformsMu sync.Mutex
+	// interruptHandlerOnce makes sure only one signal handler and watchdog are started per test binary
+	interruptHandlerOnce sync.Once
+)
+
+// MarkTeardownPending persists a marker saying the platform is about to create infrastructure that needs to be torn
+// down. It must be called after the EC2 Key Pair has been saved to the test data. The heartbeat of the marker is kept
+// up to date until the platform is torn down. Nothing is persisted if SKIP_TEARDOWN is set, since then the
+// infrastructure is being kept around on purpose.
+func (platform *TestPlatform) MarkTeardownPending() error {
+	if os.Getenv("SKIP_TEARDOWN") != "" {
+		return nil
+	}
+	keyPair := new(aws.Ec2Keypair)
+	if _, err := loadTestData(teststructure.FormatTestDataPath(platform.TestFolder, "Ec2KeyPair.json"), keyPair); err != nil {
+		return err
+	}
+	testFolder := platform.TestFolder
+	if relative, err := filepath.Rel(platform.RepoRoot, platform.TestFolder); err == nil {
+		testFolder = relative
+	}
+	now := time.Now()
+	marker := &PendingTeardown{
+		Name:       keyPair.Name,
+		Region:     keyPair.Region,
+		TestFolder: testFolder,
+		CreatedAt:  now,
+		Heartbeat:  now,
+	}
+	if err := os.MkdirAll(filepath.Join(platform.RepoRoot, pendingTeardownDir), 0750); err != nil { //nolint:gomnd
+		return fmt.Errorf("unable to create teardown pending folder: %w", err)
+	}
+
+	platform.markerMu.Lock()
+	defer platform.markerMu.Unlock()
+	platform.marker = marker
+	platform.markerPath = filepath.Join(platform.RepoRoot, pendingTeardownDir, marker.Name+".json")
+	if err := writeMarker(platform.markerPath, marker); err != nil {
+		return err
+	}
+	stop := make(chan struct{})
+	platform.stopHeartbeat = stop
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				if err := platform.heartbeat(now); err != nil {
+					fmt.Printf("Unable to update the heartbeat of %s: %v\n", platform.markerPath, err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// FinishPendingTeardowns tears down the infrastructure of every platform whose test process died before its Teardown
+// finished, which is every platform whose marker has a heartbeat older than heartbeatTimeout. If the Terraform state of
+// a platform is gone, only the EC2 Key Pair can be deleted, and the rest has to be found with `make test-reap`.
+func FinishPendingTeardowns(t *testing.T, repoRoot string) {
+	t.Helper()
+	if teststructure.SkipStageEnvVarSet() {
+		logger.Default.Logf(t, "A SKIP_XXX environment variable is set, so not finishing pending teardowns of infrastructure that may be reused")
+
+		return
+	}
+	logf := func(format string, args ...interface{}) {
+		logger.Default.Logf(t, format, args...)
+	}
+	errs := finishPendingTeardowns(repoRoot, time.Now(), logf, func(marker *PendingTeardown, testFolder string) error {
+		present, err := destroy(t, testFolder)
+		if err == nil && !present {
+			logf("Terraform state for %s is gone, deleting its EC2 Key Pair. Run `make test-reap` to remove anything else that is left.", marker.Name)
+			err = aws.DeleteEC2KeyPairE(t, &aws.Ec2Keypair{Name: marker.Name, Region: marker.Region})
+		}
+
+		return err
+	})
+	for _, err := range errs {
+		t.Error(err)
+	}
+}
+
+// finishPendingTeardowns calls finish with every marker in repoRoot whose heartbeat is older than heartbeatTimeout at
+// now, along with the absolute path of its test folder, and removes the marker and the test folder if that worked.
+// Markers of tests that are still running are skipped. It returns what went wrong.
+func finishPendingTeardowns(repoRoot string, now time.Time, logf func(format string, args ...interface{}), finish func(marker *PendingTeardown, testFolder string) error) []error {
+	markerPaths, err := filepath.Glob(filepath.Join(repoRoot, pendingTeardownDir, "*.json"))
+	if err != nil {
+		return []error{fmt.Errorf("unable to list teardown pending markers: %w", err)}
+	}
+	var errs []error
+	for _, markerPath := range markerPaths {
+		marker := new(PendingTeardown)
+		if _, err := loadTestData(markerPath, marker); err != nil {
+			errs = append(errs, fmt.Errorf("unable to read teardown pending marker %s: %w", markerPath, err))
+
+			continue
+		}
+		if age := now.Sub(marker.Heartbeat); age < heartbeatTimeout {
+			logf("Skipping pending teardown of %s, the test that created it was running %v ago", marker.Name, age.Round(time.Second))
+
+			continue
+		}
+		logf("Finishing pending teardown of %s created at %s", marker.Name, marker.CreatedAt.Format(time.RFC3339))
+		testFolder := marker.TestFolder
+		if !filepath.IsAbs(testFolder) {
+			testFolder = filepath.Join(repoRoot, testFolder)
+		}
+		if err := finish(marker, testFolder); err != nil {
+			errs = append(errs, fmt.Errorf("unable to finish pending teardown of %s: %w", marker.Name, err))
+
+			continue
+		}
+		if err := os.Remove(markerPath); err != nil {
+			errs = append(errs, fmt.Errorf("unable to remove teardown pending marker %s: %w", markerPath, err))
+		}
+		if err := removePlatformFolder(repoRoot, testFolder); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// heartbeat records in the marker of the platform that its test was still running at now. It does nothing once the
+// platform has been torn down.
+func (platform *TestPlatform) heartbeat(now time.Time) error {
+	platform.markerMu.Lock()
+	defer platform.markerMu.Unlock()
+	if platform.marker == nil {
+		return nil
+	}
+	platform.marker.Heartbeat = now
+
+	return writeMarker(platform.markerPath, platform.marker)
+}
+
+// clearTeardownPending stops the heartbeat and removes the marker and the test folder of the platform, once its
+// infrastructure is gone.
+func (platform *TestPlatform) clearTeardownPending() error {
+	platform.markerMu.Lock()
+	defer platform.markerMu.Unlock()
+	if platform.stopHeartbeat != nil {
+		close(platform.stopHeartbeat)
+		platform.stopHeartbeat = nil
+	}
+	platform.marker = nil
+	if platform.markerPath == "" {
+		return nil
+	}
+	if err := os.Remove(platform.markerPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to remove teardown pending marker: %w", err)
+	}
+
+	return removePlatformFolder(platform.RepoRoot, platform.TestFolder)
+}
+
+// writeMarker writes a teardown pending marker to path.
+func writeMarker(path string, marker *PendingTeardown) error {
+	bytes, err := json.Marshal(marker)
+	if err != nil {
+		return fmt.Errorf("unable to marshal teardown pending marker: %w", err)
+	}
+	if err := os.WriteFile(path, bytes, 0600); err != nil { //nolint:gomnd
+		return fmt.Errorf("unable to write teardown pending marker: %w", err)
+	}
+
+	return nil
+}
+
+// removePlatformFolder removes the copy of the Terraform folder that testFolder is in, if it is a copy in platformsDir.
+// Test folders anywhere else, like the original folder that is used when a SKIP_XXX environment variable is set, are
+// left alone.
+func removePlatformFolder(repoRoot string, testFolder string) error {
+	relative, err := filepath.Rel(filepath.Join(repoRoot, platformsDir), testFolder)
+	if err != nil || relative == "." || strings.HasPrefix(relative, "..") {
+		return nil
+	}
+	copied := filepath.Join(repoRoot, platformsDir, strings.Split(filepath.ToSlash(relative), "/")[0])
+	if err := os.RemoveAll(copied); err != nil {
+		return fmt.Errorf("unable to remove test folder %s: %w", copied, err)
+	}
+
+	return nil
+}
+
+// teardown destroys the platform's infrastructure the first time it is called, and removes its teardown pending marker
+// and test folder if that worked. It is safe to call from any goroutine.
+func (platform *TestPlatform) teardown() error {
+	var err error
+	platform.teardownOnce.Do(func() {
+		if _, err = destroy(platform.T, platform.TestFolder); err != nil {
+			return
+		}
+		err = platform.clearTeardownPending()
+	})
+
+	return err
+}
+
+// destroy runs terraform destroy and deletes the EC2 Key Pair using the test data in testFolder. It returns false if
+// there was no test data, meaning there was nothing it could destroy. It only uses the non-fatal variants of the
+// Terratest functions so that it can run outside of the test goroutine.
+func destroy(t *testing.T, testFolder string) (bool, error) {
+	terraformOptions := new(terraform.Options)
+	present, err := loadTestData(teststructure.FormatTestDataPath(testFolder, "TerraformOptions.json"), terraformOptions)
+	if err != nil || !present {
+		return present, err
+	}
+	if _, err := terraform.DestroyE(t, terraformOptions); err != nil {
+		return true, fmt.Errorf("unable to destroy terraform infrastructure: %w", err)
+	}
+	keyPair := new(aws.Ec2Keypair)
+	present, err = loadTestData(teststructure.FormatTestDataPath(testFolder, "Ec2KeyPair.json"), keyPair)
+	if err != nil || !present {
+		return true, err
+	}
+	if err := aws.DeleteEC2KeyPairE(t, keyPair); err != nil {
+		return true, fmt.Errorf("unable to delete ec2 key pair: %w", err)
+	}
+
+	return true, nil
+}
+
+// registerPlatform makes sure the platform gets torn down if the test binary is interrupted or is about to time out.
+func registerPlatform(platform *TestPlatform) {
+	activePlatformsMu.Lock()
+	activePlatforms[platform] = struct{}{}
+	activePlatformsMu.Unlock()
+
+	interruptHandlerOnce.Do(func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		var timeout <-chan time.Time
+		if deadline, ok := platform.T.Deadline(); ok {
+			margin := defaultTeardownMargin
+			if val, present := os.LookupEnv("TEARDOWN_MARGIN"); present {
+				if parsed, err := time.ParseDuration(val); err == nil {
+					margin = parsed
+				}
+			}
+			if delay, ok := watchdogDelay(time.Until(deadline), margin); ok {
+				timeout = time.After(delay)
+			}
+		}
+		go func() {
+			select {
+			case sig := <-signals:
+				teardownActivePlatformsAndExit(fmt.Sprintf("received %v", sig))
+			case <-timeout:
+				teardownActivePlatformsAndExit("the test timeout is about to be reached")
+			}
+		}()
+	})
+}
+
+// watchdogDelay returns how long the watchdog waits before it tears the platforms down, given how long is left until the
+// test timeout. A margin that takes up more than half of the time that is left is cut down to a quarter of it, so that
+// a short -timeout doesn't tear the platforms down as soon as the test starts. It returns false if no time is left.
+func watchdogDelay(remaining time.Duration, margin time.Duration) (time.Duration, bool) {
+	if remaining <= 0 {
+		return 0, false
+	}
+	if margin > remaining/2 { //nolint:gomnd
+		margin = remaining / 4 //nolint:gomnd
+	}
+
+	return remaining - margin, true
+}
+
+// unregisterPlatform stops the signal handler and watchdog from tearing down the platform.
+func unregisterPlatform(platform *TestPlatform) {
+	activePlatformsMu.Lock()
+	delete(activePlatforms, platform)
+	activePlatformsMu.Unlock()
+}
+
+// teardownActivePlatformsAndExit tears down every active platform in parallel and then exits the test binary. Platforms
+// that can't be torn down keep their teardown pending marker.
+func teardownActivePlatformsAndExit(reason string) {
+	fmt.Printf("Tearing down all test platforms because %s\n", reason)
+	if os.Getenv("SKIP_TEARDOWN") != "" {
+		fmt.Println("SKIP_TEARDOWN is set, leaving the infrastructure in place")
+		os.Exit(1)
+	}
+	activePlatformsMu.Lock()
+	var wg sync.WaitGroup
+	for platform := range activePlatforms {
+		wg.Add(1)
+		go func(platform *TestPlatform) {
+			defer wg.Done()
+			if err := platform.teardown(); err != nil {
+				fmt.Printf("Error tearing down %s, it is still marked as teardown pending: %v\n", platform.TestFolder, err)
+			}
+		}(platform)
+	}
+	wg.Wait()
+	activePlatformsMu.Unlock()
+	os.Exit(1)
+}
+
+// loadTestData reads JSON test data from path into value. It returns false if there is no test data at path.
+func loadTestData(path string, value interface{}) (bool, error) {
+	bytes, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to read test data %s: %w", path, err)
+	}
+	if err := json.Unmarshal(bytes, value); err != nil {
+		return false, fmt.Errorf("unable to parse test data %s: %w", path, err)
+	}
+
+	return true, nil
+}
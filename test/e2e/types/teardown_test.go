@@ -0,0 +1,140 @@
+package types_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdogDelay(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		remaining time.Duration
+		margin    time.Duration
+		delay     time.Duration
+		ok        bool
+	}{
+		{name: "margin fits", remaining: 2 * time.Hour, margin: 15 * time.Minute, delay: 105 * time.Minute, ok: true},
+		{name: "margin is half", remaining: 30 * time.Minute, margin: 15 * time.Minute, delay: 15 * time.Minute, ok: true},
+		{name: "margin is too long", remaining: 20 * time.Minute, margin: 15 * time.Minute, delay: 15 * time.Minute, ok: true},
+		{name: "margin is longer than the timeout", remaining: 10 * time.Minute, margin: 15 * time.Minute, delay: 450 * time.Second, ok: true},
+		{name: "no margin", remaining: 10 * time.Minute, delay: 10 * time.Minute, ok: true},
+		{name: "no time left", remaining: 0, margin: 15 * time.Minute},
+		{name: "deadline passed", remaining: -time.Minute, margin: 15 * time.Minute},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			delay, ok := types.WatchdogDelay(test.remaining, test.margin)
+			require.Equal(t, test.ok, ok)
+			require.Equal(t, test.delay, delay)
+		})
+	}
+}
+
+func TestMarkTeardownPending(t *testing.T) {
+	t.Setenv("SKIP_TEARDOWN", "")
+	repoRoot := t.TempDir()
+	testFolder := filepath.Join(repoRoot, types.PlatformsDir, "copy", "tf", "public-ec2-instance")
+	require.NoError(t, os.MkdirAll(filepath.Join(testFolder, ".test-data"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(testFolder, ".test-data", "Ec2KeyPair.json"), []byte(`{"Name":"swf-test","Region":"us-east-2"}`), 0600))
+	platform := &types.TestPlatform{T: t, TestFolder: testFolder, RepoRoot: repoRoot}
+
+	require.NoError(t, platform.MarkTeardownPending())
+	markerPath := filepath.Join(repoRoot, types.PendingTeardownDir, "swf-test.json")
+	marker := readMarker(t, markerPath)
+	require.Equal(t, "swf-test", marker.Name)
+	require.Equal(t, "us-east-2", marker.Region)
+	require.Equal(t, filepath.Join(types.PlatformsDir, "copy", "tf", "public-ec2-instance"), marker.TestFolder)
+	require.Equal(t, marker.CreatedAt, marker.Heartbeat)
+
+	later := marker.Heartbeat.Add(time.Hour)
+	require.NoError(t, platform.Heartbeat(later))
+	require.True(t, later.Equal(readMarker(t, markerPath).Heartbeat))
+
+	require.NoError(t, platform.ClearTeardownPending())
+	require.NoFileExists(t, markerPath)
+	require.NoDirExists(t, filepath.Join(repoRoot, types.PlatformsDir, "copy"))
+	require.DirExists(t, filepath.Join(repoRoot, types.PlatformsDir))
+	require.NoError(t, platform.Heartbeat(later.Add(time.Hour)))
+	require.NoFileExists(t, markerPath)
+}
+
+func TestMarkTeardownPendingSkipped(t *testing.T) {
+	t.Setenv("SKIP_TEARDOWN", "1")
+	repoRoot := t.TempDir()
+	platform := &types.TestPlatform{T: t, TestFolder: filepath.Join(repoRoot, "tf"), RepoRoot: repoRoot}
+
+	require.NoError(t, platform.MarkTeardownPending())
+	require.NoDirExists(t, filepath.Join(repoRoot, types.PendingTeardownDir))
+	require.NoError(t, platform.ClearTeardownPending())
+}
+
+func TestFinishPendingTeardowns(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	repoRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repoRoot, types.PendingTeardownDir), 0750))
+	markers := map[string]*types.PendingTeardown{
+		"running":   {Name: "running", TestFolder: filepath.Join(types.PlatformsDir, "running", "tf"), Heartbeat: now.Add(-time.Minute)},
+		"dead":      {Name: "dead", TestFolder: filepath.Join(types.PlatformsDir, "dead", "tf"), Heartbeat: now.Add(-types.HeartbeatTimeout)},
+		"failing":   {Name: "failing", TestFolder: filepath.Join(types.PlatformsDir, "failing", "tf"), Heartbeat: now.Add(-time.Hour)},
+		"elsewhere": {Name: "elsewhere", TestFolder: filepath.Join(repoRoot, "tf"), Heartbeat: now.Add(-time.Hour)},
+	}
+	for name, marker := range markers {
+		require.NoError(t, os.MkdirAll(filepath.Join(repoRoot, marker.TestFolder), 0750))
+		require.NoError(t, types.WriteMarker(filepath.Join(repoRoot, types.PendingTeardownDir, name+".json"), marker))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(repoRoot, "tf"), 0750))
+	require.NoError(t, os.WriteFile(filepath.Join(repoRoot, types.PendingTeardownDir, "broken.json"), []byte("{"), 0600))
+
+	finished := make(map[string]string)
+	errs := types.FinishPendingTeardownsAt(repoRoot, now, t.Logf, func(marker *types.PendingTeardown, testFolder string) error {
+		finished[marker.Name] = testFolder
+		if marker.Name == "failing" {
+			return errors.New("destroy failed")
+		}
+
+		return nil
+	})
+
+	require.Equal(t, map[string]string{
+		"dead":      filepath.Join(repoRoot, types.PlatformsDir, "dead", "tf"),
+		"failing":   filepath.Join(repoRoot, types.PlatformsDir, "failing", "tf"),
+		"elsewhere": filepath.Join(repoRoot, "tf"),
+	}, finished)
+	require.Len(t, errs, 2)
+	require.ErrorContains(t, errs[0], "unable to read teardown pending marker")
+	require.ErrorContains(t, errs[1], "unable to finish pending teardown of failing: destroy failed")
+	for name, remains := range map[string]bool{"running": true, "dead": false, "failing": true, "elsewhere": false} {
+		markerPath := filepath.Join(repoRoot, types.PendingTeardownDir, name+".json")
+		platformFolder := filepath.Join(repoRoot, types.PlatformsDir, name)
+		if remains {
+			require.FileExists(t, markerPath)
+			require.DirExists(t, platformFolder)
+		} else {
+			require.NoFileExists(t, markerPath)
+			require.NoDirExists(t, platformFolder)
+		}
+	}
+	require.DirExists(t, filepath.Join(repoRoot, "tf"), "test folders outside of the platforms folder are left alone")
+}
+
+// readMarker reads the teardown pending marker at path.
+func readMarker(t *testing.T, path string) *types.PendingTeardown {
+	t.Helper()
+	bytes, err := os.ReadFile(path)
+	require.NoError(t, err)
+	marker := new(types.PendingTeardown)
+	require.NoError(t, json.Unmarshal(bytes, marker))
+
+	return marker
+}
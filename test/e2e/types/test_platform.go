@@ -11,12 +11,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	scp "github.com/bramvdbogaerde/go-scp"
 	"github.com/bramvdbogaerde/go-scp/auth"
-	"github.com/gruntwork-io/terratest/modules/aws"
 	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/ssh"
 	"github.com/gruntwork-io/terratest/modules/terraform"
@@ -29,41 +29,84 @@ import (
 type TestPlatform struct {
 	T          *testing.T
 	TestFolder string
+	// RepoRoot is the absolute path to the root of the repo, which is where the .tool-versions file was found
+	RepoRoot string
+	// teardownOnce makes sure the infrastructure is only torn down once, whether by Teardown, a signal, or the watchdog
+	teardownOnce sync.Once
+	// markerPath is the teardown pending marker written by MarkTeardownPending
+	markerPath string
+	// marker is the content of the marker, until the platform is torn down
+	marker *PendingTeardown
+	// stopHeartbeat stops the goroutine that keeps the heartbeat of the marker up to date
+	stopHeartbeat chan struct{}
+	markerMu      sync.Mutex
+	// ssh is the SSH connection shared by the tunnels to the server, see DialRemote
+	ssh *goSsh.Client
+	// listeners are the local ports opened by Forward and Proxy
+	listeners []net.Listener
+	// tunnelsClosed is set once Teardown has closed the listeners, after which the tunnels don't log anymore
+	tunnelsClosed bool
+	sshMu         sync.Mutex
+	// kubernetes holds the clients for the cluster on the server once they have been created, see Kubernetes
+	kubernetes   *Kubernetes
+	kubernetesMu sync.Mutex
 }
 
 // NewTestPlatform generates the test "state" object that allows for helper functions such as deferring the teardown step.
+// Deferring Teardown doesn't help when the test binary is interrupted or times out, so the platform is also torn down
+// when a SIGINT or SIGTERM is received, or shortly before the `go test -timeout` deadline.
 func NewTestPlatform(t *testing.T) *TestPlatform {
 	t.Helper()
 	testPlatform := new(TestPlatform)
 	testPlatform.T = t
-	tempFolder := teststructure.CopyTerraformFolderToTemp(t, "..", "tf/public-ec2-instance")
-	testPlatform.TestFolder = tempFolder
+	repoRoot, err := FindRepoRoot()
+	require.NoError(t, err)
+	// The copy goes into the .cache folder rather than the temp folder, which is gone along with the container once
+	// `make test` exits, so that a later run can still tear the infrastructure down with its Terraform state
+	platforms := filepath.Join(repoRoot, platformsDir)
+	require.NoError(t, os.MkdirAll(platforms, 0750)) //nolint:gomnd
+	testPlatform.TestFolder = teststructure.CopyTerraformFolderToDest(t, "..", "tf/public-ec2-instance", platforms)
 
-	// Since Terraform is going to be run with that temp folder as the CWD, we also need our .tool-versions file to be
+	// Since Terraform is going to be run with that folder as the CWD, we also need our .tool-versions file to be
 	// in that directory so that the right version of Terraform is being run there. I can neither confirm nor deny that
 	// this took me 2 days to figure out...
+	err = copyFile(filepath.Join(repoRoot, ".tool-versions"), fmt.Sprintf("%v/.tool-versions", testPlatform.TestFolder))
+	require.NoError(t, err)
+	testPlatform.RepoRoot = repoRoot
+	registerPlatform(testPlatform)
+
+	return testPlatform
+}
+
+// FindRepoRoot returns the absolute path to the root of the repo, which is where the .tool-versions file is, for tests
+// that need the repo before they have a platform.
+func FindRepoRoot() (string, error) {
 	// Since we can't be sure what the working directory is, we are going to walk up one directory at a time until we
-	// find a .tool-versions file and then copy it into the temp folder
-	found := false
+	// find a .tool-versions file
 	filePath := ".tool-versions"
-	for !found {
+	for {
 		//nolint:gocritic
 		if _, err := os.Stat(filePath); err == nil {
 			// The file exists
-			found = true
+			break
 		} else if errors.Is(err, os.ErrNotExist) {
-			// The file does *not* exist. Add a "../" and try again
+			// The file does *not* exist. Add a "../" and try again, unless we are at the root of the filesystem
+			if absPath, _ := filepath.Abs(filePath); filepath.Dir(absPath) == "/" {
+				return "", fmt.Errorf("unable to find .tool-versions in any parent folder: %w", err)
+			}
 			filePath = fmt.Sprintf("../%v", filePath)
 		} else {
 			// Schrodinger: file may or may not exist. See err for details.
 			// Therefore, do *NOT* use !os.IsNotExist(err) to test for file existence
-			require.NoError(t, err)
+			return "", fmt.Errorf("unable to look for .tool-versions: %w", err)
 		}
 	}
-	err := copyFile(filePath, fmt.Sprintf("%v/.tool-versions", testPlatform.TestFolder))
-	require.NoError(t, err)
+	repoRoot, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return "", fmt.Errorf("unable to find the root of the repo: %w", err)
+	}
 
-	return testPlatform
+	return repoRoot, nil
 }
 
 // RunSSHCommand provides a simple way to run a shell command on the server that is created using Terraform.
@@ -78,26 +121,11 @@ func (platform *TestPlatform) RunSSHCommandAsSudo(command string) (string, error
 
 // CopyFileOverScp provides a way to copy large files over scp
 func (platform *TestPlatform) CopyFileOverScp(src string, dest string, mode os.FileMode) error {
-	terraformOptions := teststructure.LoadTerraformOptions(platform.T, platform.TestFolder)
-	keyPair := teststructure.LoadEc2KeyPair(platform.T, platform.TestFolder)
-	instanceIP := terraform.Output(platform.T, terraformOptions, "public_instance_ip")
-
-	// Write private key to temp file
-	os.WriteFile(platform.TestFolder+"/private_key", []byte(keyPair.KeyPair.PrivateKey), 0644)
-
-	// Setup scp connection
-	clientConfig, _ := auth.PrivateKey("ubuntu", platform.TestFolder+"/private_key", goSsh.InsecureIgnoreHostKey())
-	client := scp.NewClient(instanceIP+":22", &clientConfig)
-
-	logger.Default.Logf(platform.T, "Establishing ssh connection to %s", instanceIP)
-
-	// Establish ssh connection
-	err := client.Connect()
+	client, err := platform.connectScp()
 	if err != nil {
-		return fmt.Errorf("unable to connect to remote host: %w", err)
+		return err
 	}
-
-	logger.Default.Logf(platform.T, "Connection established to %s", instanceIP)
+	defer client.Close()
 
 	logger.Default.Logf(platform.T, "Opening file to copy: %s", src)
 
@@ -107,7 +135,6 @@ func (platform *TestPlatform) CopyFileOverScp(src string, dest string, mode os.F
 		return fmt.Errorf("unable to open src file: %w", err)
 	}
 	defer srcFile.Close()
-	defer client.Close()
 
 	logger.Default.Logf(platform.T, "File opened: %s", src)
 
@@ -124,6 +151,60 @@ func (platform *TestPlatform) CopyFileOverScp(src string, dest string, mode os.F
 	return nil
 }
 
+// CopyFileFromScp provides a way to copy large files from the server over scp
+func (platform *TestPlatform) CopyFileFromScp(src string, dest string) error {
+	client, err := platform.connectScp()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// Create the file to copy into
+	destFile, err := os.Create(filepath.Clean(dest))
+	if err != nil {
+		return fmt.Errorf("unable to create dest file: %w", err)
+	}
+	defer destFile.Close()
+
+	logger.Default.Logf(platform.T, "Copying file from remote host: %s", src)
+
+	// Copy file from remote host
+	err = client.CopyFromRemote(context.TODO(), destFile, src)
+	if err != nil {
+		return fmt.Errorf("unable to copy file: %w", err)
+	}
+
+	logger.Default.Logf(platform.T, "File copied from remote host: %s", dest)
+
+	return nil
+}
+
+// connectScp establishes an scp connection to the server that is created using Terraform.
+func (platform *TestPlatform) connectScp() (*scp.Client, error) {
+	terraformOptions := teststructure.LoadTerraformOptions(platform.T, platform.TestFolder)
+	keyPair := teststructure.LoadEc2KeyPair(platform.T, platform.TestFolder)
+	instanceIP := terraform.Output(platform.T, terraformOptions, "public_instance_ip")
+
+	// Write private key to temp file
+	os.WriteFile(platform.TestFolder+"/private_key", []byte(keyPair.KeyPair.PrivateKey), 0644)
+
+	// Setup scp connection
+	clientConfig, _ := auth.PrivateKey("ubuntu", platform.TestFolder+"/private_key", goSsh.InsecureIgnoreHostKey())
+	client := scp.NewClient(instanceIP+":22", &clientConfig)
+
+	logger.Default.Logf(platform.T, "Establishing ssh connection to %s", instanceIP)
+
+	// Establish ssh connection
+	err := client.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to remote host: %w", err)
+	}
+
+	logger.Default.Logf(platform.T, "Connection established to %s", instanceIP)
+
+	return &client, nil
+}
+
 func (platform *TestPlatform) runSSHCommandWithOptionalSudo(command string, asSudo bool) (string, error) {
 	precommand := "bash -c"
 	if asSudo {
@@ -181,13 +262,15 @@ attemptLoop:
 }
 
 // Teardown brings down the Terraform infrastructure that was created.
+// If the test failed, diagnostics are collected from the server first, see collectDiagnosticsBeforeTeardown.
 func (platform *TestPlatform) Teardown() {
+	platform.collectDiagnosticsBeforeTeardown()
+	platform.closeTunnels()
 	teststructure.RunTestStage(platform.T, "TEARDOWN", func() {
-		keyPair := teststructure.LoadEc2KeyPair(platform.T, platform.TestFolder)
-		terraformOptions := teststructure.LoadTerraformOptions(platform.T, platform.TestFolder)
-		terraform.Destroy(platform.T, terraformOptions)
-		aws.DeleteEC2KeyPair(platform.T, keyPair)
+		err := platform.teardown()
+		require.NoError(platform.T, err)
 	})
+	unregisterPlatform(platform)
 }
 
 func readTeeFile(platform *TestPlatform, host ssh.Host, privateKey string, instanceIP string) {
@@ -0,0 +1,316 @@
+package types
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	teststructure "github.com/gruntwork-io/terratest/modules/test-structure"
+	goSsh "golang.org/x/crypto/ssh"
+)
+
+// DialRemote opens a connection to address as seen from the server that is created using Terraform, tunnelled through
+// SSH. An address like 127.0.0.1:6443 reaches a port that only listens on the server itself. The SSH connection is
+// shared by every tunnel of the platform, and is reconnected once if it has gone away.
+func (platform *TestPlatform) DialRemote(ctx context.Context, network string, address string) (net.Conn, error) {
+	client, err := platform.sshClient()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialContext(ctx, client, network, address)
+	if err == nil {
+		return conn, nil
+	}
+
+	// The connection may have been dropped, in which case reconnecting is worth one more try
+	platform.closeSSHClient(client)
+	client, err = platform.sshClient()
+	if err != nil {
+		return nil, err
+	}
+	conn, err = dialContext(ctx, client, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("unable to dial %s through ssh: %w", address, err)
+	}
+
+	return conn, nil
+}
+
+// Forward listens on a local port and forwards every connection to it to remoteAddr as seen from the server that is
+// created using Terraform, like `ssh -L`. It returns the local address to connect to. The listener is closed by
+// Teardown.
+func (platform *TestPlatform) Forward(remoteAddr string) (string, error) {
+	listener, err := platform.listenLocal()
+	if err != nil {
+		return "", err
+	}
+	go forward(listener, remoteAddr, platform.DialRemote, platform.logTunnel)
+
+	return listener.Addr().String(), nil
+}
+
+// HTTPClient returns an HTTP client whose connections are made from the server that is created using Terraform, so
+// hostnames like gitlab.bigbang.dev resolve through the server's /etc/hosts and reach the cluster's ingress gateways.
+// The URL is used as is, so TLS is verified against the real hostname and the right SNI is sent.
+func (platform *TestPlatform) HTTPClient() *http.Client {
+	return httpClient(platform.DialRemote)
+}
+
+// Proxy starts an HTTP proxy on a local port whose connections are made from the server that is created using
+// Terraform, and returns its URL. It supports CONNECT, so tools like `curl --proxy` and browsers can reach HTTPS
+// services in the cluster with the right SNI. The proxy is stopped by Teardown.
+func (platform *TestPlatform) Proxy() (string, error) {
+	listener, err := platform.listenLocal()
+	if err != nil {
+		return "", err
+	}
+	server := &http.Server{
+		Handler:           &connectProxy{dial: platform.DialRemote, transport: platform.HTTPClient().Transport},
+		ReadHeaderTimeout: 30 * time.Second, //nolint:gomnd
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, net.ErrClosed) {
+			platform.logTunnel("proxy stopped: %v", err)
+		}
+	}()
+
+	return "http://" + listener.Addr().String(), nil
+}
+
+// forward accepts connections on listener until it is closed, and pipes each of them to remoteAddr dialled with dial.
+// Connections that can't be dialled are closed, and logged with logf.
+func forward(listener net.Listener, remoteAddr string, dial dialFunc, logf func(format string, args ...interface{})) {
+	for {
+		local, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer local.Close()
+			remote, err := dial(context.Background(), "tcp", remoteAddr)
+			if err != nil {
+				logf("unable to forward connection to %s: %v", remoteAddr, err)
+
+				return
+			}
+			defer remote.Close()
+			pipe(local, remote)
+		}()
+	}
+}
+
+// httpClient returns an HTTP client that makes its connections with dial rather than through a proxy.
+func httpClient(dial dialFunc) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.Proxy = nil
+	transport.DialContext = dial
+
+	return &http.Client{Transport: transport}
+}
+
+// logTunnel logs what went wrong in a tunnel. The tunnels are served by goroutines that can still be running once the
+// test is over, and logging through platform.T then would be reported against a finished test, so nothing is logged
+// once Teardown has closed the tunnels.
+func (platform *TestPlatform) logTunnel(format string, args ...interface{}) {
+	platform.sshMu.Lock()
+	defer platform.sshMu.Unlock()
+	if platform.tunnelsClosed {
+		return
+	}
+	logger.Default.Logf(platform.T, format, args...)
+}
+
+// dialFunc opens a connection to address, like net.Dialer.DialContext.
+type dialFunc func(ctx context.Context, network string, address string) (net.Conn, error)
+
+// connectProxy is an HTTP proxy that makes its connections with dial.
+type connectProxy struct {
+	dial      dialFunc
+	transport http.RoundTripper
+}
+
+// ServeHTTP tunnels CONNECT requests and forwards plain HTTP requests with an absolute URL.
+func (proxy *connectProxy) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method == http.MethodConnect {
+		proxy.tunnel(writer, request)
+
+		return
+	}
+	if !request.URL.IsAbs() {
+		http.Error(writer, "this is a proxy, requests need an absolute URL", http.StatusBadRequest)
+
+		return
+	}
+	outgoing := request.Clone(request.Context())
+	outgoing.RequestURI = ""
+	outgoing.Header.Del("Proxy-Connection")
+	outgoing.Header.Del("Proxy-Authorization")
+	response, err := proxy.transport.RoundTrip(outgoing)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadGateway)
+
+		return
+	}
+	defer response.Body.Close()
+	for key, values := range response.Header {
+		for _, value := range values {
+			writer.Header().Add(key, value)
+		}
+	}
+	writer.WriteHeader(response.StatusCode)
+	_, _ = io.Copy(writer, response.Body)
+}
+
+// tunnel connects to the host of a CONNECT request and pipes the client's connection to it.
+func (proxy *connectProxy) tunnel(writer http.ResponseWriter, request *http.Request) {
+	remote, err := proxy.dial(request.Context(), "tcp", request.Host)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadGateway)
+
+		return
+	}
+	defer remote.Close()
+	hijacker, ok := writer.(http.Hijacker)
+	if !ok {
+		http.Error(writer, "connection can't be hijacked", http.StatusInternalServerError)
+
+		return
+	}
+	local, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer local.Close()
+	if _, err := local.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		return
+	}
+	pipe(&bufferedConn{Conn: local, reader: buffered.Reader}, remote)
+}
+
+// bufferedConn is a hijacked connection whose reads start with what the HTTP server had already buffered.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (conn *bufferedConn) Read(b []byte) (int, error) {
+	return conn.reader.Read(b)
+}
+
+// listenLocal listens on a random local port, and keeps track of the listener so Teardown can close it.
+func (platform *TestPlatform) listenLocal() (net.Listener, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen on a local port: %w", err)
+	}
+	platform.sshMu.Lock()
+	platform.listeners = append(platform.listeners, listener)
+	platform.tunnelsClosed = false
+	platform.sshMu.Unlock()
+
+	return listener, nil
+}
+
+// closeTunnels closes every listener started by Forward and Proxy, and the shared SSH connection.
+func (platform *TestPlatform) closeTunnels() {
+	platform.sshMu.Lock()
+	for _, listener := range platform.listeners {
+		_ = listener.Close()
+	}
+	platform.listeners = nil
+	platform.tunnelsClosed = true
+	platform.sshMu.Unlock()
+	platform.closeSSHClient(nil)
+}
+
+// pipe copies data both ways between two connections until either side is done.
+func pipe(conn1 net.Conn, conn2 net.Conn) {
+	done := make(chan struct{}, 2) //nolint:gomnd
+	go func() {
+		_, _ = io.Copy(conn1, conn2)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn2, conn1)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// sshClient returns the SSH connection to the server that is created using Terraform, connecting if needed.
+func (platform *TestPlatform) sshClient() (*goSsh.Client, error) {
+	platform.sshMu.Lock()
+	defer platform.sshMu.Unlock()
+	if platform.ssh != nil {
+		return platform.ssh, nil
+	}
+
+	terraformOptions := teststructure.LoadTerraformOptions(platform.T, platform.TestFolder)
+	keyPair := teststructure.LoadEc2KeyPair(platform.T, platform.TestFolder)
+	instanceIP, err := terraform.OutputE(platform.T, terraformOptions, "public_instance_ip")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get instance ip: %w", err)
+	}
+	key, err := goSsh.ParsePrivateKey([]byte(keyPair.KeyPair.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key: %w", err)
+	}
+	sshConfig := &goSsh.ClientConfig{
+		User:            "ubuntu",
+		HostKeyCallback: goSsh.InsecureIgnoreHostKey(),
+		Auth: []goSsh.AuthMethod{
+			goSsh.PublicKeys(key),
+		},
+	}
+	client, err := goSsh.Dial("tcp", net.JoinHostPort(instanceIP, "22"), sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to remote host: %w", err)
+	}
+	platform.ssh = client
+
+	return client, nil
+}
+
+// closeSSHClient closes the shared SSH connection if it is still client, so that the next tunnel reconnects.
+func (platform *TestPlatform) closeSSHClient(client *goSsh.Client) {
+	platform.sshMu.Lock()
+	defer platform.sshMu.Unlock()
+	if platform.ssh == nil || (client != nil && platform.ssh != client) {
+		return
+	}
+	_ = platform.ssh.Close()
+	platform.ssh = nil
+}
+
+// dialContext dials address through client, giving up when ctx is done.
+func dialContext(ctx context.Context, client *goSsh.Client, network string, address string) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := client.Dial(network, address)
+		done <- result{conn, err}
+	}()
+	select {
+	case res := <-done:
+		return res.conn, res.err
+	case <-ctx.Done():
+		go func() {
+			if res := <-done; res.conn != nil {
+				_ = res.conn.Close()
+			}
+		}()
+
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,220 @@
+package types_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForward(t *testing.T) {
+	t.Parallel()
+	echo := echoServer(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	var dialled []string
+	var mu sync.Mutex
+	dial := func(ctx context.Context, network string, address string) (net.Conn, error) {
+		mu.Lock()
+		dialled = append(dialled, address)
+		mu.Unlock()
+
+		return (&net.Dialer{}).DialContext(ctx, network, echo)
+	}
+	go types.ForwardTo(listener, "127.0.0.1:6443", dial, t.Logf)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "hello\n", line)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"127.0.0.1:6443"}, dialled)
+}
+
+func TestForwardDialFailure(t *testing.T) {
+	t.Parallel()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	logged := make(chan string, 1)
+	dial := func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("ssh is gone")
+	}
+	go types.ForwardTo(listener, "127.0.0.1:6443", dial, func(format string, args ...interface{}) {
+		logged <- fmt.Sprintf(format, args...)
+	})
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF, "the connection is closed when the remote end can't be dialled")
+	require.Equal(t, "unable to forward connection to 127.0.0.1:6443: ssh is gone", <-logged)
+}
+
+func TestLogTunnelAfterTeardown(t *testing.T) {
+	t.Parallel()
+	platform := &types.TestPlatform{T: t}
+	listener, err := platform.ListenLocal()
+	require.NoError(t, err)
+	platform.LogTunnel("logged while the tunnels are open")
+
+	platform.CloseTunnels()
+	_, err = listener.Accept()
+	require.ErrorIs(t, err, net.ErrClosed)
+	// Once the tunnels are closed the platform's T is never used, so a tunnel that outlives the test can't panic
+	platform.T = nil
+	require.NotPanics(t, func() {
+		platform.LogTunnel("dropped once the tunnels are closed")
+	})
+}
+
+func TestHTTPClient(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = fmt.Fprintf(writer, "hello from %s", request.Host)
+	}))
+	defer server.Close()
+	var dialled []string
+	client := types.NewHTTPClient(func(ctx context.Context, network string, address string) (net.Conn, error) {
+		dialled = append(dialled, address)
+
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	})
+
+	response, err := client.Get("http://gitlab.bigbang.dev/-/readiness")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "hello from gitlab.bigbang.dev", string(body))
+	require.Equal(t, []string{"gitlab.bigbang.dev:80"}, dialled)
+}
+
+func TestConnectProxy(t *testing.T) {
+	t.Parallel()
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = fmt.Fprintf(writer, "%s %s", request.Method, request.URL.Path)
+	}))
+	defer backend.Close()
+	var mu sync.Mutex
+	var dialled []string
+	dial := func(ctx context.Context, network string, address string) (net.Conn, error) {
+		mu.Lock()
+		dialled = append(dialled, address)
+		mu.Unlock()
+		if address == "unreachable.bigbang.dev:443" {
+			return nil, errors.New("no route to host")
+		}
+
+		return (&net.Dialer{}).DialContext(ctx, network, backend.Listener.Addr().String())
+	}
+	proxy := httptest.NewServer(types.NewConnectProxy(dial, types.NewHTTPClient(dial).Transport))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+	transport := backend.Client().Transport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.Proxy = http.ProxyURL(proxyURL)
+	client := &http.Client{Transport: transport}
+
+	t.Run("connect", func(t *testing.T) {
+		response, err := client.Get("https://" + backend.Listener.Addr().String() + "/users/sign_in")
+		require.NoError(t, err)
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.Equal(t, "GET /users/sign_in", string(body))
+	})
+
+	t.Run("connect failure", func(t *testing.T) {
+		conn, err := net.Dial("tcp", proxyURL.Host)
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte("CONNECT unreachable.bigbang.dev:443 HTTP/1.1\r\nHost: unreachable.bigbang.dev:443\r\n\r\n"))
+		require.NoError(t, err)
+		response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusBadGateway, response.StatusCode)
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(body), "no route to host")
+	})
+
+	t.Run("plain http", func(t *testing.T) {
+		plain := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("X-Proxy-Authorization", request.Header.Get("Proxy-Authorization"))
+			writer.WriteHeader(http.StatusTeapot)
+			_, _ = fmt.Fprint(writer, request.URL.Path)
+		}))
+		defer plain.Close()
+		plainProxy := httptest.NewServer(types.NewConnectProxy(nil, types.NewHTTPClient(func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, plain.Listener.Addr().String())
+		}).Transport))
+		defer plainProxy.Close()
+		plainProxyURL, err := url.Parse(plainProxy.URL)
+		require.NoError(t, err)
+		plainProxyURL.User = url.UserPassword("user", "secret")
+		client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(plainProxyURL)}}
+
+		response, err := client.Get("http://sonarqube.bigbang.dev/api/system/status")
+		require.NoError(t, err)
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusTeapot, response.StatusCode)
+		require.Equal(t, "/api/system/status", string(body))
+		require.Empty(t, response.Header.Get("X-Proxy-Authorization"), "proxy credentials aren't passed on")
+	})
+
+	t.Run("relative url", func(t *testing.T) {
+		response, err := http.Get(proxy.URL + "/users/sign_in")
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.True(t, strings.HasPrefix(strings.Join(dialled, ","), backend.Listener.Addr().String()), dialled)
+	require.Contains(t, dialled, "unreachable.bigbang.dev:443")
+}
+
+// echoServer starts a server that echoes every line it reads back, and returns its address.
+func echoServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
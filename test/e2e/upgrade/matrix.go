@@ -0,0 +1,107 @@
+package upgrade
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The outcomes of an upgrade from a version, as the matrix reports them.
+const (
+	// OutcomeSupported is an upgrade that worked and kept all data that was checked
+	OutcomeSupported = "supported"
+	// OutcomeDataLost is an upgrade that lost or changed seeded data
+	OutcomeDataLost = "loses data"
+	// OutcomeFailed is an upgrade that failed, before or after the data was checked
+	OutcomeFailed = "failed"
+)
+
+// Result is the outcome of the upgrade scenario from one version.
+type Result struct {
+	Version string
+	// Report is what the scenario found, nil if the upgrade failed before the data was checked
+	Report *Report
+	// Failed is true if the test of the upgrade failed, for whatever reason
+	Failed bool
+}
+
+// Matrix collects the results of the upgrade scenario from several versions, which may run at the same time.
+type Matrix struct {
+	mu      sync.Mutex
+	results []Result
+	// order is the position of every version in the list the matrix was created with
+	order map[string]int
+}
+
+// NewMatrix returns an empty matrix for upgrades from the given versions, which it reports in that order.
+func NewMatrix(versions []string) *Matrix {
+	order := make(map[string]int, len(versions))
+	for i, version := range versions {
+		order[version] = i
+	}
+
+	return &Matrix{order: order}
+}
+
+// Add records the result of the upgrade from a version.
+func (matrix *Matrix) Add(version string, report *Report, failed bool) {
+	matrix.mu.Lock()
+	defer matrix.mu.Unlock()
+	matrix.results = append(matrix.results, Result{Version: version, Report: report, Failed: failed})
+}
+
+// Results returns the results that were added, in the order of the versions the matrix was created with.
+func (matrix *Matrix) Results() []Result {
+	matrix.mu.Lock()
+	defer matrix.mu.Unlock()
+	results := append([]Result(nil), matrix.results...)
+	sort.SliceStable(results, func(i, j int) bool {
+		return matrix.order[results[i].Version] < matrix.order[results[j].Version]
+	})
+
+	return results
+}
+
+// Outcome returns whether the upgrade is supported, loses data, or failed.
+func (result Result) Outcome() string {
+	switch {
+	case result.Report != nil && !result.Report.OK():
+		return OutcomeDataLost
+	case result.Report == nil || result.Failed:
+		return OutcomeFailed
+	default:
+		return OutcomeSupported
+	}
+}
+
+// String returns the matrix as a Markdown table with a row for every version, followed by the reports of the
+// upgrades that didn't keep all data.
+func (matrix *Matrix) String() string {
+	results := matrix.Results()
+	var text strings.Builder
+	text.WriteString("| From | Outcome | Lost or changed | Not checked |\n")
+	text.WriteString("| --- | --- | --- | --- |\n")
+	for _, result := range results {
+		lost, unchecked := "-", "-"
+		if result.Report != nil {
+			lost = fmt.Sprint(len(result.Report.Findings))
+			if len(result.Report.Unchecked) > 0 {
+				names := make([]string, 0, len(result.Report.Unchecked))
+				for name := range result.Report.Unchecked {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				unchecked = strings.Join(names, ", ")
+			}
+		}
+		fmt.Fprintf(&text, "| %s | %s | %s | %s |\n", result.Version, result.Outcome(), lost, unchecked)
+	}
+	for _, result := range results {
+		if result.Report != nil && !result.Report.OK() {
+			fmt.Fprintf(&text, "\n## From %s\n\n%s", result.Version, result.Report)
+		}
+	}
+
+	return text.String()
+}
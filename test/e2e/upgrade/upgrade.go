@@ -0,0 +1,178 @@
+// Package upgrade runs upgrade scenarios for the e2e tests: data is seeded into the capabilities on the version that is
+// upgraded from, and read back once the upgrade is done to find the data that was lost or changed on the way. It also
+// resolves the versions to upgrade from, and collects the outcome of the upgrade from each of them in a matrix.
+package upgrade
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Records are what a capability seeded, as a fingerprint of each piece of data, such as the checksum of a file, by a
+// description of it, such as the path of the file.
+type Records map[string]string
+
+// Capability seeds data into one capability of the software factory and reads it back after the upgrade.
+type Capability struct {
+	// Name is the name of the capability, as in capabilities.yaml, and of the subtests its hooks run in
+	Name string
+	// Seed creates data on the version that is upgraded from and returns its records. It runs in a subtest, which it
+	// skips if the capability can't be seeded, so the data it creates must not be removed when the subtest finishes.
+	Seed func(t *testing.T) Records
+	// Verify reads the data back after the upgrade, given the records Seed returned, and returns the records of what
+	// it found. Data it didn't find is left out.
+	Verify func(t *testing.T, seeded Records) Records
+}
+
+// Finding is a piece of seeded data that didn't survive the upgrade the way it was seeded.
+type Finding struct {
+	Capability string
+	Item       string
+	Seeded     string
+	// Found is the fingerprint of the data after the upgrade, unless it was lost
+	Found string
+	Lost  bool
+}
+
+// Report is what an upgrade scenario found after the upgrade.
+type Report struct {
+	// Findings are the data that was lost or changed, by capability and then by item
+	Findings []Finding
+	// Unchecked are the capabilities whose data wasn't checked, with the reason
+	Unchecked map[string]string
+}
+
+// Scenario seeds data into capabilities and verifies it after the upgrade. Seed and Verify have to be called with
+// the same Scenario, since the records of the seeded data are only kept in memory.
+type Scenario struct {
+	capabilities []Capability
+	seeded       map[string]Records
+	unseeded     map[string]string
+}
+
+// NewScenario returns a scenario that seeds and verifies data in the given capabilities, in that order.
+func NewScenario(capabilities ...Capability) *Scenario {
+	return &Scenario{capabilities: capabilities, seeded: make(map[string]Records), unseeded: make(map[string]string)}
+}
+
+// Names returns the names of the capabilities of the scenario, which have to be ready before it seeds them.
+func (scenario *Scenario) Names() []string {
+	names := make([]string, 0, len(scenario.capabilities))
+	for _, capability := range scenario.capabilities {
+		names = append(names, capability.Name)
+	}
+
+	return names
+}
+
+// Seed seeds every capability in its own subtest, so a capability that can't be seeded doesn't keep the others from
+// being seeded.
+func (scenario *Scenario) Seed(t *testing.T) {
+	t.Helper()
+	for _, capability := range scenario.capabilities {
+		capability := capability
+		passed := t.Run(capability.Name, func(t *testing.T) {
+			scenario.seeded[capability.Name] = capability.Seed(t)
+		})
+		if _, ok := scenario.seeded[capability.Name]; ok {
+			continue
+		}
+		if passed {
+			scenario.unseeded[capability.Name] = "seeding was skipped"
+		} else {
+			scenario.unseeded[capability.Name] = "seeding failed"
+		}
+	}
+}
+
+// Verify verifies the data of every capability that was seeded in its own subtest, and returns what it found.
+func (scenario *Scenario) Verify(t *testing.T) *Report {
+	t.Helper()
+	report := &Report{Unchecked: make(map[string]string)}
+	for _, capability := range scenario.capabilities {
+		capability := capability
+		seeded, ok := scenario.seeded[capability.Name]
+		if !ok {
+			report.Unchecked[capability.Name] = scenario.unseeded[capability.Name]
+
+			continue
+		}
+		var found Records
+		verified := false
+		t.Run(capability.Name, func(t *testing.T) {
+			found = capability.Verify(t, seeded)
+			verified = true
+		})
+		if !verified {
+			report.Unchecked[capability.Name] = "verification failed"
+
+			continue
+		}
+		report.Findings = append(report.Findings, Compare(capability.Name, seeded, found)...)
+	}
+
+	return report
+}
+
+// Compare returns the findings for the data a capability seeded that wasn't found the way it was seeded, by item.
+func Compare(capability string, seeded Records, found Records) []Finding {
+	items := make([]string, 0, len(seeded))
+	for item := range seeded {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	var findings []Finding
+	for _, item := range items {
+		value, ok := found[item]
+		switch {
+		case !ok:
+			findings = append(findings, Finding{Capability: capability, Item: item, Seeded: seeded[item], Lost: true})
+		case value != seeded[item]:
+			findings = append(findings, Finding{Capability: capability, Item: item, Seeded: seeded[item], Found: value})
+		}
+	}
+
+	return findings
+}
+
+// String describes the finding, such as `GitLab: file data.txt of root/e2e-abc was lost`.
+func (finding Finding) String() string {
+	if finding.Lost {
+		return fmt.Sprintf("%s: %s was lost", finding.Capability, finding.Item)
+	}
+
+	return fmt.Sprintf("%s: %s changed from %q to %q", finding.Capability, finding.Item, finding.Seeded, finding.Found)
+}
+
+// OK returns true if all data that was checked survived the upgrade.
+func (report *Report) OK() bool {
+	return len(report.Findings) == 0
+}
+
+// String returns the report as text, with a line for every finding and every capability that wasn't checked.
+func (report *Report) String() string {
+	var text strings.Builder
+	if report.OK() {
+		text.WriteString("All data that was checked survived the upgrade\n")
+	} else {
+		text.WriteString("Data lost or changed by the upgrade:\n")
+		for _, finding := range report.Findings {
+			fmt.Fprintf(&text, "  %s\n", finding)
+		}
+	}
+	if len(report.Unchecked) > 0 {
+		names := make([]string, 0, len(report.Unchecked))
+		for name := range report.Unchecked {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		text.WriteString("Not checked:\n")
+		for _, name := range names {
+			fmt.Fprintf(&text, "  %s: %s\n", name, report.Unchecked[name])
+		}
+	}
+
+	return text.String()
+}
@@ -0,0 +1,88 @@
+package upgrade_test
+
+import (
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/upgrade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+	seeded := upgrade.Records{"file a": "1", "file b": "2", "file c": "3"}
+	found := upgrade.Records{"file a": "1", "file c": "4", "file d": "5"}
+
+	findings := upgrade.Compare("Nexus", seeded, found)
+	require.Equal(t, []upgrade.Finding{
+		{Capability: "Nexus", Item: "file b", Seeded: "2", Lost: true},
+		{Capability: "Nexus", Item: "file c", Seeded: "3", Found: "4"},
+	}, findings)
+	require.Equal(t, "Nexus: file b was lost", findings[0].String())
+	require.Equal(t, `Nexus: file c changed from "3" to "4"`, findings[1].String())
+}
+
+func TestScenario(t *testing.T) {
+	t.Parallel()
+	upgraded := false
+	scenario := upgrade.NewScenario(
+		upgrade.Capability{
+			Name: "GitLab",
+			Seed: func(t *testing.T) upgrade.Records {
+				return upgrade.Records{"commit": "abc", "file": "123"}
+			},
+			Verify: func(t *testing.T, seeded upgrade.Records) upgrade.Records {
+				require.True(t, upgraded, "verified before the upgrade")
+
+				return upgrade.Records{"commit": seeded["commit"]}
+			},
+		},
+		upgrade.Capability{
+			Name: "Jira",
+			Seed: func(t *testing.T) upgrade.Records {
+				t.Skip("no license")
+
+				return nil
+			},
+			Verify: func(t *testing.T, seeded upgrade.Records) upgrade.Records {
+				t.Error("verified a capability that wasn't seeded")
+
+				return nil
+			},
+		},
+	)
+	require.Equal(t, []string{"GitLab", "Jira"}, scenario.Names())
+
+	scenario.Seed(t)
+	upgraded = true
+	report := scenario.Verify(t)
+	require.False(t, report.OK())
+	require.Equal(t, []upgrade.Finding{{Capability: "GitLab", Item: "file", Seeded: "123", Lost: true}}, report.Findings)
+	require.Equal(t, map[string]string{"Jira": "seeding was skipped"}, report.Unchecked)
+	require.Equal(t, "Data lost or changed by the upgrade:\n  GitLab: file was lost\nNot checked:\n  Jira: seeding was skipped\n", report.String())
+}
+
+func TestMatrix(t *testing.T) {
+	t.Parallel()
+	matrix := upgrade.NewMatrix([]string{"0.0.11", "0.0.12", "0.0.13"})
+	lost := &upgrade.Report{Findings: []upgrade.Finding{{Capability: "Nexus", Item: "file a", Seeded: "1", Lost: true}}}
+	matrix.Add("0.0.13", &upgrade.Report{Unchecked: map[string]string{"Jira": "seeding was skipped"}}, false)
+	matrix.Add("0.0.11", nil, true)
+	matrix.Add("0.0.12", lost, true)
+
+	results := matrix.Results()
+	require.Len(t, results, 3)
+	require.Equal(t, upgrade.OutcomeFailed, results[0].Outcome())
+	require.Equal(t, upgrade.OutcomeDataLost, results[1].Outcome())
+	require.Equal(t, upgrade.OutcomeSupported, results[2].Outcome())
+	require.Equal(t, `| From | Outcome | Lost or changed | Not checked |
+| --- | --- | --- | --- |
+| 0.0.11 | failed | - | - |
+| 0.0.12 | loses data | 1 | - |
+| 0.0.13 | supported | 0 | Jira |
+
+## From 0.0.12
+
+Data lost or changed by the upgrade:
+  Nexus: file a was lost
+`, matrix.String())
+}
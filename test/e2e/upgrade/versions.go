@@ -0,0 +1,247 @@
+package upgrade
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// lastPrefix starts a source spec that asks for the latest released versions, such as "last:3".
+const lastPrefix = "last:"
+
+// releaseTag matches the tags of released versions, such as 0.0.13 or v0.0.13.
+var releaseTag = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)$`)
+
+// bearerParam matches a parameter of a WWW-Authenticate header, such as realm="https://ghcr.io/token".
+var bearerParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// linkNext matches the Link header registries send when there are more tags to list.
+var linkNext = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// Sources are the versions to upgrade from, as the spec ParseSources reads.
+type Sources struct {
+	// Versions are the versions that were listed
+	Versions []string
+	// Last is how many of the latest released versions to upgrade from, instead of Versions
+	Last int
+}
+
+// ParseSources reads the versions to upgrade from, which are either a comma separated list of versions, such as
+// "0.0.12,0.0.13", or "last:N" for the N latest released versions.
+func ParseSources(spec string) (Sources, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, lastPrefix) {
+		last, err := strconv.Atoi(strings.TrimPrefix(spec, lastPrefix))
+		if err != nil || last < 1 {
+			return Sources{}, fmt.Errorf("%q should be last:N with N a number above 0", spec)
+		}
+
+		return Sources{Last: last}, nil
+	}
+	var sources Sources
+	for _, version := range strings.Split(spec, ",") {
+		if version = strings.TrimSpace(version); version != "" {
+			sources.Versions = append(sources.Versions, version)
+		}
+	}
+	if len(sources.Versions) == 0 {
+		return Sources{}, fmt.Errorf("%q lists no versions", spec)
+	}
+
+	return sources, nil
+}
+
+// LatestVersions returns the n latest released versions among tags, oldest first. Tags that aren't a released version,
+// such as "latest" or release candidates, are left out.
+func LatestVersions(tags []string, n int) []string {
+	type release struct {
+		tag     string
+		version [3]int
+	}
+	var releases []release
+	for _, tag := range tags {
+		match := releaseTag.FindStringSubmatch(tag)
+		if match == nil {
+			continue
+		}
+		var version [3]int
+		for i := range version {
+			version[i], _ = strconv.Atoi(match[i+1])
+		}
+		releases = append(releases, release{tag: tag, version: version})
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		for k := range releases[i].version {
+			if releases[i].version[k] != releases[j].version[k] {
+				return releases[i].version[k] < releases[j].version[k]
+			}
+		}
+
+		return releases[i].tag < releases[j].tag
+	})
+	if len(releases) > n {
+		releases = releases[len(releases)-n:]
+	}
+	latest := make([]string, 0, len(releases))
+	for _, release := range releases {
+		latest = append(latest, release.tag)
+	}
+
+	return latest
+}
+
+// ReadIndex reads a local index of released versions, with one version on each line. Empty lines and lines that start
+// with # are left out.
+func ReadIndex(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open version index: %w", err)
+	}
+	defer file.Close()
+	var versions []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		versions = append(versions, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read version index %s: %w", path, err)
+	}
+
+	return versions, nil
+}
+
+// RegistryTags lists the tags of a repository in an OCI registry, such as defenseunicorns/uds-package/software-factory-demo
+// in https://ghcr.io. When the registry asks for a token, it is fetched from the registry's token service, with basic
+// auth if a username is given and anonymously otherwise.
+func RegistryTags(client *http.Client, registryURL string, repository string, username string, password string) ([]string, error) {
+	registryURL = strings.TrimSuffix(registryURL, "/")
+	next := fmt.Sprintf("%s/v2/%s/tags/list", registryURL, repository)
+	token := ""
+	var tags []string
+	for next != "" {
+		response, err := getTags(client, next, token)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode == http.StatusUnauthorized && token == "" {
+			challenge := response.Header.Get("WWW-Authenticate")
+			response.Body.Close()
+			if token, err = registryToken(client, challenge, username, password); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		content, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read tags of %s: %w", repository, err)
+		}
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("listing tags of %s returned HTTP %d: %s", repository, response.StatusCode, content)
+		}
+		if err := json.Unmarshal(content, &list); err != nil {
+			return nil, fmt.Errorf("unable to parse tags of %s: %w", repository, err)
+		}
+		tags = append(tags, list.Tags...)
+		next = ""
+		if match := linkNext.FindStringSubmatch(response.Header.Get("Link")); match != nil {
+			base, _ := url.Parse(registryURL)
+			link, err := base.Parse(match[1])
+			if err != nil {
+				return nil, fmt.Errorf("registry sent an invalid link to more tags of %s: %w", repository, err)
+			}
+			next = link.String()
+		}
+	}
+
+	return tags, nil
+}
+
+// getTags requests a page of the tags of a repository, with the bearer token if there is one.
+func getTags(client *http.Client, tagsURL string, token string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, tagsURL, nil) //nolint:noctx
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list tags at %s: %w", tagsURL, err)
+	}
+
+	return response, nil
+}
+
+// registryToken gets a token from the token service a registry named in the challenge of its WWW-Authenticate header.
+func registryToken(client *http.Client, challenge string, username string, password string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("registry asked for authentication it doesn't offer a token for: %q", challenge)
+	}
+	params := make(map[string]string)
+	for _, match := range bearerParam.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("registry asked for a token without a valid realm: %q", challenge)
+	}
+	query := tokenURL.Query()
+	for _, name := range []string{"service", "scope"} {
+		if params[name] != "" {
+			query.Set(name, params[name])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+	request, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil) //nolint:noctx
+	if err != nil {
+		return "", fmt.Errorf("unable to create request: %w", err)
+	}
+	if username != "" {
+		request.SetBasicAuth(username, password)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("unable to get registry token: %w", err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read registry token: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token service returned HTTP %d: %s", response.StatusCode, content)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(content, &token); err != nil {
+		return "", fmt.Errorf("unable to parse registry token: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("registry token service returned no token: %s", content)
+	}
+
+	return token.Token, nil
+}
@@ -0,0 +1,75 @@
+package upgrade_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/upgrade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSources(t *testing.T) {
+	t.Parallel()
+	sources, err := upgrade.ParseSources(" 0.0.11, 0.0.12 ,")
+	require.NoError(t, err)
+	require.Equal(t, upgrade.Sources{Versions: []string{"0.0.11", "0.0.12"}}, sources)
+	sources, err = upgrade.ParseSources("last:3")
+	require.NoError(t, err)
+	require.Equal(t, upgrade.Sources{Last: 3}, sources)
+
+	_, err = upgrade.ParseSources("last:0")
+	require.Error(t, err)
+	_, err = upgrade.ParseSources(",")
+	require.Error(t, err)
+}
+
+func TestLatestVersions(t *testing.T) {
+	t.Parallel()
+	tags := []string{"0.0.9", "latest", "0.0.10", "0.0.13-rc1", "0.1.0", "0.0.12", "sha256-abc.sig"}
+	require.Equal(t, []string{"0.0.10", "0.0.12", "0.1.0"}, upgrade.LatestVersions(tags, 3))
+	require.Equal(t, []string{"0.0.9", "0.0.10", "0.0.12", "0.1.0"}, upgrade.LatestVersions(tags, 10))
+}
+
+func TestReadIndex(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "versions.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# Released versions\n0.0.11\n\n 0.0.12 \n"), 0600))
+	versions, err := upgrade.ReadIndex(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"0.0.11", "0.0.12"}, versions)
+}
+
+func TestRegistryTags(t *testing.T) {
+	t.Parallel()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(writer http.ResponseWriter, request *http.Request) {
+		require.Equal(t, "repository:uds-package/software-factory-demo:pull", request.URL.Query().Get("scope"))
+		fmt.Fprint(writer, `{"token": "anonymous"}`)
+	})
+	mux.HandleFunc("/v2/uds-package/software-factory-demo/tags/list", func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer anonymous" {
+			writer.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:uds-package/software-factory-demo:pull"`, server.URL))
+			http.Error(writer, `{"errors": [{"code": "UNAUTHORIZED"}]}`, http.StatusUnauthorized)
+
+			return
+		}
+		if request.URL.Query().Get("last") == "" {
+			writer.Header().Set("Link", `</v2/uds-package/software-factory-demo/tags/list?last=0.0.12&n=2>; rel="next"`)
+			fmt.Fprint(writer, `{"tags": ["0.0.11", "0.0.12"]}`)
+
+			return
+		}
+		fmt.Fprint(writer, `{"tags": ["0.0.13"]}`)
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	tags, err := upgrade.RegistryTags(server.Client(), server.URL, "uds-package/software-factory-demo", "", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"0.0.11", "0.0.12", "0.0.13"}, tags)
+}
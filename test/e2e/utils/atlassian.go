@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/atlassian"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// WaitForAtlassianState waits until the Atlassian application at baseURL reports one of the given states on its status
+// endpoint, and returns its status. It gives up right away if the application reports that it failed to start.
+func WaitForAtlassianState(t *testing.T, client *http.Client, baseURL string, timeout time.Duration, states ...string) *atlassian.Status {
+	t.Helper()
+	timeoutClient := *client
+	timeoutClient.Timeout = httpRequestTimeout
+	var status *atlassian.Status
+	maxRetries := int(timeout / httpRetryInterval)
+	description := fmt.Sprintf("Wait for %s to be %s", baseURL, strings.Join(states, " or "))
+	_, err := retry.DoWithRetryE(t, description, maxRetries, httpRetryInterval, func() (string, error) {
+		var err error
+		status, err = atlassian.GetStatus(&timeoutClient, baseURL)
+		if err != nil {
+			return "", err
+		}
+		for _, state := range states {
+			if status.State == state {
+				return "", nil
+			}
+		}
+		if status.State == atlassian.StateError {
+			return "", retry.FatalError{Underlying: fmt.Errorf("%s failed to start", baseURL)}
+		}
+
+		return "", fmt.Errorf("%s is %s", baseURL, status.State)
+	})
+	require.NoError(t, err)
+
+	return status
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/backup"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// backupScript is where the backup script is copied to on the test host
+	backupScript = "/tmp/backup-databases.sh"
+	// BackupDir is where the dumps of the databases and the copies of the buckets are kept on the test host
+	BackupDir = "/root/backups"
+)
+
+// RunBackupScript runs backup.Script on the test host with the arguments, such as "backup", BackupDir and the
+// namespaces of the databases, and returns what it reported it did.
+func RunBackupScript(t *testing.T, platform *types.TestPlatform, args ...string) backup.Inventory {
+	t.Helper()
+	localScript := filepath.Join(platform.TestFolder, filepath.Base(backupScript))
+	require.NoError(t, os.WriteFile(localScript, backup.Script, 0600)) //nolint:gomnd
+	defer os.Remove(localScript)
+	require.NoError(t, platform.CopyFileOverScp(localScript, backupScript, 0644)) //nolint:gomnd
+	output, err := platform.RunSSHCommandAsSudo(fmt.Sprintf(`bash %s %s`, backupScript, strings.Join(args, " ")))
+	require.NoError(t, err, output)
+	inventory, err := backup.ParseInventory(output)
+	require.NoError(t, err)
+
+	return inventory
+}
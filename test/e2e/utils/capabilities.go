@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/tlsinspect"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// CapabilitiesFile maps the packages of the bundle to their readiness checks, relative to the root of the repo
+	CapabilitiesFile = "test/e2e/capabilities.yaml"
+	// readinessTimeout is how long each check waits for a capability to become ready
+	readinessTimeout = 20 * time.Minute
+)
+
+// capabilityMapping is the content of CapabilitiesFile.
+type capabilityMapping struct {
+	Packages map[string]struct {
+		// Capability is how the capability the package deploys is checked
+		Capability *types.Capability `yaml:"capability"`
+		// None is the reason the package isn't checked on its own
+		None string `yaml:"none"`
+	} `yaml:"packages"`
+}
+
+// LoadCapabilities returns the capabilities deployed by the packages in the bundle, in the order the bundle deploys
+// them. Every package of the bundle must be listed in CapabilitiesFile, either with its readiness check or with the
+// reason it doesn't have one, so that a package added to the bundle can't go unchecked.
+func LoadCapabilities(repoRoot string) ([]types.Capability, error) {
+	udsBundle, err := bundle.Read(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := os.ReadFile(filepath.Join(repoRoot, CapabilitiesFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", CapabilitiesFile, err)
+	}
+	mapping := new(capabilityMapping)
+	if err := yaml.Unmarshal(bytes, mapping); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", CapabilitiesFile, err)
+	}
+
+	var capabilities []types.Capability
+	var unmapped []string
+	for _, pkg := range udsBundle.Packages {
+		entry, ok := mapping.Packages[pkg.Name]
+		switch {
+		case !ok:
+			unmapped = append(unmapped, pkg.Name)
+		case (entry.Capability == nil) == (entry.None == ""):
+			return nil, fmt.Errorf("package %s in %s needs exactly one of capability or none", pkg.Name, CapabilitiesFile)
+		case entry.Capability != nil:
+			capabilities = append(capabilities, *entry.Capability)
+		}
+	}
+	if len(unmapped) > 0 {
+		return nil, fmt.Errorf("packages %s in %s have no readiness check in %s. Add a capability for them, or none with the reason they don't need one", strings.Join(unmapped, ", "), bundle.File, CapabilitiesFile)
+	}
+
+	var stale []string
+	for name := range mapping.Packages {
+		if _, ok := udsBundle.Package(name); !ok {
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+
+		return nil, fmt.Errorf("packages %s in %s are not in %s anymore", strings.Join(stale, ", "), CapabilitiesFile, bundle.File)
+	}
+
+	return capabilities, nil
+}
+
+// CheckCapabilities waits for the workloads of every capability to be ready, then sets up DNS for the cluster's ingress
+// gateways on the test host, and then checks that every capability is reachable through its ingress. DNS has to wait
+// for the workloads, since dns.sh only finds the gateways and virtual services that exist by then. Every capability is
+// checked in its own subtest, so one broken capability doesn't hide the state of the others.
+func CheckCapabilities(t *testing.T, platform *types.TestPlatform, domain string, capabilities []types.Capability) {
+	t.Helper()
+
+	ready := make(map[string]bool)
+	t.Run("ready", func(t *testing.T) {
+		for _, capability := range capabilities {
+			capability := capability
+			ready[capability.Name] = t.Run(capability.Name, func(t *testing.T) {
+				waitForCapability(t, platform, capability)
+			})
+		}
+	})
+
+	// Setup DNS records for cluster services
+	output, err := platform.RunSSHCommandAsSudo(`cd ~/app && rm -f hosts.patch && utils/metallb/dns.sh && utils/metallb/hosts-write.sh`)
+	require.NoError(t, err, output)
+
+	t.Run("reachable", func(t *testing.T) {
+		for _, capability := range capabilities {
+			capability := capability
+			if capability.Host == "" {
+				continue
+			}
+			t.Run(capability.Name, func(t *testing.T) {
+				if !ready[capability.Name] {
+					t.Skipf("The workloads of %s are not ready", capability.Name)
+				}
+				checkCapability(t, platform, domain, capability)
+			})
+		}
+	})
+}
+
+// waitForCapability waits for the workloads of the capability to be ready.
+func waitForCapability(t *testing.T, platform *types.TestPlatform, capability types.Capability) {
+	t.Helper()
+	kube, err := platform.Kubernetes()
+	require.NoError(t, err)
+	for _, workload := range capability.Workloads {
+		// Wait for the workload to report that it is ready
+		err := kube.WaitForWorkload(workload, readinessTimeout)
+		require.NoError(t, err)
+	}
+}
+
+// checkCapability makes sure the ingress of the capability meets the TLS policy, and waits for its health endpoint to
+// return the expected status.
+func checkCapability(t *testing.T, platform *types.TestPlatform, domain string, capability types.Capability) {
+	t.Helper()
+	host := fmt.Sprintf("%s.%s", capability.Host, domain)
+
+	// Ensure that the ingress only accepts current TLS versions and cipher suites, with a valid certificate for the host
+	report, err := tlsinspect.Inspect(context.Background(), platform.DialRemote, net.JoinHostPort(host, "443"), host)
+	require.NoError(t, err)
+	err = tlsinspect.DefaultPolicy().Check(report, domain, time.Now())
+	require.NoError(t, err)
+
+	// Ensure that the capability is available outside of the cluster.
+	err = WaitForHTTPStatus(t, platform.HTTPClient(), capability.URL(domain), capability.ExpectedStatus, readinessTimeout)
+	require.NoError(t, err)
+}
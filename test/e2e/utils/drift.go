@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/drift"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// coreDNS is the Deployment of CoreDNS, which software-factory-idam-dns restarts after it adds its hosts.
+var coreDNS = bundle.Resource{Kind: "Deployment", Namespace: "kube-system", Name: "coredns"}
+
+// RedeployBundle deploys the bundle that SetupTestPlatform built again, over the one that is already deployed.
+func RedeployBundle(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	output, err := platform.RunSSHCommandAsSudo(`cd ~/app && make deploy`)
+	require.NoError(t, err, output)
+}
+
+// SnapshotCluster returns the state of the workloads, secrets, config maps, services and persistent volume claims in
+// the namespaces, along with the Deployment of CoreDNS and its Corefile. The pods of a workload that are terminating
+// are left out, since they are on their way to being replaced.
+func SnapshotCluster(kube *types.Kubernetes, namespaces []string) (*drift.Snapshot, error) {
+	snapshot := drift.NewSnapshot()
+	for _, namespace := range namespaces {
+		objects, err := namespaceObjects(kube, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			snapshot.Add(object)
+		}
+	}
+	objects, err := namespaceObjects(kube, coreDNS.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range objects {
+		if object.Resource == coreDNS {
+			snapshot.Add(object)
+		}
+	}
+	configMap, err := kube.Clientset.CoreV1().ConfigMaps(drift.Corefile.Namespace).Get(context.Background(), drift.Corefile.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s: %w", drift.Corefile, err)
+	}
+	snapshot.Corefile = configMap.Data["Corefile"]
+
+	return snapshot, nil
+}
+
+// namespaceObjects returns the state of the resources in a namespace that SnapshotCluster looks at.
+func namespaceObjects(kube *types.Kubernetes, namespace string) ([]drift.Object, error) { //nolint:funlen
+	ctx := context.Background()
+	core := kube.Clientset.CoreV1()
+	apps := kube.Clientset.AppsV1()
+	var objects []drift.Object
+	add := func(kind string, meta metav1.ObjectMeta, hash string) {
+		objects = append(objects, drift.Object{
+			Resource:   bundle.Resource{Kind: kind, Namespace: namespace, Name: meta.Name},
+			UID:        string(meta.UID),
+			Generation: meta.Generation,
+			Hash:       hash,
+		})
+	}
+
+	secrets, err := core.Secrets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the secrets in %s: %w", namespace, err)
+	}
+	for _, secret := range secrets.Items {
+		add("Secret", secret.ObjectMeta, hashData(secret.Data))
+	}
+	configMaps, err := core.ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the config maps in %s: %w", namespace, err)
+	}
+	for _, configMap := range configMaps.Items {
+		data := make(map[string][]byte, len(configMap.Data)+len(configMap.BinaryData))
+		for key, value := range configMap.Data {
+			data[key] = []byte(value)
+		}
+		for key, value := range configMap.BinaryData {
+			data[key] = value
+		}
+		add("ConfigMap", configMap.ObjectMeta, hashData(data))
+	}
+	services, err := core.Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the services in %s: %w", namespace, err)
+	}
+	for _, service := range services.Items {
+		add("Service", service.ObjectMeta, "")
+	}
+	claims, err := core.PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the persistent volume claims in %s: %w", namespace, err)
+	}
+	for _, claim := range claims.Items {
+		add("PersistentVolumeClaim", claim.ObjectMeta, "")
+	}
+
+	deployments, err := apps.Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the deployments in %s: %w", namespace, err)
+	}
+	workloads := make(map[string]int)
+	for _, deployment := range deployments.Items {
+		workloads["Deployment/"+deployment.Name] = len(objects)
+		add("Deployment", deployment.ObjectMeta, "")
+	}
+	statefulSets, err := apps.StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the stateful sets in %s: %w", namespace, err)
+	}
+	for _, statefulSet := range statefulSets.Items {
+		workloads["StatefulSet/"+statefulSet.Name] = len(objects)
+		add("StatefulSet", statefulSet.ObjectMeta, "")
+	}
+	daemonSets, err := apps.DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the daemon sets in %s: %w", namespace, err)
+	}
+	for _, daemonSet := range daemonSets.Items {
+		workloads["DaemonSet/"+daemonSet.Name] = len(objects)
+		add("DaemonSet", daemonSet.ObjectMeta, "")
+	}
+
+	// Pods belong to a Deployment through a ReplicaSet
+	replicaSets, err := apps.ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the replica sets in %s: %w", namespace, err)
+	}
+	deploymentOf := make(map[string]string)
+	for _, replicaSet := range replicaSets.Items {
+		if owner := metav1.GetControllerOf(&replicaSet); owner != nil && owner.Kind == "Deployment" {
+			deploymentOf[replicaSet.Name] = owner.Name
+		}
+	}
+	pods, err := core.Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the pods in %s: %w", namespace, err)
+	}
+	podUIDs := make(map[int][]string)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		index, ok := workloads[podWorkload(pod, deploymentOf)]
+		if !ok || pod.DeletionTimestamp != nil {
+			continue
+		}
+		podUIDs[index] = append(podUIDs[index], string(pod.UID))
+		for _, status := range pod.Status.ContainerStatuses {
+			objects[index].Restarts += status.RestartCount
+		}
+	}
+	for index, uids := range podUIDs {
+		sort.Strings(uids)
+		objects[index].Pods = hashData(map[string][]byte{"uids": []byte(fmt.Sprint(uids))})
+	}
+
+	return objects, nil
+}
+
+// podWorkload returns the workload a pod belongs to, as its kind and name such as "StatefulSet/postgresql", or an
+// empty string if it doesn't belong to one, like the pods of jobs.
+func podWorkload(pod *corev1.Pod, deploymentOf map[string]string) string {
+	owner := metav1.GetControllerOf(pod)
+	switch {
+	case owner == nil:
+		return ""
+	case owner.Kind == "ReplicaSet" && deploymentOf[owner.Name] != "":
+		return "Deployment/" + deploymentOf[owner.Name]
+	case owner.Kind == "StatefulSet" || owner.Kind == "DaemonSet":
+		return owner.Kind + "/" + owner.Name
+	default:
+		return ""
+	}
+}
+
+// hashData returns the SHA-256 of data, with its keys in order.
+func hashData(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s=%d:", key, len(data[key]))
+		hash.Write(data[key])
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
@@ -0,0 +1,21 @@
+package utils
+
+// HostSteps exposes hostSteps to the tests of the package.
+var HostSteps = hostSteps
+
+// IsPlacementFailure exposes isPlacementFailure to the tests of the package.
+var IsPlacementFailure = isPlacementFailure
+
+// Placements returns what getPlacements returns as instance type and availability zone pairs, such as
+// "m6i.12xlarge us-east-1c".
+func Placements(awsRegion string) []string {
+	var placements []string
+	for _, candidate := range getPlacements(awsRegion) {
+		placements = append(placements, candidate.InstanceType+" "+candidate.AvailabilityZone)
+	}
+
+	return placements
+}
+
+// DeregisterGoldenImages exposes deregisterGoldenImages to the tests of the package.
+var DeregisterGoldenImages = deregisterGoldenImages
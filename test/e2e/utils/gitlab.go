@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/browser"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/gitlab"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// gitlabNamespace is where GitLab is deployed
+	gitlabNamespace = "gitlab"
+	// gitlabRootPasswordSecret holds the password GitLab gave its root user
+	gitlabRootPasswordSecret = "gitlab-gitlab-initial-root-password"
+	// pipelineRetryInterval is how long WaitForGitLabPipeline waits between checks
+	pipelineRetryInterval = 10 * time.Second
+)
+
+// GitLabRootSession signs in to GitLab as root with its password, which GitLab keeps in a secret, and returns the
+// browser with the session.
+func GitLabRootSession(t *testing.T, platform *types.TestPlatform, domain string) *browser.Browser {
+	t.Helper()
+	kube, err := platform.Kubernetes()
+	require.NoError(t, err)
+	secret, err := kube.Clientset.CoreV1().Secrets(gitlabNamespace).Get(context.Background(), gitlabRootPasswordSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	session, err := browser.New(platform.HTTPClient())
+	require.NoError(t, err)
+	page, err := session.Get(fmt.Sprintf("https://gitlab.%s/users/sign_in", domain))
+	require.NoError(t, err)
+	form, ok := page.FormWithField("user[password]")
+	require.True(t, ok, "GitLab's sign in page has no password form")
+	page, err = session.Submit(form, map[string]string{"user[login]": "root", "user[password]": string(secret.Data["password"])}, "")
+	require.NoError(t, err)
+	require.NotEqual(t, "/users/sign_in", page.URL.Path, "GitLab rejected the root password")
+
+	return session
+}
+
+// GitLabAdmin returns a client for the GitLab API with a personal access token of root that can use the API and push
+// to repositories. The token is revoked again when the test finishes, and expires after two days if that fails.
+func GitLabAdmin(t *testing.T, platform *types.TestPlatform, domain string) *gitlab.Client {
+	t.Helper()
+	gitlabURL := fmt.Sprintf("https://gitlab.%s", domain)
+	session := GitLabRootSession(t, platform, domain)
+
+	// The API accepts the session for changes too, as long as it comes with the CSRF token of a page
+	page, err := session.Get(gitlabURL + "/")
+	require.NoError(t, err)
+	csrfToken := page.Meta("csrf-token")
+	require.NotEmpty(t, csrfToken, "GitLab's dashboard has no CSRF token")
+	var root struct {
+		ID int `json:"id"`
+	}
+	page, err = session.Get(gitlabURL + "/api/v4/user")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, page.StatusCode, string(page.Body))
+	require.NoError(t, json.Unmarshal(page.Body, &root))
+
+	body, err := json.Marshal(map[string]interface{}{
+		"name":       "e2e-" + strings.ToLower(random.UniqueId()),
+		"scopes":     []string{"api", "write_repository"},
+		"expires_at": time.Now().UTC().AddDate(0, 0, 2).Format("2006-01-02"), //nolint:gomnd
+	})
+	require.NoError(t, err)
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/v4/users/%d/personal_access_tokens", gitlabURL, root.ID), strings.NewReader(string(body))) //nolint:noctx
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-CSRF-Token", csrfToken)
+	page, err = session.Do(request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, page.StatusCode, string(page.Body))
+	var token struct {
+		Token string `json:"token"`
+	}
+	require.NoError(t, json.Unmarshal(page.Body, &token))
+
+	client := gitlab.NewClient(platform.HTTPClient(), gitlabURL, token.Token)
+	t.Cleanup(func() {
+		if err := client.RevokeToken(); err != nil {
+			t.Errorf("unable to revoke gitlab test token: %v", err)
+		}
+	})
+
+	return client
+}
+
+// CreateGitLabTestProject creates an empty project with a unique name, and deletes it again when the test finishes.
+func CreateGitLabTestProject(t *testing.T, client *gitlab.Client) *gitlab.Project {
+	t.Helper()
+	project, err := client.CreateProject("e2e-" + strings.ToLower(random.UniqueId()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := client.DeleteProject(project.ID); err != nil {
+			t.Errorf("unable to delete gitlab test project %s: %v", project.PathWithNamespace, err)
+		}
+	})
+
+	return project
+}
+
+// PushToGitLab commits files, by their path in the repository, to the main branch of project from the test host with
+// git over HTTPS, authenticating with the client's token. It returns the SHA of the commit. The push runs from a
+// script copied to the host, so the token doesn't end up in the log of SSH commands.
+func PushToGitLab(t *testing.T, platform *types.TestPlatform, client *gitlab.Client, project *gitlab.Project, files map[string][]byte) string {
+	t.Helper()
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var script strings.Builder
+	script.WriteString("set -euo pipefail\nrepo=$(mktemp -d)\ntrap 'rm -rf \"$repo\" \"$0\"' EXIT\ncd \"$repo\"\ngit init -q\ngit checkout -q -b main\n")
+	for _, path := range paths {
+		fmt.Fprintf(&script, "mkdir -p \"$(dirname %q)\"\necho %s | base64 -d > %q\n", path, base64.StdEncoding.EncodeToString(files[path]), path)
+	}
+	remote := strings.Replace(project.HTTPURLToRepo, "https://", fmt.Sprintf("https://oauth2:%s@", client.Token()), 1)
+	fmt.Fprintf(&script, "git add -A\ngit -c user.name=E2E -c user.email=e2e@bigbang.dev commit -q -m \"Add e2e test files\"\ngit push -q %q main\ngit rev-parse HEAD\n", remote)
+
+	remoteScript := CopySecretScript(t, platform, "push", []byte(script.String()))
+	output, err := platform.RunSSHCommand("bash " + remoteScript)
+	require.NoError(t, err, output)
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// WaitForGitLabPipeline waits until the pipeline GitLab started for a commit of the project has finished, and returns
+// it, whether it succeeded or not.
+func WaitForGitLabPipeline(t *testing.T, client *gitlab.Client, project *gitlab.Project, sha string, timeout time.Duration) *gitlab.Pipeline {
+	t.Helper()
+	var pipeline gitlab.Pipeline
+	maxRetries := int(timeout / pipelineRetryInterval)
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("Wait for the pipeline of %s at %s to finish", project.PathWithNamespace, sha), maxRetries, pipelineRetryInterval, func() (string, error) {
+		pipelines, err := client.Pipelines(project.ID, sha)
+		if err != nil {
+			return "", err
+		}
+		if len(pipelines) == 0 {
+			return "", fmt.Errorf("no pipeline has started for %s", sha)
+		}
+		pipeline = pipelines[0]
+		if !pipeline.Finished() {
+			return "", fmt.Errorf("pipeline %d is %s", pipeline.ID, pipeline.Status)
+		}
+
+		return "", nil
+	})
+	require.NoError(t, err)
+
+	return &pipeline
+}
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/tools"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	terratestaws "github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	teststructure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// goldenImageHashTag is the tag on a golden image that holds the ImageHash of the host steps it was built from
+	goldenImageHashTag = "uds-swf/host-steps-hash"
+	// goldenImageNamePrefix is the prefix of the name of every golden image
+	goldenImageNamePrefix = "uds-swf-terratest-golden"
+)
+
+// BuildGoldenImage uses Terratest to create an EC2 instance, runs the same host steps that SetupTestPlatform uses on
+// it, and then creates an AMI from the instance tagged with the hash of those steps. Set env var GOLDEN_IMAGE to "yes"
+// to have SetupTestPlatform launch from the image. The instance itself is removed by the platform's Teardown, the
+// image is kept. Once the image is available, every other golden image is deregistered along with its snapshots,
+// since SetupTestPlatform only ever launches from the newest image for the current host steps.
+func BuildGoldenImage(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	awsRegion, err := getAwsRegion()
+	require.NoError(t, err)
+	pinnedTools, err := tools.Resolve(platform.RepoRoot)
+	require.NoError(t, err)
+	steps := hostSteps(pinnedTools)
+	hash := ImageHash(steps)
+	teststructure.RunTestStage(t, "SETUP", func() {
+		provisionInstance(t, platform, awsRegion, "")
+		runHostSteps(t, platform, steps)
+
+		// Don't bake downloads or apt caches into the image
+		output, err := platform.RunSSHCommandAsSudo(`rm -rf /tmp/tools && apt clean`)
+		require.NoError(t, err, output)
+	})
+	teststructure.RunTestStage(t, "IMAGE", func() {
+		terraformOptions := teststructure.LoadTerraformOptions(t, platform.TestFolder)
+		instanceID := terraform.Output(t, terraformOptions, "public_instance_id")
+		client := terratestaws.NewEc2Client(t, awsRegion)
+		name := fmt.Sprintf("%s-%s-%s", goldenImageNamePrefix, hash, strings.ToLower(random.UniqueId()))
+		tags := []*ec2.Tag{
+			{Key: aws.String("Name"), Value: aws.String(name)},
+			{Key: aws.String(goldenImageHashTag), Value: aws.String(hash)},
+		}
+		image, err := client.CreateImage(&ec2.CreateImageInput{
+			InstanceId:  aws.String(instanceID),
+			Name:        aws.String(name),
+			Description: aws.String("Pre-baked host for the UDS Software Factory e2e tests"),
+			TagSpecifications: []*ec2.TagSpecification{
+				{ResourceType: aws.String(ec2.ResourceTypeImage), Tags: tags},
+				{ResourceType: aws.String(ec2.ResourceTypeSnapshot), Tags: tags},
+			},
+		})
+		require.NoError(t, err)
+		logger.Default.Logf(t, "Waiting for golden image %s (%s) to become available", aws.StringValue(image.ImageId), name)
+		err = client.WaitUntilImageAvailable(&ec2.DescribeImagesInput{ImageIds: []*string{image.ImageId}})
+		require.NoError(t, err)
+		logger.Default.Logf(t, "Golden image %s is available for host steps hash %s", aws.StringValue(image.ImageId), hash)
+
+		removed, err := deregisterGoldenImages(client, aws.StringValue(image.ImageId))
+		if len(removed) > 0 {
+			logger.Default.Logf(t, "Deregistered superseded golden images %s", strings.Join(removed, ", "))
+		}
+		require.NoError(t, err)
+	})
+}
+
+// findGoldenImage returns the ID of the newest golden image built from host steps with the given hash. Golden images
+// built from different host steps are stale, and finding only those is an error so that they are never silently used.
+func findGoldenImage(t *testing.T, awsRegion string, hash string) (string, error) {
+	t.Helper()
+	client, err := terratestaws.NewEc2ClientE(t, awsRegion)
+	if err != nil {
+		return "", fmt.Errorf("unable to create ec2 client: %w", err)
+	}
+	images, err := goldenImages(client, ec2.ImageStateAvailable)
+	if err != nil {
+		return "", err
+	}
+
+	var matching []*ec2.Image
+	var stale []string
+	for _, image := range images {
+		imageHash := ""
+		for _, tag := range image.Tags {
+			if aws.StringValue(tag.Key) == goldenImageHashTag {
+				imageHash = aws.StringValue(tag.Value)
+			}
+		}
+		if imageHash == hash {
+			matching = append(matching, image)
+		} else {
+			stale = append(stale, fmt.Sprintf("%s (%s)", aws.StringValue(image.ImageId), imageHash))
+		}
+	}
+	if len(matching) == 0 {
+		if len(stale) > 0 {
+			return "", fmt.Errorf("golden images %v are stale, none were built for host steps hash %s. Run `make test-golden-image` to build a new one", strings.Join(stale, ", "), hash)
+		}
+
+		return "", fmt.Errorf("no golden image found for host steps hash %s. Run `make test-golden-image` to build one", hash)
+	}
+
+	sort.Slice(matching, func(i, j int) bool {
+		created1, _ := time.Parse(time.RFC3339, aws.StringValue(matching[i].CreationDate))
+		created2, _ := time.Parse(time.RFC3339, aws.StringValue(matching[j].CreationDate))
+
+		return created1.After(created2)
+	})
+
+	return aws.StringValue(matching[0].ImageId), nil
+}
+
+// goldenImages returns the golden images owned by the account that are in one of the given states.
+func goldenImages(client ec2iface.EC2API, states ...string) ([]*ec2.Image, error) {
+	output, err := client.DescribeImages(&ec2.DescribeImagesInput{
+		Owners: []*string{aws.String("self")},
+		Filters: []*ec2.Filter{
+			{Name: aws.String("name"), Values: []*string{aws.String(goldenImageNamePrefix + "-*")}},
+			{Name: aws.String("state"), Values: aws.StringSlice(states)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list golden images: %w", err)
+	}
+
+	return output.Images, nil
+}
+
+// deregisterGoldenImages deregisters every golden image except the one with ID keep, and deletes the snapshots that
+// back them, since AWS keeps charging for those after the image is gone. It returns the IDs of the images it
+// deregistered, also when it fails part way.
+func deregisterGoldenImages(client ec2iface.EC2API, keep string) ([]string, error) {
+	images, err := goldenImages(client, ec2.ImageStateAvailable, ec2.ImageStateFailed, ec2.ImageStateError)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, image := range images {
+		imageID := aws.StringValue(image.ImageId)
+		if imageID == keep {
+			continue
+		}
+		if _, err := client.DeregisterImage(&ec2.DeregisterImageInput{ImageId: image.ImageId}); err != nil {
+			return removed, fmt.Errorf("unable to deregister golden image %s: %w", imageID, err)
+		}
+		removed = append(removed, imageID)
+		for _, mapping := range image.BlockDeviceMappings {
+			if mapping.Ebs == nil || mapping.Ebs.SnapshotId == nil {
+				continue
+			}
+			if _, err := client.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: mapping.Ebs.SnapshotId}); err != nil {
+				return removed, fmt.Errorf("unable to delete snapshot %s of golden image %s: %w", aws.StringValue(mapping.Ebs.SnapshotId), imageID, err)
+			}
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,59 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEC2 is a local stand-in for the EC2 API that holds a fixed set of images and records what gets deleted.
+type fakeEC2 struct {
+	ec2iface.EC2API
+	images  []*ec2.Image
+	deleted []string
+}
+
+func (fake *fakeEC2) DescribeImages(*ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+	return &ec2.DescribeImagesOutput{Images: fake.images}, nil
+}
+
+func (fake *fakeEC2) DeregisterImage(input *ec2.DeregisterImageInput) (*ec2.DeregisterImageOutput, error) {
+	fake.deleted = append(fake.deleted, aws.StringValue(input.ImageId))
+
+	return &ec2.DeregisterImageOutput{}, nil
+}
+
+func (fake *fakeEC2) DeleteSnapshot(input *ec2.DeleteSnapshotInput) (*ec2.DeleteSnapshotOutput, error) {
+	fake.deleted = append(fake.deleted, aws.StringValue(input.SnapshotId))
+
+	return &ec2.DeleteSnapshotOutput{}, nil
+}
+
+// goldenImage returns an image backed by the given snapshot.
+func goldenImage(imageID string, snapshotID string) *ec2.Image {
+	return &ec2.Image{
+		ImageId: aws.String(imageID),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+			{DeviceName: aws.String("/dev/sda1"), Ebs: &ec2.EbsBlockDevice{SnapshotId: aws.String(snapshotID)}},
+			{DeviceName: aws.String("/dev/sdb"), VirtualName: aws.String("ephemeral0")},
+		},
+	}
+}
+
+func TestDeregisterGoldenImages(t *testing.T) {
+	t.Parallel()
+	fake := &fakeEC2{images: []*ec2.Image{
+		goldenImage("ami-stale", "snap-stale"),
+		goldenImage("ami-new", "snap-new"),
+		goldenImage("ami-older", "snap-older"),
+	}}
+
+	removed, err := utils.DeregisterGoldenImages(fake, "ami-new")
+	require.NoError(t, err)
+	require.Equal(t, []string{"ami-stale", "ami-older"}, removed)
+	require.Equal(t, []string{"ami-stale", "snap-stale", "ami-older", "snap-older"}, fake.deleted)
+}
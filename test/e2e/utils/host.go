@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/tools"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// HostStep is one piece of preparing a fresh Ubuntu instance to run the software factory. The same steps are used by
+// SetupTestPlatform and when building a golden image, so an instance launched from a golden image can skip them.
+type HostStep struct {
+	// Name describes the step in the test logs
+	Name string
+	// Commands are run in order, as root, to perform the step
+	Commands []string
+	// Check is run as root before the step and succeeds if the step has already been done
+	Check string
+}
+
+// hostSteps returns the steps that install everything SetupTestPlatform needs on the instance before the repo is cloned.
+func hostSteps(pinnedTools []tools.Tool) []HostStep {
+	steps := []HostStep{
+		{
+			Name: "Install Docker",
+			Commands: []string{
+				// Install Docker Dependencies
+				`apt install -y ca-certificates curl gnupg lsb-release`,
+				// Add Docker GPG Key
+				`mkdir -m 0755 -p /etc/apt/keyrings && curl -fsSL https://download.docker.com/linux/ubuntu/gpg | gpg --dearmor --yes -o /etc/apt/keyrings/docker.gpg`,
+				// Setup Docker APT Repo
+				`echo "deb [arch=$(dpkg --print-architecture) signed-by=/etc/apt/keyrings/docker.gpg] https://download.docker.com/linux/ubuntu $(lsb_release -cs) stable" | tee /etc/apt/sources.list.d/docker.list > /dev/null`,
+				// Update APT repos including new docker repo
+				`apt update -y`,
+				// Install Docker
+				`apt install -y docker-ce docker-ce-cli containerd.io docker-buildx-plugin docker-compose-plugin`,
+			},
+			Check: `dpkg -s docker-ce docker-ce-cli containerd.io docker-buildx-plugin docker-compose-plugin > /dev/null`,
+		},
+	}
+
+	// Download, verify and install the pinned versions of k3d, kubectl, zarf and uds
+	for _, tool := range pinnedTools {
+		steps = append(steps, HostStep{
+			Name:     fmt.Sprintf("Install %v %v", tool.Name, tool.Version),
+			Commands: []string{tool.InstallCommand()},
+			Check:    tool.CheckCommand(),
+		})
+	}
+
+	// Install dependencies. Doing it here since the instance user-data is being flaky, still saying things like make are not installed
+	steps = append(steps, HostStep{
+		Name:     "Install dependencies",
+		Commands: []string{`apt update && apt install -y jq git make wget`},
+		Check:    `dpkg -s jq git make wget > /dev/null`,
+	})
+
+	return steps
+}
+
+// ImageHash identifies a set of host steps. Golden images are tagged with the hash of the steps they were built from,
+// so any change to a tool version, checksum, or install command means older images are detected as stale.
+func ImageHash(steps []HostStep) string {
+	hash := sha256.New()
+	for _, step := range steps {
+		fmt.Fprintf(hash, "%v\n", step.Name)
+		for _, command := range step.Commands {
+			fmt.Fprintf(hash, "%v\n", command)
+		}
+		fmt.Fprintf(hash, "%v\n", step.Check)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// runHostSteps runs every step that hasn't already been done on the instance.
+func runHostSteps(t *testing.T, platform *types.TestPlatform, steps []HostStep) {
+	t.Helper()
+	for _, step := range steps {
+		if _, err := platform.RunSSHCommandAsSudo(step.Check); err == nil {
+			logger.Default.Logf(t, "Skipping host step %q, it has already been done", step.Name)
+			continue
+		}
+		logger.Default.Logf(t, "Running host step %q", step.Name)
+		for _, command := range step.Commands {
+			output, err := platform.RunSSHCommandAsSudo(command)
+			require.NoError(t, err, output)
+		}
+	}
+}
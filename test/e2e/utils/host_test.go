@@ -0,0 +1,53 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/tools"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedTools are the tools of a lock file.
+func lockedTools() []tools.Tool {
+	return []tools.Tool{
+		{Name: "k3d", Version: "v5.6.0", URL: "https://example.com/k3d", SHA256: "aaa", Binary: "k3d"},
+		{Name: "zarf", Version: "v0.31.4", URL: "https://example.com/zarf", SHA256: "bbb", Binary: "zarf"},
+	}
+}
+
+func TestImageHash(t *testing.T) {
+	t.Parallel()
+	hash := utils.ImageHash(utils.HostSteps(lockedTools()))
+	require.Len(t, hash, 16)
+	require.Equal(t, hash, utils.ImageHash(utils.HostSteps(lockedTools())), "the same lock file gave another hash")
+
+	changedChecksum := lockedTools()
+	changedChecksum[1].SHA256 = "ccc"
+	require.NotEqual(t, hash, utils.ImageHash(utils.HostSteps(changedChecksum)), "a new checksum kept the hash")
+	changedVersion := lockedTools()
+	changedVersion[0].Version = "v5.6.1"
+	require.NotEqual(t, hash, utils.ImageHash(utils.HostSteps(changedVersion)), "a new version kept the hash")
+	require.NotEqual(t, hash, utils.ImageHash(utils.HostSteps(lockedTools()[:1])), "a removed tool kept the hash")
+
+	steps := utils.HostSteps(lockedTools())
+	steps[0].Commands = append(steps[0].Commands, "apt install -y htop")
+	require.NotEqual(t, hash, utils.ImageHash(steps), "a new command kept the hash")
+	steps = utils.HostSteps(lockedTools())
+	steps[len(steps)-1].Check = "true"
+	require.NotEqual(t, hash, utils.ImageHash(steps), "a new check kept the hash")
+}
+
+func TestHostSteps(t *testing.T) {
+	t.Parallel()
+	steps := utils.HostSteps(lockedTools())
+	var names []string
+	for _, step := range steps {
+		require.NotEmpty(t, step.Commands, step.Name)
+		require.NotEmpty(t, step.Check, step.Name)
+		names = append(names, step.Name)
+	}
+	require.Equal(t, []string{"Install Docker", "Install k3d v5.6.0", "Install zarf v0.31.4", "Install dependencies"}, names)
+	require.Contains(t, steps[1].Commands[0], "aaa")
+	require.Contains(t, steps[2].Check, "bbb")
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+)
+
+const (
+	// httpRequestTimeout is how long a single request made by WaitForHTTPStatus may take
+	httpRequestTimeout = 30 * time.Second
+	// httpRetryInterval is how long WaitForHTTPStatus waits between requests
+	httpRetryInterval = 5 * time.Second
+)
+
+// WaitForHTTPStatus requests url with client until it returns the expected status code, following redirects. Use the
+// client returned by the platform's HTTPClient to reach services through the test host.
+func WaitForHTTPStatus(t *testing.T, client *http.Client, url string, expected int, timeout time.Duration) error {
+	t.Helper()
+	timeoutClient := *client
+	timeoutClient.Timeout = httpRequestTimeout
+	maxRetries := int(timeout / httpRetryInterval)
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("Wait for %s to return HTTP %d", url, expected), maxRetries, httpRetryInterval, func() (string, error) {
+		response, err := timeoutClient.Get(url) //nolint:noctx
+		if err != nil {
+			return "", fmt.Errorf("unable to get %s: %w", url, err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != expected {
+			return "", fmt.Errorf("%s returned HTTP %d", url, response.StatusCode)
+		}
+
+		return "", nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s never returned HTTP %d: %w", url, expected, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/keycloak"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// keycloakNamespace is where uds-idam deploys Keycloak
+	keycloakNamespace = "keycloak"
+	// keycloakAdminSecret holds the environment of Keycloak, which includes the credentials of its admin user
+	keycloakAdminSecret = "keycloak-env"
+	// RealmFile is the realm software-factory-idam-realm has Keycloak import, relative to the root of the repo
+	RealmFile = "packages/idam-realm/files/baby-yoda.json"
+	// domainVariable is replaced with the domain in RealmFile when it is deployed
+	domainVariable = "###ZARF_VAR_DOMAIN###"
+)
+
+// KeycloakAdmin returns a client for the Keycloak admin API, logged in as the admin user whose credentials the
+// Keycloak chart keeps in a secret.
+func KeycloakAdmin(t *testing.T, platform *types.TestPlatform, domain string) *keycloak.Client {
+	t.Helper()
+	kube, err := platform.Kubernetes()
+	require.NoError(t, err)
+	secret, err := kube.Clientset.CoreV1().Secrets(keycloakNamespace).Get(context.Background(), keycloakAdminSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	return keycloak.NewClient(platform.HTTPClient(), fmt.Sprintf("https://keycloak.%s", domain), string(secret.Data["KEYCLOAK_ADMIN"]), string(secret.Data["KEYCLOAK_ADMIN_PASSWORD"]))
+}
+
+// KeycloakFixtures returns fixtures in the realm the capabilities use, created as the Keycloak admin user, that are
+// deleted again when the test finishes.
+func KeycloakFixtures(t *testing.T, platform *types.TestPlatform, domain string) *keycloak.Fixtures {
+	t.Helper()
+	fixtures := KeycloakAdmin(t, platform, domain).Fixtures(keycloak.Realm)
+	t.Cleanup(func() {
+		if err := fixtures.Cleanup(); err != nil {
+			t.Errorf("unable to clean up keycloak fixtures: %v", err)
+		}
+	})
+
+	return fixtures
+}
+
+// CreateKeycloakTestUser creates a user in the realm that is allowed to log in to every capability, and deletes it
+// again when the test finishes. It returns the user and its password.
+func CreateKeycloakTestUser(t *testing.T, platform *types.TestPlatform, domain string) (keycloak.User, string) {
+	t.Helper()
+	fixtures := KeycloakFixtures(t, platform, domain)
+	username := "e2e-" + strings.ToLower(random.UniqueId())
+	// The realm requires 12 characters, 2 of them special
+	password := fmt.Sprintf("%s!%s#%s", random.UniqueId(), random.UniqueId(), random.UniqueId())
+	user := keycloak.User{
+		Username:      username,
+		Email:         fmt.Sprintf("%s@%s", username, domain),
+		FirstName:     "E2E",
+		LastName:      username,
+		Enabled:       true,
+		EmailVerified: true,
+		Credentials:   []keycloak.Credential{{Type: "password", Value: password}},
+	}
+	id, err := fixtures.CreateUser(user)
+	require.NoError(t, err)
+	user.ID = id
+	err = fixtures.Client().AddUserToGroup(keycloak.Realm, id, keycloak.AuthorizedGroupID)
+	require.NoError(t, err)
+
+	return user, password
+}
+
+// ExpectedRealm reads RealmFile the way Keycloak gets it when the bundle is deployed for domain, for comparing with
+// the realm Keycloak imported.
+func ExpectedRealm(repoRoot string, domain string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(filepath.Join(repoRoot, RealmFile))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", RealmFile, err)
+	}
+	var realm map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.ReplaceAll(string(content), domainVariable, domain)), &realm); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", RealmFile, err)
+	}
+
+	return realm, nil
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/mattermost"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+)
+
+// mattermostNamespace is where the Mattermost operator deploys Mattermost
+const mattermostNamespace = "mattermost"
+
+// mattermostLocalModeEnv turns on local mode of Mattermost, which lets mmctl talk to the server over a socket in its pod.
+const mattermostLocalModeEnv = `{"name":"MM_SERVICESETTINGS_ENABLELOCALMODE","value":"true"}`
+
+// mattermostLocalModeTimeout is how long the Mattermost operator gets to roll out Mattermost with local mode turned on.
+const mattermostLocalModeTimeout = 10 * time.Minute
+
+// mattermostLocalModeRetryInterval is how often mmctl is tried while Mattermost restarts with local mode turned on.
+const mattermostLocalModeRetryInterval = 10 * time.Second
+
+// MattermostAdmin creates a system admin of Mattermost and returns a client logged in as it. The admin is created with
+// mmctl in local mode, since nobody can sign up through the API while sign up is limited to SSO. The password is passed
+// in a script copied to the host, so it doesn't end up in the log of SSH commands. The admin is deactivated again when
+// the test finishes.
+func MattermostAdmin(t *testing.T, platform *types.TestPlatform, domain string) *mattermost.Client {
+	t.Helper()
+	enableMattermostLocalMode(t, platform)
+
+	username := "e2e-admin-" + strings.ToLower(random.UniqueId())
+	password := MattermostTestPassword()
+	script := fmt.Sprintf("set -euo pipefail\ntrap 'rm -f \"$0\"' EXIT\nkubectl exec -n %s deploy/mattermost -- mmctl --local user create --email %s@%s --username %s --password %q --system-admin\n", mattermostNamespace, username, domain, username, password)
+	output, err := platform.RunSSHCommandAsSudo("bash " + CopySecretScript(t, platform, "mmctl", []byte(script)))
+	require.NoError(t, err, output)
+
+	admin, err := mattermost.Login(platform.HTTPClient(), "https://chat."+domain, username, password)
+	require.NoError(t, err)
+	me, err := admin.Me()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := admin.DeactivateUser(me.ID); err != nil {
+			t.Errorf("unable to deactivate mattermost test admin %s: %v", username, err)
+		}
+	})
+
+	return admin
+}
+
+// enableMattermostLocalMode makes sure mmctl can reach Mattermost in local mode. The mattermost package doesn't turn on
+// local mode, so it is added to the environment of the Mattermost resource, and the operator rolls out Mattermost with
+// it. Local mode only listens on a socket inside the pod, so it stays on for the rest of the run.
+func enableMattermostLocalMode(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	probe := fmt.Sprintf(`kubectl exec -n %s deploy/mattermost -- mmctl --local system version`, mattermostNamespace)
+	if _, err := platform.RunSSHCommandAsSudo(probe); err == nil {
+		return
+	}
+
+	name, err := platform.RunSSHCommandAsSudo(fmt.Sprintf(`kubectl get mattermost -n %s -o name`, mattermostNamespace))
+	require.NoError(t, err, name)
+	name = strings.TrimSpace(name)
+	require.NotEmpty(t, name, "no Mattermost resource in namespace %s", mattermostNamespace)
+	env, err := platform.RunSSHCommandAsSudo(fmt.Sprintf(`kubectl get -n %s %s -o jsonpath={.spec.mattermostEnv}`, mattermostNamespace, name))
+	require.NoError(t, err, env)
+	// A JSON patch can only append to a list that exists.
+	patch := fmt.Sprintf(`[{"op":"add","path":"/spec/mattermostEnv/-","value":%s}]`, mattermostLocalModeEnv)
+	if strings.TrimSpace(env) == "" {
+		patch = fmt.Sprintf(`[{"op":"add","path":"/spec/mattermostEnv","value":[%s]}]`, mattermostLocalModeEnv)
+	}
+	output, err := platform.RunSSHCommandAsSudo(fmt.Sprintf(`kubectl patch -n %s %s --type json -p %q`, mattermostNamespace, name, patch))
+	require.NoError(t, err, output)
+
+	maxRetries := int(mattermostLocalModeTimeout / mattermostLocalModeRetryInterval)
+	_, err = retry.DoWithRetryE(t, "Wait for mmctl to reach Mattermost in local mode", maxRetries, mattermostLocalModeRetryInterval, func() (string, error) {
+		return platform.RunSSHCommandAsSudo(probe)
+	})
+	require.NoError(t, err)
+}
+
+// MattermostTestPassword returns a random password that meets the password requirements of Mattermost.
+func MattermostTestPassword() string {
+	return fmt.Sprintf("%s!%s#%s", random.UniqueId(), strings.ToLower(random.UniqueId()), random.UniqueId())
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/nexus"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// nexusNamespace is where Nexus is deployed
+	nexusNamespace = "nexus"
+	// nexusAdminSecret holds the password the Nexus chart gave the admin user
+	nexusAdminSecret = "nexus-repository-manager-secret"
+)
+
+// NexusAdmin returns a client for Nexus that authenticates as the admin user, whose password the Nexus chart keeps in
+// a secret.
+func NexusAdmin(t *testing.T, platform *types.TestPlatform, domain string) *nexus.Client {
+	t.Helper()
+	kube, err := platform.Kubernetes()
+	require.NoError(t, err)
+	secret, err := kube.Clientset.CoreV1().Secrets(nexusNamespace).Get(context.Background(), nexusAdminSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	return nexus.NewClient(platform.HTTPClient(), fmt.Sprintf("https://nexus.%s", domain), "admin", string(secret.Data["admin.password"]))
+}
+
+// CreateNexusTestRepository creates a hosted repository with a unique name with create, such as
+// CreateRawHostedRepository of the client, and deletes it again when the test finishes. It returns the name.
+func CreateNexusTestRepository(t *testing.T, client *nexus.Client, create func(name string) error) string {
+	t.Helper()
+	name := "e2e-" + strings.ToLower(random.UniqueId())
+	require.NoError(t, create(name))
+	t.Cleanup(func() {
+		if err := client.DeleteRepository(name); err != nil {
+			t.Errorf("unable to delete nexus test repository %s: %v", name, err)
+		}
+	})
+
+	return name
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultInstanceTypes are the instance types tried, in order, when env var AWS_INSTANCE_TYPES isn't set. They all
+// have the 48 vCPUs and 192 GiB of memory the software factory needs.
+var defaultInstanceTypes = []string{"m6i.12xlarge", "m6id.12xlarge", "m5.12xlarge"}
+
+// defaultAvailabilityZones are the zone letters tried, in order, when neither AWS_AVAILABILITY_ZONES nor
+// AWS_AVAILABILITY_ZONE is set.
+var defaultAvailabilityZones = []string{"c", "b", "a"}
+
+// placementFailures are the errors from AWS that mean the instance couldn't be placed with a particular instance type
+// and availability zone, but might be placed with another. They are matched on the error code AWS puts in front of its
+// message, so that errors of Terraform itself, such as "Unsupported argument", fail right away instead.
+var placementFailures = []string{
+	"InsufficientInstanceCapacity:",
+	"Unsupported:",
+	"is not supported in your requested Availability Zone",
+	"for parameter availabilityZone is invalid",
+}
+
+// placement is an instance type and availability zone to try launching the test instance in.
+type placement struct {
+	InstanceType     string
+	AvailabilityZone string
+}
+
+// getPlacements returns the instance type and availability zone combinations to try, in order. Every availability zone
+// is tried with the first instance type before moving on to the next instance type.
+func getPlacements(awsRegion string) []placement {
+	instanceTypes := getListEnvVar("AWS_INSTANCE_TYPES", defaultInstanceTypes)
+	zones := getAwsAvailabilityZones(awsRegion)
+	placements := make([]placement, 0, len(instanceTypes)*len(zones))
+	for _, instanceType := range instanceTypes {
+		for _, zone := range zones {
+			placements = append(placements, placement{InstanceType: instanceType, AvailabilityZone: zone})
+		}
+	}
+
+	return placements
+}
+
+// getAwsAvailabilityZones returns the AWS Availability Zones to try, in order. They are read as a comma separated list
+// of zone letters from env var AWS_AVAILABILITY_ZONES, then from the single zone letter in env var
+// AWS_AVAILABILITY_ZONE. We default to {awsRegion}c, {awsRegion}b, then {awsRegion}a if neither is specified.
+func getAwsAvailabilityZones(awsRegion string) []string {
+	letters := defaultAvailabilityZones
+	if zoneLetter, present := os.LookupEnv("AWS_AVAILABILITY_ZONE"); present && zoneLetter != "" {
+		letters = []string{zoneLetter}
+	}
+	letters = getListEnvVar("AWS_AVAILABILITY_ZONES", letters)
+	zones := make([]string, 0, len(letters))
+	for _, letter := range letters {
+		zones = append(zones, fmt.Sprintf("%s%s", awsRegion, letter))
+	}
+
+	return zones
+}
+
+// isPlacementFailure returns true if the error means the instance type isn't available in the availability zone right now.
+func isPlacementFailure(err error) bool {
+	for _, failure := range placementFailures {
+		if strings.Contains(err.Error(), failure) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getListEnvVar returns the comma separated values of an environment variable, or the default if it isn't set.
+func getListEnvVar(varName string, defaultValue []string) []string {
+	val, present := os.LookupEnv(varName)
+	if !present || strings.TrimSpace(val) == "" {
+		return defaultValue
+	}
+	var values []string
+	for _, value := range strings.Split(val, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
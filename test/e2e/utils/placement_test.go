@@ -0,0 +1,98 @@
+package utils_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPlacementFailure(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		err     string
+		failure bool
+	}{
+		{
+			name:    "no capacity",
+			err:     "Error: creating EC2 Instance: InsufficientInstanceCapacity: We currently do not have sufficient m6i.12xlarge capacity in the Availability Zone you requested (us-east-1c).",
+			failure: true,
+		},
+		{
+			name:    "instance type not in zone",
+			err:     "Error: creating EC2 Instance: Unsupported: Your requested instance type (m6id.12xlarge) is not supported in your requested Availability Zone (us-east-1e).",
+			failure: true,
+		},
+		{
+			name:    "unknown zone",
+			err:     "Error: creating EC2 Subnet: InvalidParameterValue: Value (us-east-1z) for parameter availabilityZone is invalid. Subnets can currently only be created in the following availability zones: us-east-1a.",
+			failure: true,
+		},
+		{
+			name:    "terraform unsupported argument",
+			err:     `Error: Unsupported argument on main.tf line 12: An argument named "instance_typ" is not expected here.`,
+			failure: false,
+		},
+		{
+			name:    "terraform unsupported attribute",
+			err:     `Error: Unsupported attribute on outputs.tf line 3: This object has no argument, nested block, or exported attribute named "public_ipp".`,
+			failure: false,
+		},
+		{
+			name:    "credentials",
+			err:     "Error: creating EC2 Instance: UnauthorizedOperation: You are not authorized to perform this operation.",
+			failure: false,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, test.failure, utils.IsPlacementFailure(errors.New(test.err)))
+		})
+	}
+}
+
+func TestPlacements(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		placements []string
+	}{
+		{
+			name: "defaults",
+			placements: []string{
+				"m6i.12xlarge us-east-1c", "m6i.12xlarge us-east-1b", "m6i.12xlarge us-east-1a",
+				"m6id.12xlarge us-east-1c", "m6id.12xlarge us-east-1b", "m6id.12xlarge us-east-1a",
+				"m5.12xlarge us-east-1c", "m5.12xlarge us-east-1b", "m5.12xlarge us-east-1a",
+			},
+		},
+		{
+			name:       "single zone",
+			env:        map[string]string{"AWS_INSTANCE_TYPES": "m5.12xlarge", "AWS_AVAILABILITY_ZONE": "d"},
+			placements: []string{"m5.12xlarge us-east-1d"},
+		},
+		{
+			name:       "zones take precedence over the single zone",
+			env:        map[string]string{"AWS_INSTANCE_TYPES": "m5.12xlarge", "AWS_AVAILABILITY_ZONE": "d", "AWS_AVAILABILITY_ZONES": " a, ,b "},
+			placements: []string{"m5.12xlarge us-east-1a", "m5.12xlarge us-east-1b"},
+		},
+		{
+			name:       "blank lists fall back to the defaults",
+			env:        map[string]string{"AWS_INSTANCE_TYPES": " ", "AWS_AVAILABILITY_ZONES": "", "AWS_AVAILABILITY_ZONE": "a"},
+			placements: []string{"m6i.12xlarge us-east-1a", "m6id.12xlarge us-east-1a", "m5.12xlarge us-east-1a"},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		// Not parallel, since the placements come from the environment
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{"AWS_INSTANCE_TYPES", "AWS_AVAILABILITY_ZONE", "AWS_AVAILABILITY_ZONES"} {
+				t.Setenv(name, test.env[name])
+			}
+			require.Equal(t, test.placements, utils.Placements("us-east-1"))
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/bundle"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/removal"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// removalRetryInterval is how long WaitForBundleRemoval waits between checks
+	removalRetryInterval = 15 * time.Second
+	// hostsBlockBegin starts the hosts block software-factory-idam-dns adds to the Corefile of CoreDNS
+	hostsBlockBegin = "#swf-begin"
+)
+
+// policyExceptionGVR is the API of the Kyverno policy exceptions in the manifests of the packages.
+var policyExceptionGVR = schema.GroupVersionResource{Group: "kyverno.io", Version: "v2alpha1", Resource: "policyexceptions"}
+
+// RemoveBundle removes the software factory bundle that SetupTestPlatform built and deployed, with all its packages.
+func RemoveBundle(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	output, err := platform.RunSSHCommandAsSudo(`cd ~/app/build && ./uds remove uds-bundle-software-factory-demo-*.tar.zst --confirm --no-progress`)
+	require.NoError(t, err, output)
+}
+
+// ExpectedRemovals returns what removing the bundle should remove: the namespaces of software-factory-namespaces, the
+// resources in the manifests of the packages in the repo, and the hosts block software-factory-idam-dns adds to
+// CoreDNS.
+func ExpectedRemovals(repoRoot string) ([]bundle.Resource, error) {
+	namespaces, err := bundle.Namespaces(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := bundle.Manifests(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	expected := append(namespaces, manifests...)
+
+	return append(expected, bundle.Resource{Kind: removal.KindHostsBlock, Namespace: "kube-system", Name: "coredns"}), nil
+}
+
+// WaitForBundleRemoval waits until none of the expected resources is left in the cluster, apart from the retained
+// ones, and returns what is still there. Namespaces take a while to go, so it keeps checking until timeout, and returns
+// the leftovers of the last check if some never go.
+func WaitForBundleRemoval(t *testing.T, platform *types.TestPlatform, expected []bundle.Resource, retained []removal.Retained, timeout time.Duration) *removal.Result {
+	t.Helper()
+	kube, err := platform.Kubernetes()
+	require.NoError(t, err)
+	var result *removal.Result
+	maxRetries := int(timeout / removalRetryInterval)
+	_, err = retry.DoWithRetryE(t, "Wait for the bundle to be removed", maxRetries, removalRetryInterval, func() (string, error) {
+		var err error
+		result, err = removal.Check(expected, retained, func(resource bundle.Resource) (bool, error) {
+			return resourcePresent(kube, resource)
+		})
+		if err != nil {
+			return "", err
+		}
+		if !result.OK() {
+			return "", fmt.Errorf("%d resources are left over", len(result.Leftovers))
+		}
+
+		return "", nil
+	})
+	if err != nil && result == nil {
+		require.NoError(t, err)
+	}
+
+	return result
+}
+
+// resourcePresent returns whether a resource the bundle created is still in the cluster. A resource whose API is gone,
+// such as a policy exception once Kyverno was removed, isn't there either.
+func resourcePresent(kube *types.Kubernetes, resource bundle.Resource) (bool, error) {
+	ctx := context.Background()
+	var err error
+	switch resource.Kind {
+	case "Namespace":
+		_, err = kube.Clientset.CoreV1().Namespaces().Get(ctx, resource.Name, metav1.GetOptions{})
+	case "Service":
+		_, err = kube.Clientset.CoreV1().Services(resource.Namespace).Get(ctx, resource.Name, metav1.GetOptions{})
+	case "Secret":
+		_, err = kube.Clientset.CoreV1().Secrets(resource.Namespace).Get(ctx, resource.Name, metav1.GetOptions{})
+	case "PolicyException":
+		_, err = kube.Dynamic.Resource(policyExceptionGVR).Namespace(resource.Namespace).Get(ctx, resource.Name, metav1.GetOptions{})
+	case removal.KindHostsBlock:
+		configMap, getErr := kube.Clientset.CoreV1().ConfigMaps(resource.Namespace).Get(ctx, resource.Name, metav1.GetOptions{})
+		if getErr == nil {
+			return strings.Contains(configMap.Data["Corefile"], hostsBlockBegin), nil
+		}
+		err = getErr
+	default:
+		return false, fmt.Errorf("don't know how to look for a %s", resource.Kind)
+	}
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to get %s: %w", resource, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/sonarqube"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
+	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// sonarqubeNamespace is where SonarQube is deployed
+	sonarqubeNamespace = "sonarqube"
+	// sonarqubeAdminSecret holds the password the SonarQube chart set for the admin user
+	sonarqubeAdminSecret = "sonarqube-sonarqube-admin-password"
+	// analysisRetryInterval is how long WaitForSonarQubeAnalysis waits between checks
+	analysisRetryInterval = 5 * time.Second
+)
+
+// SonarQubeAdmin returns a client for the SonarQube Web API that authenticates as the admin user, whose password the
+// SonarQube chart keeps in a secret.
+func SonarQubeAdmin(t *testing.T, platform *types.TestPlatform, domain string) *sonarqube.Client {
+	t.Helper()
+	kube, err := platform.Kubernetes()
+	require.NoError(t, err)
+	secret, err := kube.Clientset.CoreV1().Secrets(sonarqubeNamespace).Get(context.Background(), sonarqubeAdminSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	return sonarqube.NewClient(platform.HTTPClient(), fmt.Sprintf("https://sonarqube.%s", domain), "admin", string(secret.Data["password"]))
+}
+
+// CreateSonarQubeToken generates a token of the user of the client, and revokes it again when the test finishes.
+func CreateSonarQubeToken(t *testing.T, client *sonarqube.Client) string {
+	t.Helper()
+	name := "e2e-" + strings.ToLower(random.UniqueId())
+	token, err := client.GenerateToken(name)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := client.RevokeToken(name); err != nil {
+			t.Errorf("unable to revoke sonarqube test token %s: %v", name, err)
+		}
+	})
+
+	return token
+}
+
+// WaitForSonarQubeAnalysis waits until SonarQube has processed an analysis report of the project, and returns the task
+// that processed it. It gives up right away if processing failed.
+func WaitForSonarQubeAnalysis(t *testing.T, client *sonarqube.Client, projectKey string, timeout time.Duration) *sonarqube.Task {
+	t.Helper()
+	var task sonarqube.Task
+	maxRetries := int(timeout / analysisRetryInterval)
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("Wait for an analysis of %s", projectKey), maxRetries, analysisRetryInterval, func() (string, error) {
+		tasks, err := client.Tasks(projectKey)
+		if err != nil {
+			return "", err
+		}
+		if len(tasks) == 0 {
+			return "", fmt.Errorf("no analysis of %s has been submitted", projectKey)
+		}
+		task = tasks[0]
+		switch task.Status {
+		case sonarqube.TaskSuccess:
+			return "", nil
+		case sonarqube.TaskFailed, sonarqube.TaskCanceled:
+			return "", retry.FatalError{Underlying: fmt.Errorf("analysis %s of %s is %s: %s", task.ID, projectKey, task.Status, task.ErrorMessage)}
+		default:
+			return "", fmt.Errorf("analysis %s of %s is %s", task.ID, projectKey, task.Status)
+		}
+	})
+	require.NoError(t, err)
+
+	return &task
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/upgrade"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// bundleRegistry is the OCI registry the software factory bundle is released to
+	bundleRegistry = "ghcr.io"
+	// bundleRepository is the repository of the released bundle in bundleRegistry
+	bundleRepository = "defenseunicorns/uds-package/software-factory-demo"
+)
+
+// UpgradeSources returns the versions to upgrade from that env var UPGRADE_FROM lists, or nothing if it isn't set. It
+// lists either versions separated by commas, such as "0.0.12,0.0.13", or "last:N" for the N latest released versions.
+// Those are read from the index file in env var UPGRADE_VERSIONS_FILE, one version on each line and relative to the
+// root of the repo, if it is set, and from the tags of the released bundle in the OCI registry otherwise. The registry
+// is called with GHCR_USERNAME and GHCR_PASSWORD if they are set, and anonymously otherwise.
+func UpgradeSources(t *testing.T, repoRoot string) []string {
+	t.Helper()
+	spec, present := os.LookupEnv("UPGRADE_FROM")
+	if !present || spec == "" {
+		return nil
+	}
+	sources, err := upgrade.ParseSources(spec)
+	require.NoError(t, err)
+	if sources.Last == 0 {
+		return sources.Versions
+	}
+
+	var released []string
+	if indexFile := os.Getenv("UPGRADE_VERSIONS_FILE"); indexFile != "" {
+		if !filepath.IsAbs(indexFile) {
+			indexFile = filepath.Join(repoRoot, indexFile)
+		}
+		released, err = upgrade.ReadIndex(indexFile)
+	} else {
+		client := &http.Client{Timeout: httpRequestTimeout}
+		released, err = upgrade.RegistryTags(client, "https://"+bundleRegistry, bundleRepository, os.Getenv("GHCR_USERNAME"), os.Getenv("GHCR_PASSWORD"))
+	}
+	require.NoError(t, err)
+	versions := upgrade.LatestVersions(released, sources.Last)
+	require.NotEmpty(t, versions, "found no released versions to upgrade from")
+
+	return versions
+}
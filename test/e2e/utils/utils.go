@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	customteststructure "github.com/defenseunicorns/uds-package-software-factory/test/e2e/terratest/teststructure"
+	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/tools"
 	"github.com/defenseunicorns/uds-package-software-factory/test/e2e/types"
 	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/random"
 	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/terraform"
@@ -18,13 +21,74 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// SetupTestPlatform uses Terratest to create an EC2 instance. It then (on the new instance) downloads
-// the repo specified by env var REPO_URL at the ref specified by env var GIT_BRANCH, installs Zarf,
-// logs into registry1.dso.mil using env vars REGISTRY1_USERNAME and REGISTRY1_PASSWORD, builds all
+// CopySecretScript copies a script that holds secrets, like a token, to /tmp on the test host, so that the secrets don't
+// end up in the log of SSH commands, and returns its path there. CopyFileOverScp creates files that anyone can read,
+// but scp keeps the mode of a file that already exists, so the file is created with mode 0600 first. The script is
+// named after prefix, and should remove itself once it has run.
+func CopySecretScript(t *testing.T, platform *types.TestPlatform, prefix string, script []byte) string {
+	t.Helper()
+	name := fmt.Sprintf("%s-%s.sh", prefix, strings.ToLower(random.UniqueId()))
+	localScript := filepath.Join(platform.TestFolder, name)
+	require.NoError(t, os.WriteFile(localScript, script, 0600)) //nolint:gomnd
+	defer os.Remove(localScript)
+	remoteScript := "/tmp/" + name
+	output, err := platform.RunSSHCommand("install -m 600 /dev/null " + remoteScript)
+	require.NoError(t, err, output)
+	require.NoError(t, platform.CopyFileOverScp(localScript, remoteScript, 0600)) //nolint:gomnd
+	output, err = platform.RunSSHCommand("stat -c %a " + remoteScript)
+	require.NoError(t, err, output)
+	require.Equal(t, "600", strings.TrimSpace(output), "%s is readable by others on the test host", remoteScript)
+
+	return remoteScript
+}
+
+// SetupTestPlatform uses Terratest to create an EC2 instance. It then (on the new instance) installs the pinned,
+// checksum-verified tools from tools.lock.json, downloads the repo specified by env var REPO_URL at the ref specified
+// by env var GIT_BRANCH, logs into registry1.dso.mil using env vars REGISTRY1_USERNAME and REGISTRY1_PASSWORD, builds all
 // the packages, and deploys the init package, the flux package, and the software factory package.
 // It is finished when the zarf command returns from deploying the software factory package. It is
 // the responsibility of the test being run to do the appropriate waiting for services to come up.
-func SetupTestPlatform(t *testing.T, platform *types.TestPlatform) { //nolint:funlen
+// If env var GOLDEN_IMAGE is "yes" the instance is launched from the golden image built by BuildGoldenImage for the
+// current host steps, and the steps already baked into it are skipped.
+func SetupTestPlatform(t *testing.T, platform *types.TestPlatform) {
+	t.Helper()
+	setupTestPlatform(t, platform, previousVersion(t), nil)
+}
+
+// SetupUpgradeTestPlatform is SetupTestPlatform with a hook for upgrades. When env var UPGRADE is "yes",
+// afterPreviousVersion is called once LATEST_VERSION has been deployed, before the branch version is deployed over it,
+// such as to seed the data an upgrade test checks afterwards.
+func SetupUpgradeTestPlatform(t *testing.T, platform *types.TestPlatform, afterPreviousVersion func()) {
+	t.Helper()
+	setupTestPlatform(t, platform, previousVersion(t), afterPreviousVersion)
+}
+
+// SetupTestPlatformFrom is SetupUpgradeTestPlatform for an upgrade from previousVersion, whatever env vars UPGRADE and
+// LATEST_VERSION are set to.
+func SetupTestPlatformFrom(t *testing.T, platform *types.TestPlatform, previousVersion string, afterPreviousVersion func()) {
+	t.Helper()
+	setupTestPlatform(t, platform, previousVersion, afterPreviousVersion)
+}
+
+// previousVersion returns the version SetupTestPlatform upgrades from, which is LATEST_VERSION when UPGRADE is "yes",
+// and empty when it doesn't upgrade.
+func previousVersion(t *testing.T) string {
+	t.Helper()
+	latestVersion, err := getEnvVar("LATEST_VERSION")
+	require.NoError(t, err)
+	isUpgrade, err := getEnvVar("UPGRADE")
+	require.NoError(t, err)
+	if isUpgrade != "yes" {
+		return ""
+	}
+
+	return latestVersion
+}
+
+// setupTestPlatform does what SetupTestPlatform and the functions like it describe. The released bundle of
+// previousVersion is deployed before the branch version, unless previousVersion is empty. afterPreviousVersion may be
+// nil.
+func setupTestPlatform(t *testing.T, platform *types.TestPlatform, previousVersion string, afterPreviousVersion func()) { //nolint:funlen
 	t.Helper()
 	repoURL, err := getEnvVar("REPO_URL")
 	require.NoError(t, err)
@@ -40,87 +104,32 @@ func SetupTestPlatform(t *testing.T, platform *types.TestPlatform) { //nolint:fu
 	require.NoError(t, err)
 	ghcrPassword, err := getEnvVar("GHCR_PASSWORD")
 	require.NoError(t, err)
-	latestVersion, err := getEnvVar("LATEST_VERSION")
-	require.NoError(t, err)
-	isUpgrade, err := getEnvVar("UPGRADE")
-	require.NoError(t, err)
 	copyBundle, err := getEnvVar("COPY_BUNDLE")
 	require.NoError(t, err)
-	awsAvailabilityZone := getAwsAvailabilityZone(awsRegion)
-	namespace := "uds-swf"
-	stage := "terratest"
-	name := fmt.Sprintf("e2e-%s", random.UniqueId())
-	instanceType := "m6i.12xlarge"
-	teststructure.RunTestStage(t, "SETUP", func() {
-		keyPairName := fmt.Sprintf("%s-%s-%s", namespace, stage, name)
-		keyPair := aws.CreateAndImportEC2KeyPair(t, awsRegion, keyPairName)
-		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-			TerraformDir: platform.TestFolder,
-			Vars: map[string]interface{}{
-				"aws_region":            awsRegion,
-				"aws_availability_zone": awsAvailabilityZone,
-				"namespace":             namespace,
-				"stage":                 stage,
-				"name":                  name,
-				"key_pair_name":         keyPairName,
-				"instance_type":         instanceType,
-			},
-		})
-		teststructure.SaveTerraformOptions(t, platform.TestFolder, terraformOptions)
-		// Use a custom version of this function because the upstream version leaks the private SSH key in the pipeline logs
-		customteststructure.SaveEc2KeyPair(t, platform.TestFolder, keyPair)
-		terraform.InitAndApply(t, terraformOptions)
-
-		// It can take a minute or so for the instance to boot up, so retry a few times
-		err = waitForInstanceReady(t, platform, 5*time.Second, 15) //nolint:gomnd
+	pinnedTools, err := tools.Resolve(platform.RepoRoot)
+	require.NoError(t, err)
+	steps := hostSteps(pinnedTools)
+	amiID := ""
+	if os.Getenv("GOLDEN_IMAGE") == "yes" {
+		amiID, err = findGoldenImage(t, awsRegion, ImageHash(steps))
 		require.NoError(t, err)
+	}
+	teststructure.RunTestStage(t, "SETUP", func() {
+		provisionInstance(t, platform, awsRegion, amiID)
 
-		// Install Docker Dependencies
-		output, err := platform.RunSSHCommandAsSudo(`apt install -y ca-certificates curl gnupg lsb-release`)
-		require.NoError(t, err, output)
-
-		// Add Docker GPG Key
-		output, err = platform.RunSSHCommandAsSudo(`mkdir -m 0755 -p /etc/apt/keyrings && curl -fsSL https://download.docker.com/linux/ubuntu/gpg | gpg --dearmor -o /etc/apt/keyrings/docker.gpg`)
-		require.NoError(t, err, output)
-
-		// Setup Docker APT Repo
-		output, err = platform.RunSSHCommandAsSudo(`echo "deb [arch=$(dpkg --print-architecture) signed-by=/etc/apt/keyrings/docker.gpg] https://download.docker.com/linux/ubuntu $(lsb_release -cs) stable" | tee /etc/apt/sources.list.d/docker.list > /dev/null`)
-		require.NoError(t, err, output)
-
-		// Update APT repos including new docker repo
-		output, err = platform.RunSSHCommandAsSudo(`apt update -y`)
-		require.NoError(t, err, output)
-
-		// Install Docker
-		output, err = platform.RunSSHCommandAsSudo(`apt install -y docker-ce docker-ce-cli containerd.io docker-buildx-plugin docker-compose-plugin`)
-		require.NoError(t, err, output)
-
-		// Download and install k3d
-		output, err = platform.RunSSHCommandAsSudo(`curl -s https://raw.githubusercontent.com/k3d-io/k3d/main/install.sh | bash`)
-		require.NoError(t, err, output)
-
-		// Download kubectl binary
-		output, err = platform.RunSSHCommandAsSudo(`curl -LO "https://dl.k8s.io/release/$(curl -L -s https://dl.k8s.io/release/stable.txt)/bin/linux/amd64/kubectl"`)
-		require.NoError(t, err, output)
+		// Install everything the instance needs. Steps that were baked into a golden image are skipped.
+		runHostSteps(t, platform, steps)
 
-		// Install kubectl
-		output, err = platform.RunSSHCommandAsSudo(`install -o root -g root -m 0755 kubectl /usr/local/bin/kubectl`)
-		require.NoError(t, err, output)
-
-		// Install dependencies. Doing it here since the instance user-data is being flaky, still saying things like make are not installed
-		output, err = platform.RunSSHCommandAsSudo(`apt update && apt install -y jq git make wget sslscan && sysctl -w vm.max_map_count=262144`)
+		// Kernel settings aren't kept in the golden image, so always set them
+		output, err := platform.RunSSHCommandAsSudo(`sysctl -w vm.max_map_count=262144`)
 		require.NoError(t, err, output)
 
 		// Clone the repo idempotently
 		output, err = platform.RunSSHCommandAsSudo(fmt.Sprintf(`rm -rf ~/app && git clone --depth 1 %v --branch %v --single-branch ~/app`, repoURL, gitBranch))
 		require.NoError(t, err, output)
 
-		// Install Zarf
-		output, err = platform.RunSSHCommandAsSudo(`cd ~/app && make build/zarf`)
-		require.NoError(t, err, output)
-
-		// Add the zarf binary to the path
-		output, err = platform.RunSSHCommandAsSudo(`cd ~/app && cp build/zarf /usr/local/bin/zarf`)
+		// Put zarf and uds in the build folder so the Makefile doesn't download its own unverified copies
+		output, err = platform.RunSSHCommandAsSudo(`mkdir -p ~/app/build && cp /usr/local/bin/zarf ~/app/build/zarf && cp /usr/local/bin/uds ~/app/build/uds`)
 		require.NoError(t, err, output)
 
 		// Copy zarf-config.yaml to the build folder
@@ -164,10 +173,14 @@ func SetupTestPlatform(t *testing.T, platform *types.TestPlatform) { //nolint:fu
 			require.NoError(t, err, output)
 		}
 
-		if isUpgrade == "yes" {
-			// Deploy current SWF version
-			output, err = platform.RunSSHCommandAsSudo(fmt.Sprintf(`~/app/build/uds deploy oci://ghcr.io/defenseunicorns/uds-package/software-factory-demo:%s --confirm --no-progress`, latestVersion))
+		if previousVersion != "" {
+			// Deploy the released SWF version that is upgraded from
+			output, err = platform.RunSSHCommandAsSudo(fmt.Sprintf(`~/app/build/uds deploy oci://%s/%s:%s --confirm --no-progress`, bundleRegistry, bundleRepository, previousVersion))
 			require.NoError(t, err, output)
+
+			if afterPreviousVersion != nil {
+				afterPreviousVersion()
+			}
 		}
 
 		// Deploy branch version
@@ -177,6 +190,57 @@ func SetupTestPlatform(t *testing.T, platform *types.TestPlatform) { //nolint:fu
 	})
 }
 
+// provisionInstance uses Terratest to create the EC2 instance and waits until it accepts SSH connections. If amiID is
+// empty the instance is launched from the latest Ubuntu 22.04 AMI. Each instance type and availability zone from
+// getPlacements is tried in turn until AWS has capacity for one of them, and the one that was used is saved in the
+// test data as "instance_type" and "availability_zone".
+func provisionInstance(t *testing.T, platform *types.TestPlatform, awsRegion string, amiID string) {
+	t.Helper()
+	namespace := "uds-swf"
+	stage := "terratest"
+	name := fmt.Sprintf("e2e-%s", random.UniqueId())
+	keyPairName := fmt.Sprintf("%s-%s-%s", namespace, stage, name)
+	keyPair := aws.CreateAndImportEC2KeyPair(t, awsRegion, keyPairName)
+	// Use a custom version of this function because the upstream version leaks the private SSH key in the pipeline logs
+	customteststructure.SaveEc2KeyPair(t, platform.TestFolder, keyPair)
+	err := platform.MarkTeardownPending()
+	require.NoError(t, err)
+	for _, candidate := range getPlacements(awsRegion) {
+		terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+			TerraformDir: platform.TestFolder,
+			Vars: map[string]interface{}{
+				"aws_region":            awsRegion,
+				"aws_availability_zone": candidate.AvailabilityZone,
+				"namespace":             namespace,
+				"stage":                 stage,
+				"name":                  name,
+				"key_pair_name":         keyPairName,
+				"instance_type":         candidate.InstanceType,
+				"ami_id":                amiID,
+			},
+		})
+		// Save before applying so that Teardown can clean up whatever was created even if the apply fails
+		teststructure.SaveTerraformOptions(t, platform.TestFolder, terraformOptions)
+		logger.Default.Logf(t, "Trying instance type %s in availability zone %s", candidate.InstanceType, candidate.AvailabilityZone)
+		_, err = terraform.InitAndApplyE(t, terraformOptions)
+		if err == nil {
+			teststructure.SaveString(t, platform.TestFolder, "instance_type", candidate.InstanceType)
+			teststructure.SaveString(t, platform.TestFolder, "availability_zone", candidate.AvailabilityZone)
+
+			break
+		}
+		if !isPlacementFailure(err) {
+			break
+		}
+		logger.Default.Logf(t, "Unable to place instance type %s in availability zone %s, trying the next one: %v", candidate.InstanceType, candidate.AvailabilityZone, err)
+	}
+	require.NoError(t, err)
+
+	// It can take a minute or so for the instance to boot up, so retry a few times
+	err = waitForInstanceReady(t, platform, 5*time.Second, 15) //nolint:gomnd
+	require.NoError(t, err)
+}
+
 // getAwsRegion returns the desired AWS region to use by first checking the env var AWS_REGION, then checking
 // AWS_DEFAULT_REGION if AWS_REGION isn't set. If neither is set it returns an error.
 func getAwsRegion() (string, error) {
@@ -193,20 +257,6 @@ func getAwsRegion() (string, error) {
 	return val, nil
 }
 
-// getAwsAvailabilityZone returns the desired AWS Availability Zone to use by first checking the env var AWS_AVAILABILITY_ZONE,
-// We default to {awsRegion}b if env var is not specified.
-func getAwsAvailabilityZone(awsRegion string) string {
-	zoneLetter, present := os.LookupEnv("AWS_AVAILABILITY_ZONE")
-	var zone string
-	if !present {
-		zone = fmt.Sprintf("%s%s", awsRegion, "c")
-	} else {
-		zone = fmt.Sprintf("%s%s", awsRegion, zoneLetter)
-	}
-
-	return zone
-}
-
 // getEnvVar gets an environment variable, returning an error if it isn't found.
 func getEnvVar(varName string) (string, error) {
 	val, present := os.LookupEnv(varName)
@@ -10,6 +10,13 @@ locals {
 
 provider "aws" {
   region = var.aws_region
+
+  # Tag everything with the run it belongs to so the reaper can find whatever a crashed run leaves behind
+  default_tags {
+    tags = {
+      "uds-swf/run" = local.fullname
+    }
+  }
 }
 
 # ---------------------------------------------------------------------------------------------------------------------
@@ -64,7 +71,7 @@ resource "aws_route_table_association" "terratest_public_rt_assoc" {
 # ---------------------------------------------------------------------------------------------------------------------
 
 resource "aws_instance" "public" {
-  ami                    = data.aws_ami.ubuntu.id
+  ami                    = var.ami_id != "" ? var.ami_id : data.aws_ami.ubuntu.id
   instance_type          = var.instance_type
   vpc_security_group_ids = [aws_security_group.public.id]
   key_name               = var.key_pair_name
@@ -46,3 +46,14 @@ variable "instance_type" {
   description = "The EC2 instance type to run."
   type        = string
 }
+
+# ---------------------------------------------------------------------------------------------------------------------
+# OPTIONAL PARAMETERS
+# These parameters have reasonable defaults.
+# ---------------------------------------------------------------------------------------------------------------------
+
+variable "ami_id" {
+  description = "The AMI to launch the EC2 instance from, such as a golden image. Defaults to the latest Ubuntu 22.04 AMI."
+  type        = string
+  default     = ""
+}